@@ -0,0 +1,334 @@
+package pgs
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+// Annotation markers are embedded in rendered content to delimit the regions
+// generated from an Entity. They use characters from Unicode's private use
+// area, which pass through text and html templates unchanged. A region is
+// encoded as the begin marker, the Entity's source file, the separator, its
+// comma-separated source path, the open marker, the region's content and
+// finally the end marker.
+const (
+	annotationBegin     = '\uE000'
+	annotationSeparator = '\uE001'
+	annotationOpen      = '\uE002'
+	annotationEnd       = '\uE003'
+)
+
+var annotationMarkers = string([]rune{annotationBegin, annotationEnd})
+
+// AnnotationBegin returns a marker beginning a region of generated content
+// that is attached to e. The region must be closed with AnnotationEnd, and
+// regions may be nested. When the content is persisted, the markers are
+// removed and the region is described in the file's GeneratedCodeInfo, which
+// tools such as IDEs use to navigate from generated code to its proto
+// definition. Markers are only processed if the Generator is initialized with
+// the AnnotateGeneratedCode InitOption. Marked regions are supported in the
+// contents of GeneratorFile, GeneratorInjection and their template
+// equivalents; the markers are removed from GeneratorAppend and
+// GeneratorTemplateAppend without producing annotations, and are left as-is in
+// CustomFile and CustomTemplateFile.
+//
+// The annotation offsets are computed before post-processing, and are updated
+// to match the post-processed content by aligning its lines while ignoring
+// whitespace. Annotations on lines that are otherwise changed are dropped.
+func AnnotationBegin(e Entity) string {
+	path := e.SourcePath()
+	parts := make([]string, len(path))
+	for i, p := range path {
+		parts[i] = strconv.Itoa(int(p))
+	}
+
+	return fmt.Sprintf("%c%s%c%s%c",
+		annotationBegin, e.File().Name(), annotationSeparator, strings.Join(parts, ","), annotationOpen)
+}
+
+// AnnotationEnd returns a marker ending the region of generated content begun
+// by the most recent unclosed AnnotationBegin.
+func AnnotationEnd() string { return string(annotationEnd) }
+
+// Annotate wraps s in markers attaching it to e. See AnnotationBegin for
+// details. Annotate is intended to be registered as a template function:
+//
+//	tpl := template.New("foo").Funcs(map[string]interface{}{
+//		"annotate": pgs.Annotate,
+//	})
+//
+//	type {{ annotate . (name .) }} struct {
+func Annotate(e Entity, s string) string { return AnnotationBegin(e) + s + AnnotationEnd() }
+
+// extractAnnotations removes the annotation markers from content, returning
+// the annotations they describe with offsets into the returned content.
+func extractAnnotations(content string) (string, []*descriptor.GeneratedCodeInfo_Annotation, error) {
+	if !strings.ContainsAny(content, annotationMarkers) {
+		return content, nil, nil
+	}
+
+	var (
+		buf   strings.Builder
+		anns  []*descriptor.GeneratedCodeInfo_Annotation
+		stack []*descriptor.GeneratedCodeInfo_Annotation
+	)
+
+	for {
+		i := strings.IndexAny(content, annotationMarkers)
+		if i < 0 {
+			buf.WriteString(content)
+			break
+		}
+
+		buf.WriteString(content[:i])
+		r, size := utf8.DecodeRuneInString(content[i:])
+		content = content[i+size:]
+
+		if r == annotationEnd {
+			if len(stack) == 0 {
+				return "", nil, errors.New("annotation end without a matching begin")
+			}
+			stack[len(stack)-1].End = proto.Int32(int32(buf.Len()))
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		j := strings.IndexRune(content, annotationOpen)
+		if j < 0 {
+			return "", nil, errors.New("malformed annotation begin marker")
+		}
+
+		a, err := parseAnnotation(content[:j])
+		if err != nil {
+			return "", nil, err
+		}
+		content = content[j+utf8.RuneLen(annotationOpen):]
+
+		a.Begin = proto.Int32(int32(buf.Len()))
+		anns = append(anns, a)
+		stack = append(stack, a)
+	}
+
+	if len(stack) > 0 {
+		return "", nil, fmt.Errorf("unterminated annotation for %s", stack[len(stack)-1].GetSourceFile())
+	}
+
+	return buf.String(), anns, nil
+}
+
+func parseAnnotation(header string) (*descriptor.GeneratedCodeInfo_Annotation, error) {
+	i := strings.IndexRune(header, annotationSeparator)
+	if i < 0 {
+		return nil, errors.New("malformed annotation begin marker")
+	}
+
+	a := &descriptor.GeneratedCodeInfo_Annotation{SourceFile: proto.String(header[:i])}
+
+	if path := header[i+utf8.RuneLen(annotationSeparator):]; path != "" {
+		for _, p := range strings.Split(path, ",") {
+			n, err := strconv.ParseInt(p, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("malformed annotation path %q", path)
+			}
+			a.Path = append(a.Path, int32(n))
+		}
+	}
+
+	return a, nil
+}
+
+// remapAnnotations updates anns, with offsets into old, to match new, the same
+// content after post-processing. The lines of old and new are aligned ignoring
+// whitespace, and offsets are mapped between aligned lines by counting their
+// non-whitespace bytes. Annotations whose bounds fall on unaligned lines are
+// dropped.
+func remapAnnotations(old, new string, anns []*descriptor.GeneratedCodeInfo_Annotation) []*descriptor.GeneratedCodeInfo_Annotation {
+	if old == new {
+		return anns
+	}
+
+	a, b := splitSourceLines(old), splitSourceLines(new)
+
+	aKeys, bKeys := make([]string, len(a)), make([]string, len(b))
+	for i, ln := range a {
+		aKeys[i] = ln.key
+	}
+	for i, ln := range b {
+		bKeys[i] = ln.key
+	}
+
+	aligned := make(map[int]int, len(a))
+	m := difflib.NewMatcherWithJunk(aKeys, bKeys, false, nil)
+	for _, blk := range m.GetMatchingBlocks() {
+		for i := 0; i < blk.Size; i++ {
+			aligned[blk.A+i] = blk.B + i
+		}
+	}
+
+	mapOffset := func(off int32, end bool) (*int32, bool) {
+		li := sort.Search(len(a), func(i int) bool { return a[i].start > int(off) }) - 1
+		if li < 0 {
+			return nil, false
+		}
+
+		bi, ok := aligned[li]
+		if !ok {
+			return nil, false
+		}
+
+		k := nonSpaceCount(a[li].text[:int(off)-a[li].start])
+		return proto.Int32(int32(b[bi].start + nonSpaceOffset(b[bi].text, k, end))), true
+	}
+
+	out := make([]*descriptor.GeneratedCodeInfo_Annotation, 0, len(anns))
+	for _, ann := range anns {
+		begin, ok := mapOffset(ann.GetBegin(), false)
+		if !ok {
+			continue
+		}
+
+		end, ok := mapOffset(ann.GetEnd(), true)
+		if !ok {
+			continue
+		}
+
+		ann.Begin, ann.End = begin, end
+		out = append(out, ann)
+	}
+
+	return out
+}
+
+// sourceLine is a line of content, including its trailing newline, with its
+// offset in the content and its text stripped of whitespace.
+type sourceLine struct {
+	start int
+	text  string
+	key   string
+}
+
+func splitSourceLines(s string) []sourceLine {
+	lines := make([]sourceLine, 0, strings.Count(s, "\n")+1)
+	for start := 0; ; {
+		i := strings.IndexByte(s[start:], '\n') + 1
+		if i == 0 {
+			i = len(s) - start
+		}
+
+		text := s[start : start+i]
+		lines = append(lines, sourceLine{start: start, text: text, key: stripSpace(text)})
+
+		start += i
+		if start >= len(s) {
+			return lines
+		}
+	}
+}
+
+func isSpace(b byte) bool {
+	switch b {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	default:
+		return false
+	}
+}
+
+func stripSpace(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if !isSpace(s[i]) {
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func nonSpaceCount(s string) (n int) {
+	for i := 0; i < len(s); i++ {
+		if !isSpace(s[i]) {
+			n++
+		}
+	}
+	return n
+}
+
+// nonSpaceOffset returns the offset in line preceding its k-th (zero-based)
+// non-whitespace byte. If end is true, the offset immediately following the
+// preceding non-whitespace byte is returned instead.
+func nonSpaceOffset(line string, k int, end bool) int {
+	last := 0
+	for i := 0; i < len(line); i++ {
+		if isSpace(line[i]) {
+			continue
+		}
+
+		if k == 0 {
+			if end {
+				return last
+			}
+			return i
+		}
+
+		k--
+		last = i + 1
+	}
+
+	return last
+}
+
+// injectAnnotations returns the GeneratedCodeInfo of a file after the
+// injection f was inserted into it at offset start, with each non-empty line
+// of f prefixed by indent. The file's own annotations following the insertion
+// are shifted by n, the number of bytes inserted.
+func injectAnnotations(info *descriptor.GeneratedCodeInfo, f *plugin_go.CodeGeneratorResponse_File,
+	start int, indent string, n int) *descriptor.GeneratedCodeInfo {
+	if len(info.GetAnnotation()) == 0 && len(f.GetGeneratedCodeInfo().GetAnnotation()) == 0 {
+		return info
+	}
+
+	shift := func(off int32) *int32 {
+		if int(off) >= start {
+			off += int32(n)
+		}
+		return &off
+	}
+
+	var anns []*descriptor.GeneratedCodeInfo_Annotation
+	for _, a := range info.GetAnnotation() {
+		a.Begin, a.End = shift(a.GetBegin()), shift(a.GetEnd())
+		anns = append(anns, a)
+	}
+
+	content := f.GetContent()
+	lines := splitSourceLines(content)
+	inject := func(off int32, end bool) *int32 {
+		indents := 0
+		for _, ln := range lines {
+			if ln.start > int(off) || ln.start == int(off) && end {
+				break
+			}
+			if ln.text != "\n" {
+				indents++
+			}
+		}
+		return proto.Int32(int32(start + int(off) + indents*len(indent)))
+	}
+
+	for _, a := range f.GetGeneratedCodeInfo().GetAnnotation() {
+		a.Begin, a.End = inject(a.GetBegin(), false), inject(a.GetEnd(), true)
+		anns = append(anns, a)
+	}
+
+	sort.SliceStable(anns, func(i, j int) bool { return anns[i].GetBegin() < anns[j].GetBegin() })
+	return &descriptor.GeneratedCodeInfo{Annotation: anns}
+}
@@ -0,0 +1,253 @@
+package pgs
+
+import (
+	"go/format"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+// annotated returns the content of each annotation in f.
+func annotated(f *plugin_go.CodeGeneratorResponse_File) []string {
+	var out []string
+	for _, a := range f.GetGeneratedCodeInfo().GetAnnotation() {
+		out = append(out, f.GetContent()[a.GetBegin():a.GetEnd()])
+	}
+	return out
+}
+
+type goFmtPP struct{}
+
+func (goFmtPP) Match(a Artifact) bool { return true }
+
+func (goFmtPP) Process(in []byte) ([]byte, error) { return format.Source(in) }
+
+func TestAnnotate(t *testing.T) {
+	t.Parallel()
+
+	g := buildGraph(t, "info")
+	m, ok := g.Lookup(".graph.info.Info")
+	require.True(t, ok)
+	fld, ok := g.Lookup(".graph.info.Info.other_field")
+	require.True(t, ok)
+
+	content := "type " + AnnotationBegin(m) + "Info struct {\n\t" +
+		Annotate(fld, "OtherField") + " []int32\n}" + AnnotationEnd() + "\n"
+
+	out, anns, err := extractAnnotations(content)
+	require.NoError(t, err)
+	assert.Equal(t, "type Info struct {\n\tOtherField []int32\n}\n", out)
+
+	require.Len(t, anns, 2)
+	assert.Equal(t, "info/info.proto", anns[0].GetSourceFile())
+	assert.Equal(t, []int32{4, 0}, anns[0].GetPath())
+	assert.Equal(t, "Info struct {\n\tOtherField []int32\n}", out[anns[0].GetBegin():anns[0].GetEnd()])
+	assert.Equal(t, []int32{4, 0, 2, 1}, anns[1].GetPath())
+	assert.Equal(t, "OtherField", out[anns[1].GetBegin():anns[1].GetEnd()])
+
+	f := g.Targets()["info/info.proto"]
+	_, anns, err = extractAnnotations(Annotate(f, "file"))
+	require.NoError(t, err)
+	require.Len(t, anns, 1)
+	assert.Empty(t, anns[0].GetPath())
+}
+
+func TestExtractAnnotations_Errors(t *testing.T) {
+	t.Parallel()
+
+	begin := func(header string) string { return string(annotationBegin) + header }
+
+	tests := map[string]string{
+		"unmatched end":  "foo" + AnnotationEnd(),
+		"unterminated":   begin("foo.proto"+string(annotationSeparator)+"4"+string(annotationOpen)) + "bar",
+		"no separator":   begin("foo.proto4"+string(annotationOpen)) + "bar" + AnnotationEnd(),
+		"no open marker": begin("foo.proto" + string(annotationSeparator) + "4"),
+		"bad path":       begin("foo.proto"+string(annotationSeparator)+"4,x"+string(annotationOpen)) + "bar" + AnnotationEnd(),
+	}
+
+	for name, content := range tests {
+		_, _, err := extractAnnotations(content)
+		assert.Error(t, err, name)
+	}
+
+	out, anns, err := extractAnnotations("plain")
+	assert.NoError(t, err)
+	assert.Equal(t, "plain", out)
+	assert.Nil(t, anns)
+}
+
+// annotatingPersister returns a dummyPersister with annotations enabled.
+func annotatingPersister(d Debugger) *stdPersister {
+	p := dummyPersister(d)
+	p.SetAnnotations(true)
+	return p
+}
+
+func TestPersister_Persist_Annotations(t *testing.T) {
+	t.Parallel()
+
+	g := buildGraph(t, "info")
+	lookup := func(name string) Entity {
+		e, ok := g.Lookup(name)
+		require.True(t, ok, name)
+		return e
+	}
+	m, fld := lookup(".graph.info.Info"), lookup(".graph.info.Info.other_field")
+
+	tpl := template.Must(template.New("tpl").Funcs(map[string]interface{}{
+		"annotate": Annotate,
+	}).Parse("package foo\n\ntype {{ annotate . .Name.String }}   struct{\n\t// @@protoc_insertion_point(fields)\n}\n"))
+
+	t.Run("post-processed", func(t *testing.T) {
+		t.Parallel()
+
+		d := InitMockDebugger()
+		p := annotatingPersister(d)
+		p.AddPostProcessor(goFmtPP{}, StampHeaders("protoc-gen-test", ""))
+
+		resp := p.Persist(
+			GeneratorTemplateFile{Name: "foo.go", TemplateArtifact: TemplateArtifact{Template: tpl, Data: m}},
+			GeneratorFile{
+				Name:     "bar.go",
+				Contents: "package bar\nvar  " + Annotate(fld, "x , y") + " = 1, 2\n",
+			},
+		)
+		require.NoError(t, d.Err())
+		require.Len(t, resp.File, 2)
+
+		assert.False(t, strings.ContainsAny(resp.File[0].GetContent(), annotationMarkers))
+		assert.Equal(t, []string{"Info"}, annotated(resp.File[0]))
+		assert.Equal(t, []int32{4, 0}, resp.File[0].GetGeneratedCodeInfo().GetAnnotation()[0].GetPath())
+		assert.Equal(t, []string{"x, y"}, annotated(resp.File[1]))
+	})
+
+	t.Run("injection", func(t *testing.T) {
+		t.Parallel()
+
+		inj := GeneratorInjection{
+			FileName:       "foo.go",
+			InsertionPoint: "fields",
+			Contents:       Annotate(fld, "OtherField") + " []int32\n",
+		}
+
+		d := InitMockDebugger()
+		resp := annotatingPersister(d).Persist(inj)
+		require.NoError(t, d.Err())
+		require.Len(t, resp.File, 1)
+		assert.Equal(t, []string{"OtherField"}, annotated(resp.File[0]))
+
+		d = InitMockDebugger()
+		p := annotatingPersister(d)
+		p.SetResolveInsertionPoints(true)
+		resp = p.Persist(
+			GeneratorTemplateFile{Name: "foo.go", TemplateArtifact: TemplateArtifact{Template: tpl, Data: m}},
+			inj,
+		)
+		require.NoError(t, d.Err())
+		require.Len(t, resp.File, 1)
+		assert.Equal(t, []string{"Info", "OtherField"}, annotated(resp.File[0]))
+		assert.Contains(t, resp.File[0].GetContent(), "\n\tOtherField []int32\n\t// @@protoc_insertion_point(fields)")
+	})
+
+	t.Run("unannotated", func(t *testing.T) {
+		t.Parallel()
+
+		d := InitMockDebugger()
+		p := annotatingPersister(d)
+		resp := p.Persist(
+			GeneratorFile{Name: "foo.txt", Contents: "foo"},
+			GeneratorAppend{FileName: "foo.txt", Contents: Annotate(m, "bar")},
+			CustomFile{Name: "/baz.txt", Contents: Annotate(m, "baz"), Perms: 0644},
+		)
+		require.NoError(t, d.Err())
+		require.Len(t, resp.File, 2)
+
+		assert.Nil(t, resp.File[0].GeneratedCodeInfo)
+		assert.Equal(t, "bar", resp.File[1].GetContent())
+		assert.Nil(t, resp.File[1].GeneratedCodeInfo)
+
+		b, err := afero.ReadFile(p.fs, "/baz.txt")
+		require.NoError(t, err)
+		assert.Equal(t, Annotate(m, "baz"), string(b), "custom files should be left as-is")
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		d := InitMockDebugger()
+		resp := dummyPersister(d).Persist(GeneratorFile{Name: "foo.txt", Contents: Annotate(m, "foo")})
+		require.NoError(t, d.Err())
+		require.Len(t, resp.File, 1)
+		assert.Equal(t, Annotate(m, "foo"), resp.File[0].GetContent())
+		assert.Nil(t, resp.File[0].GeneratedCodeInfo)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		d := InitMockDebugger()
+		annotatingPersister(d).Persist(GeneratorFile{Name: "foo.txt", Contents: AnnotationEnd()})
+		assert.Error(t, d.Err())
+	})
+}
+
+func TestPersister_Persist_PrivateUseContent(t *testing.T) {
+	t.Parallel()
+
+	// icon fonts map glyphs into the private use area, including the code
+	// points of the annotation markers, which are unbalanced here
+	css := ".icon-a:before { content: \"\uE000\"; }\n.icon-b:before { content: \"\uE003\"; }\n"
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+	resp := p.Persist(
+		GeneratorFile{Name: "icons.css", Contents: css},
+		CustomFile{Name: "/icons.css", Contents: css, Perms: 0644},
+	)
+	require.NoError(t, d.Err())
+	require.Len(t, resp.File, 1)
+	assert.Equal(t, css, resp.File[0].GetContent())
+
+	b, err := afero.ReadFile(p.fs, "/icons.css")
+	require.NoError(t, err)
+	assert.Equal(t, css, string(b))
+
+	d = InitMockDebugger()
+	p = annotatingPersister(d)
+	p.Persist(CustomFile{Name: "/icons.css", Contents: css, Perms: 0644})
+	require.NoError(t, d.Err(), "custom files are not annotated")
+
+	b, err = afero.ReadFile(p.fs, "/icons.css")
+	require.NoError(t, err)
+	assert.Equal(t, css, string(b))
+}
+
+func TestRemapAnnotations(t *testing.T) {
+	t.Parallel()
+
+	old := "a  b\nunchanged\n  c d\nremoved\n"
+	new := "// header\n\na b\nunchanged\n\tc  d\n"
+
+	ann := func(begin, end int32) *descriptor.GeneratedCodeInfo_Annotation {
+		return &descriptor.GeneratedCodeInfo_Annotation{Begin: &begin, End: &end}
+	}
+
+	anns := remapAnnotations(old, new, []*descriptor.GeneratedCodeInfo_Annotation{
+		ann(0, 4),   // "a  b"
+		ann(5, 14),  // "unchanged"
+		ann(17, 20), // "c d"
+		ann(21, 28), // "removed"
+	})
+
+	var got []string
+	for _, a := range anns {
+		got = append(got, new[a.GetBegin():a.GetEnd()])
+	}
+	assert.Equal(t, []string{"a b", "unchanged", "c  d"}, got)
+}
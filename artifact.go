@@ -3,10 +3,13 @@ package pgs
 import (
 	"bytes"
 	"errors"
+	htmltpl "html/template"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	texttpl "text/template"
 
 	"google.golang.org/protobuf/proto"
 	plugin_go "google.golang.org/protobuf/types/pluginpb"
@@ -47,9 +50,21 @@ type TemplateArtifact struct {
 	Data interface{}
 }
 
+// tplMu serializes the execution of Templates that are not known to be safe
+// for concurrent use.
+var tplMu sync.Mutex
+
 func (ta TemplateArtifact) render() (string, error) {
 	buf := &bytes.Buffer{}
 
+	switch ta.Template.(type) {
+	case *texttpl.Template, *htmltpl.Template:
+		// safe to execute in parallel
+	default:
+		tplMu.Lock()
+		defer tplMu.Unlock()
+	}
+
 	if err := ta.Template.Execute(buf, ta.Data); err != nil {
 		return "", err
 	}
@@ -71,6 +86,9 @@ type GeneratorFile struct {
 	// Overwrite specifies whether or not this file should replace another file
 	// with the same name if a prior Plugin or Module has created one.
 	Overwrite bool
+	// Source is the proto File this file was generated from, if any. It is
+	// included in the header added by the StampHeaders PostProcessor.
+	Source File
 }
 
 // ProtoFile satisfies the GeneratorArtifact interface. An error is returned if
@@ -101,6 +119,9 @@ type GeneratorTemplateFile struct {
 	// Overwrite specifies whether or not this file should replace another file
 	// with the same name if a prior Plugin or Module has created one.
 	Overwrite bool
+	// Source is the proto File this file was generated from, if any. It is
+	// included in the header added by the StampHeaders PostProcessor.
+	Source File
 }
 
 // ProtoFile satisfies the GeneratorArtifact interface. An error is returned if
@@ -273,6 +294,14 @@ type CustomFile struct {
 	// Overwrite indicates if an existing file on disk should be overwritten by
 	// this file.
 	Overwrite bool
+	// HandEdits determines how an existing file is handled if it was modified
+	// since it was generated. Only files stamped with a checksum by the
+	// StampHeaders PostProcessor can be checked. Ignored if Overwrite is false.
+	HandEdits HandEditPolicy
+
+	// Source is the proto File this file was generated from, if any. It is
+	// included in the header added by the StampHeaders PostProcessor.
+	Source File
 }
 
 // CustomTemplateFile Artifacts are files generated from a Template directly
@@ -297,6 +326,14 @@ type CustomTemplateFile struct {
 	// Overwrite indicates if an existing file on disk should be overwritten by
 	// this file.
 	Overwrite bool
+	// HandEdits determines how an existing file is handled if it was modified
+	// since it was generated. Only files stamped with a checksum by the
+	// StampHeaders PostProcessor can be checked. Ignored if Overwrite is false.
+	HandEdits HandEditPolicy
+
+	// Source is the proto File this file was generated from, if any. It is
+	// included in the header added by the StampHeaders PostProcessor.
+	Source File
 }
 
 func cleanGeneratorFileName(name string) (string, error) {
@@ -314,7 +351,8 @@ func cleanGeneratorFileName(name string) (string, error) {
 // GeneratorError Artifacts are strings describing errors that happened in the
 // code generation, but have not been fatal. They'll be used to populate the
 // CodeGeneratorResponse's `error` field. Since that field is a string, multiple
-// GeneratorError Artifacts will be concatenated.
+// GeneratorError Artifacts will be concatenated. To report an error attached to
+// a specific Entity, use a Diagnostic instead.
 type GeneratorError struct {
 	Artifact
 
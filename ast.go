@@ -1,6 +1,12 @@
 package pgs
 
 import (
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 	plugin_go "google.golang.org/protobuf/types/pluginpb"
 )
@@ -21,6 +27,34 @@ type AST interface {
 	// (FQN). The FQN uses dot notation of the form ".{package}.{entity}", or the
 	// input path for Files.
 	Lookup(name string) (Entity, bool)
+
+	// References returns all uses of the Message or Enum e by the Fields,
+	// Extensions and Methods of the AST, in a deterministic order. Unlike
+	// Dependents, the index of References is independent of BiDirectional mode,
+	// and is built once, the first time it is queried. Nil is returned for any
+	// other type of Entity, or if e is unused.
+	References(e Entity) []Reference
+
+	// Prune returns a FileDescriptorSet containing only the roots and the
+	// Entities they transitively require: the types of their Fields, the inputs
+	// and outputs of Methods, the extendees of Extensions and the Extensions
+	// used in options. A File or Service root includes all of its contents,
+	// while a Method root retains only that Method of its Service. Messages and
+	// Enums retain all of their Fields and values. Messages enclosing a nested
+	// Entity that are not otherwise required are kept only as namespaces,
+	// without their Fields, options, ranges or reserved names.
+	//
+	// If keep is non-nil, it is called for each Message, Enum, EnumValue, Field,
+	// Extension, Service and Method, and those for which it returns false are
+	// omitted along with their contents. It is an error to omit an Entity that
+	// is required by another.
+	//
+	// Unused Files are removed and the imports of the remaining Files are
+	// rewritten to those they require, so that the set is self-contained and in
+	// topological order. Source locations are updated to match the retained
+	// elements. An error is returned if a root is not a File, Service, Method,
+	// Message, Enum or Extension, or if the resulting set is invalid.
+	Prune(roots []Entity, keep func(Entity) bool) (*descriptor.FileDescriptorSet, error)
 }
 
 type graph struct {
@@ -30,6 +64,8 @@ type graph struct {
 	packages   map[string]Package
 	entities   map[string]Entity
 	extensions []Extension
+	reg        *reflectRegistry
+	refs       referenceIndex
 }
 
 func (g *graph) Targets() map[string]File { return g.targets }
@@ -55,6 +91,7 @@ func ProcessCodeGeneratorRequest(debug Debugger, req *plugin_go.CodeGeneratorReq
 		packages:   make(map[string]Package),
 		entities:   make(map[string]Entity),
 		extensions: []Extension{},
+		reg:        newReflectRegistry(debug),
 	}
 
 	for _, f := range req.GetFileToGenerate() {
@@ -120,6 +157,104 @@ func ProcessFileDescriptorSetBidirectional(debug Debugger, fdset *descriptor.Fil
 	return ProcessCodeGeneratorRequestBidirectional(debug, &req)
 }
 
+// ProcessRegistry converts the files in a protoregistry.Files into a fully
+// connected AST entity graph. This allows executing Modules against the
+// descriptors of generated Go code linked into the binary (via
+// protoregistry.GlobalFiles, which is used if files is nil) or a registry built
+// at runtime, without invoking protoc.
+//
+// The targets are the paths of the files to generate, populating the Targets
+// map. If provided, only the targets and their transitive dependencies are
+// included in the AST; otherwise, all files in the registry are. An error is
+// returned if a target or dependency is missing from the registry. The Reflect
+// methods of the emitted AST's entities return the registry's descriptors.
+func ProcessRegistry(debug Debugger, files *protoregistry.Files, targets ...string) AST {
+	if files == nil {
+		files = protoregistry.GlobalFiles
+	}
+
+	req, err := registryRequest(files, targets)
+	debug.CheckErr(err, "unable to load files from registry")
+
+	g := ProcessCodeGeneratorRequest(debug, req)
+	g.(*graph).reg.base = files
+	return g
+}
+
+// ProcessRegistryBidirectional has the same functionality as ProcessRegistry,
+// but builds the AST so that files, messages, and enums have references to any
+// files or messages that directly or transitively depend on them.
+func ProcessRegistryBidirectional(debug Debugger, files *protoregistry.Files, targets ...string) AST {
+	if files == nil {
+		files = protoregistry.GlobalFiles
+	}
+
+	req, err := registryRequest(files, targets)
+	debug.CheckErr(err, "unable to load files from registry")
+
+	g := ProcessCodeGeneratorRequestBidirectional(debug, req)
+	g.(*graph).reg.base = files
+	return g
+}
+
+// registryRequest builds a CodeGeneratorRequest for targets from the files in
+// reg, in topological order. If an error occurs, the request contains only the
+// files (and their dependencies) loaded before it.
+func registryRequest(reg *protoregistry.Files, targets []string) (*plugin_go.CodeGeneratorRequest, error) {
+	req := &plugin_go.CodeGeneratorRequest{FileToGenerate: targets}
+	seen := make(map[string]struct{})
+
+	var visit func(fd protoreflect.FileDescriptor) error
+	visit = func(fd protoreflect.FileDescriptor) error {
+		if _, ok := seen[fd.Path()]; ok {
+			return nil
+		}
+		seen[fd.Path()] = struct{}{}
+
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			imp := imports.Get(i)
+			if imp.IsPlaceholder() {
+				return fmt.Errorf("dependency %q of %q not found in registry", imp.Path(), fd.Path())
+			}
+			if err := visit(imp.FileDescriptor); err != nil {
+				return err
+			}
+		}
+
+		req.ProtoFile = append(req.ProtoFile, protodesc.ToFileDescriptorProto(fd))
+		return nil
+	}
+
+	if len(targets) == 0 {
+		var all []protoreflect.FileDescriptor
+		reg.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+			all = append(all, fd)
+			return true
+		})
+		sort.Slice(all, func(i, j int) bool { return all[i].Path() < all[j].Path() })
+
+		for _, fd := range all {
+			if err := visit(fd); err != nil {
+				return req, err
+			}
+		}
+		return req, nil
+	}
+
+	for _, t := range targets {
+		fd, err := reg.FindFileByPath(t)
+		if err != nil {
+			return req, fmt.Errorf("target %q not found in registry", t)
+		}
+		if err = visit(fd); err != nil {
+			return req, err
+		}
+	}
+
+	return req, nil
+}
+
 func (g *graph) hydratePackage(f *descriptor.FileDescriptorProto) Package {
 	lookup := f.GetPackage()
 	if pkg, exists := g.packages[lookup]; exists {
@@ -136,6 +271,7 @@ func (g *graph) hydrateFile(pkg Package, f *descriptor.FileDescriptorProto) File
 	fl := &file{
 		pkg:  pkg,
 		desc: f,
+		reg:  g.reg,
 	}
 	if pkg := f.GetPackage(); pkg != "" {
 		fl.fqn = "." + pkg
@@ -197,11 +333,20 @@ func (g *graph) hydrateFile(pkg Package, f *descriptor.FileDescriptorProto) File
 	return fl
 }
 
-func (g *graph) hydrateSourceCodeInfo(f File, fd *descriptor.FileDescriptorProto) {
-	for _, loc := range fd.GetSourceCodeInfo().GetLocation() {
-		info := sci{desc: loc}
+func (g *graph) hydrateSourceCodeInfo(f *file, fd *descriptor.FileDescriptorProto) {
+	locs := fd.GetSourceCodeInfo().GetLocation()
+	f.locs = make(map[string]SourceCodeInfo, len(locs))
+
+	for _, loc := range locs {
+		info := sci{desc: loc, file: fd.GetName()}
 		path := loc.GetPath()
 
+		// protoc may emit multiple locations for the same path (eg, for each
+		// option of a field), in which case the first is kept
+		if _, ok := f.locs[pathKey(path)]; !ok {
+			f.locs[pathKey(path)] = info
+		}
+
 		if len(path) == 1 {
 			switch path[0] {
 			case syntaxPath:
@@ -10,6 +10,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
 	plugin_go "google.golang.org/protobuf/types/pluginpb"
 )
 
@@ -337,6 +339,104 @@ func TestGraph_Extensions(t *testing.T) {
 	assert.Len(t, ent.(Message).Extensions(), 1)
 }
 
+func TestGraph_Registry(t *testing.T) {
+	t.Parallel()
+
+	fdset := readFileDescSet(t, "testdata/fdset.bin")
+	files, err := protodesc.NewFiles(fdset)
+	require.NoError(t, err)
+
+	t.Run("targets", func(t *testing.T) {
+		t.Parallel()
+
+		d := InitMockDebugger()
+		ast := ProcessRegistry(d, files, "kitchen/sink.proto")
+		require.False(t, d.Failed(), "failed to build graph from registry")
+		require.NoError(t, d.Err())
+
+		require.Contains(t, ast.Targets(), "kitchen/sink.proto")
+		assert.True(t, ast.Targets()["kitchen/sink.proto"].BuildTarget())
+
+		_, ok := ast.Lookup("google/protobuf/timestamp.proto")
+		assert.True(t, ok, "dependencies should be included")
+		_, ok = ast.Lookup("kitchen/kitchen.proto")
+		assert.False(t, ok, "non-dependencies should be excluded")
+
+		sink, ok := ast.Lookup(".kitchen.Sink")
+		require.True(t, ok)
+		want, err := files.FindDescriptorByName("kitchen.Sink")
+		require.NoError(t, err)
+		assert.Equal(t, want, sink.(Message).Reflect())
+	})
+
+	t.Run("all files", func(t *testing.T) {
+		t.Parallel()
+
+		d := InitMockDebugger()
+		ast := ProcessRegistry(d, files)
+		require.False(t, d.Failed(), "failed to build graph from registry")
+		require.NoError(t, d.Err())
+
+		assert.Empty(t, ast.Targets())
+		_, ok := ast.Lookup(".kitchen.Kitchen")
+		assert.True(t, ok)
+	})
+
+	t.Run("bidirectional", func(t *testing.T) {
+		t.Parallel()
+
+		d := InitMockDebugger()
+		ast := ProcessRegistryBidirectional(d, files)
+		require.False(t, d.Failed(), "failed to build graph from registry")
+
+		finish, ok := ast.Lookup(".kitchen.Sink.Material.Finish")
+		require.True(t, ok)
+		assert.Len(t, finish.(Enum).Dependents(), 3)
+	})
+
+	t.Run("global", func(t *testing.T) {
+		t.Parallel()
+
+		d := InitMockDebugger()
+		ast := ProcessRegistry(d, nil, "google/protobuf/compiler/plugin.proto")
+		require.False(t, d.Failed(), "failed to build graph from registry")
+		require.NoError(t, d.Err())
+
+		req, ok := ast.Lookup(".google.protobuf.compiler.CodeGeneratorRequest")
+		require.True(t, ok)
+		assert.Equal(t,
+			(&plugin_go.CodeGeneratorRequest{}).ProtoReflect().Descriptor(),
+			req.(Message).Reflect())
+	})
+
+	t.Run("missing target", func(t *testing.T) {
+		t.Parallel()
+
+		d := InitMockDebugger()
+		ProcessRegistry(d, files, "fizz/buzz.proto")
+		assert.EqualError(t, d.Err(), `target "fizz/buzz.proto" not found in registry`)
+	})
+
+	t.Run("missing dependency", func(t *testing.T) {
+		t.Parallel()
+
+		fd, err := protodesc.FileOptions{AllowUnresolvable: true}.New(&descriptor.FileDescriptorProto{
+			Name:       proto.String("foo.proto"),
+			Dependency: []string{"bar.proto"},
+		}, nil)
+		require.NoError(t, err)
+
+		reg := new(protoregistry.Files)
+		require.NoError(t, reg.RegisterFile(fd))
+
+		d := InitMockDebugger()
+		ast := ProcessRegistry(d, reg, "foo.proto")
+		assert.EqualError(t, d.Err(), `dependency "bar.proto" of "foo.proto" not found in registry`)
+		_, ok := ast.Lookup("foo.proto")
+		assert.False(t, ok)
+	})
+}
+
 func TestGraph_Bidirectional(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,181 @@
+package pgs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+// defaultValue returns the typed default value of f, parsing its descriptor's
+// default_value if set. See Field.DefaultValue for the types returned.
+func defaultValue(f Field) (interface{}, error) {
+	if f.Type().IsRepeated() || f.Type().IsMap() {
+		return nil, nil
+	}
+
+	s, set := f.Descriptor().GetDefaultValue(), f.Descriptor().DefaultValue != nil
+
+	switch f.Descriptor().GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_INT32,
+		descriptor.FieldDescriptorProto_TYPE_SINT32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		if !set {
+			return int32(0), nil
+		}
+		v, err := strconv.ParseInt(s, 0, 32)
+		return int32(v), err
+	case descriptor.FieldDescriptorProto_TYPE_INT64,
+		descriptor.FieldDescriptorProto_TYPE_SINT64,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		if !set {
+			return int64(0), nil
+		}
+		return strconv.ParseInt(s, 0, 64)
+	case descriptor.FieldDescriptorProto_TYPE_UINT32,
+		descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		if !set {
+			return uint32(0), nil
+		}
+		v, err := strconv.ParseUint(s, 0, 32)
+		return uint32(v), err
+	case descriptor.FieldDescriptorProto_TYPE_UINT64,
+		descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		if !set {
+			return uint64(0), nil
+		}
+		return strconv.ParseUint(s, 0, 64)
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		if !set {
+			return float32(0), nil
+		}
+		v, err := strconv.ParseFloat(s, 32)
+		return float32(v), err
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		if !set {
+			return float64(0), nil
+		}
+		return strconv.ParseFloat(s, 64)
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		if !set {
+			return false, nil
+		}
+		return strconv.ParseBool(s)
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return s, nil
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		if !set {
+			return []byte(nil), nil
+		}
+		return unescapeBytes(s)
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		return defaultEnumValue(f.Type().Enum(), s, set)
+	default:
+		return nil, nil
+	}
+}
+
+// defaultEnumValue returns the EnumValue of e named s, or its first value if
+// the default is not set.
+func defaultEnumValue(e Enum, s string, set bool) (EnumValue, error) {
+	if e == nil || len(e.Values()) == 0 {
+		return nil, fmt.Errorf("unresolved enum default %q", s)
+	}
+
+	if !set {
+		return e.Values()[0], nil
+	}
+
+	for _, v := range e.Values() {
+		if v.Name().String() == s {
+			return v, nil
+		}
+	}
+
+	return nil, fmt.Errorf("enum %s has no value %q", e.FullyQualifiedName(), s)
+}
+
+// unescapeBytes decodes a bytes default value, which protoc encodes using C
+// escape sequences.
+func unescapeBytes(s string) ([]byte, error) {
+	out := make([]byte, 0, len(s))
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			out = append(out, s[i])
+			continue
+		}
+
+		i++
+		if i == len(s) {
+			return nil, fmt.Errorf("invalid escape sequence at end of %q", s)
+		}
+
+		switch c := s[i]; c {
+		case 'a':
+			out = append(out, '\a')
+		case 'b':
+			out = append(out, '\b')
+		case 'f':
+			out = append(out, '\f')
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 't':
+			out = append(out, '\t')
+		case 'v':
+			out = append(out, '\v')
+		case '\\', '\'', '"', '?':
+			out = append(out, c)
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			j := i + 1
+			for j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7' {
+				j++
+			}
+			v, err := strconv.ParseUint(s[i:j], 8, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid octal escape in %q", s)
+			}
+			out = append(out, byte(v))
+			i = j - 1
+		case 'x', 'X':
+			j := i + 1
+			for j < len(s) && j < i+3 && strings.IndexByte("0123456789abcdefABCDEF", s[j]) >= 0 {
+				j++
+			}
+			if j == i+1 {
+				return nil, fmt.Errorf("invalid hex escape in %q", s)
+			}
+			v, _ := strconv.ParseUint(s[i+1:j], 16, 8)
+			out = append(out, byte(v))
+			i = j - 1
+		default:
+			return nil, fmt.Errorf("invalid escape sequence \\%c in %q", c, s)
+		}
+	}
+
+	return out, nil
+}
+
+// jsonCamelCase derives the JSON name of a field from its name, as protoc
+// does when json_name is not set: underscores are removed, and the letter
+// following each is capitalized.
+func jsonCamelCase(s string) string {
+	var b strings.Builder
+	upper := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '_' {
+			upper = true
+			continue
+		}
+		if upper && c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		upper = false
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnescapeBytes(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string][]byte{
+		"":               {},
+		"abc":            []byte("abc"),
+		`\000\001\377`:   {0, 1, 255},
+		`\0a`:            {0, 'a'},
+		`\x00\xffz`:      {0, 255, 'z'},
+		`\xAb\x9`:        {0xab, 9},
+		`\a\b\f\n\r\t\v`: []byte("\a\b\f\n\r\t\v"),
+		`\\\'\"\?`:       []byte(`\'"?`),
+		`\1234`:          {0123, '4'},
+		"caf\xc3\xa9\\n": []byte("caf\xc3\xa9\n"),
+	}
+
+	for in, expected := range tests {
+		out, err := unescapeBytes(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, expected, out, in)
+	}
+
+	for _, in := range []string{`\`, `\q`, `\xg`, `\777`} {
+		_, err := unescapeBytes(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestJSONCamelCase(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"foo":               "foo",
+		"foo_bar":           "fooBar",
+		"foo__bar":          "fooBar",
+		"_foo":              "Foo",
+		"foo_":              "foo",
+		"foo_1bar":          "foo1bar",
+		"FooBar":            "FooBar",
+		"foo_bar__baz_1qux": "fooBarBaz1qux",
+	}
+
+	for in, expected := range tests {
+		assert.Equal(t, expected, jsonCamelCase(in), in)
+	}
+}
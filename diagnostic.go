@@ -0,0 +1,90 @@
+package pgs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity describes how a Diagnostic impacts code generation.
+type Severity int
+
+const (
+	// SeverityError Diagnostics are reported to protoc as an error, failing the
+	// code generation once all modules have executed.
+	SeverityError Severity = iota
+
+	// SeverityWarning Diagnostics are logged but do not fail code generation.
+	SeverityWarning
+)
+
+// String returns a string representation of the severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// A Diagnostic Artifact describes an error or warning attached to an Entity.
+// Unlike the Fail methods of a Debugger, Diagnostics do not terminate the
+// process; all Diagnostics from every Module are collected and error
+// Diagnostics are emitted together in the CodeGeneratorResponse's `error`
+// field, separated by "; " like other errors, in the same format protoc uses
+// for its own errors:
+//
+//	path/to/file.proto:12:3: message
+type Diagnostic struct {
+	Artifact
+
+	// Entity is the source of the Diagnostic. Its SourceCodeInfo is used to
+	// resolve the position reported. If nil, no position is reported.
+	Entity Entity
+
+	// Severity of the Diagnostic.
+	Severity Severity
+
+	// Message describes the problem.
+	Message string
+}
+
+// Position returns the location of the Diagnostic's Entity in the form
+// "file:line:col", with 1-based line and column numbers. If the Entity has no
+// source location (eg, if the input lacked source info), only the file name is
+// returned. An empty string is returned if Entity is nil.
+func (d Diagnostic) Position() string {
+	if d.Entity == nil {
+		return ""
+	}
+
+	name := d.Entity.File().Name().String()
+	if _, ok := d.Entity.(File); ok {
+		return name
+	}
+
+	info := d.Entity.SourceCodeInfo()
+	if info == nil {
+		return name
+	}
+
+	return position(name, info.Span())
+}
+
+// String satisfies the fmt.Stringer interface, rendering the Diagnostic in
+// protoc's standard format. Warnings are prefixed with "warning: ".
+func (d Diagnostic) String() string {
+	parts := make([]string, 0, 3)
+
+	if pos := d.Position(); pos != "" {
+		parts = append(parts, pos)
+	}
+
+	if d.Severity == SeverityWarning {
+		parts = append(parts, d.Severity.String())
+	}
+
+	return strings.Join(append(parts, d.Message), ": ")
+}
@@ -0,0 +1,48 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestSeverity_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "error", SeverityError.String())
+	assert.Equal(t, "warning", SeverityWarning.String())
+	assert.Equal(t, "Severity(5)", Severity(5).String())
+}
+
+func TestDiagnostic_Position(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, Diagnostic{}.Position())
+
+	m := dummyMsg()
+	assert.Equal(t, "file.proto", Diagnostic{Entity: m}.Position())
+	assert.Equal(t, "file.proto", Diagnostic{Entity: m.File()}.Position())
+
+	m.addSourceCodeInfo(sci{desc: &descriptor.SourceCodeInfo_Location{
+		Span: []int32{4, 0, 7, 1},
+	}})
+	assert.Equal(t, "file.proto:5:1", Diagnostic{Entity: m}.Position())
+}
+
+func TestDiagnostic_String(t *testing.T) {
+	t.Parallel()
+
+	m := dummyMsg()
+	m.addSourceCodeInfo(sci{desc: &descriptor.SourceCodeInfo_Location{
+		Span: []int32{2, 8, 12},
+	}})
+
+	assert.Equal(t, "file.proto:3:9: bad message",
+		Diagnostic{Entity: m, Message: "bad message"}.String())
+
+	assert.Equal(t, "file.proto:3:9: warning: odd message",
+		Diagnostic{Entity: m, Severity: SeverityWarning, Message: "odd message"}.String())
+
+	assert.Equal(t, "no entity", Diagnostic{Message: "no entity"}.String())
+}
@@ -0,0 +1,843 @@
+package pgs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Breakage is a set of flags describing the ways in which a Change breaks
+// compatibility with the previous version of the protos.
+type Breakage uint8
+
+const (
+	// WireBreaking changes break the binary encoding: messages serialized with
+	// one version are misinterpreted by the other, or RPCs fail.
+	WireBreaking Breakage = 1 << iota
+
+	// JSONBreaking changes break the JSON encoding of messages.
+	JSONBreaking
+
+	// SourceBreaking changes break code generated from the protos, such as by
+	// renaming or removing a declaration.
+	SourceBreaking
+)
+
+// String returns the names of the flags set in b, separated by "|", or "none"
+// if b is empty.
+func (b Breakage) String() string {
+	var names []string
+	for _, f := range []struct {
+		flag Breakage
+		name string
+	}{{WireBreaking, "wire"}, {JSONBreaking, "json"}, {SourceBreaking, "source"}} {
+		if b&f.flag != 0 {
+			names = append(names, f.name)
+		}
+	}
+
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// ChangeKind identifies the type of a Change.
+type ChangeKind int
+
+// The kinds of Changes reported by DiffAST.
+const (
+	FileAdded ChangeKind = iota
+	FileRemoved
+	MessageAdded
+	MessageRemoved
+	FieldAdded
+	FieldRemoved
+	FieldRenamed
+	FieldJSONNameChanged
+	FieldNumberChanged
+	FieldTypeChanged
+	FieldLabelChanged
+	FieldOneOfChanged
+	EnumAdded
+	EnumRemoved
+	EnumValueAdded
+	EnumValueRemoved
+	EnumValueRenamed
+	EnumValueNumberChanged
+	ExtensionAdded
+	ExtensionRemoved
+	ServiceAdded
+	ServiceRemoved
+	ServiceRenamed
+	MethodAdded
+	MethodRemoved
+	MethodRenamed
+	MethodInputChanged
+	MethodOutputChanged
+	MethodStreamingChanged
+)
+
+var changeKindNames = [...]string{
+	FileAdded:              "FileAdded",
+	FileRemoved:            "FileRemoved",
+	MessageAdded:           "MessageAdded",
+	MessageRemoved:         "MessageRemoved",
+	FieldAdded:             "FieldAdded",
+	FieldRemoved:           "FieldRemoved",
+	FieldRenamed:           "FieldRenamed",
+	FieldJSONNameChanged:   "FieldJSONNameChanged",
+	FieldNumberChanged:     "FieldNumberChanged",
+	FieldTypeChanged:       "FieldTypeChanged",
+	FieldLabelChanged:      "FieldLabelChanged",
+	FieldOneOfChanged:      "FieldOneOfChanged",
+	EnumAdded:              "EnumAdded",
+	EnumRemoved:            "EnumRemoved",
+	EnumValueAdded:         "EnumValueAdded",
+	EnumValueRemoved:       "EnumValueRemoved",
+	EnumValueRenamed:       "EnumValueRenamed",
+	EnumValueNumberChanged: "EnumValueNumberChanged",
+	ExtensionAdded:         "ExtensionAdded",
+	ExtensionRemoved:       "ExtensionRemoved",
+	ServiceAdded:           "ServiceAdded",
+	ServiceRemoved:         "ServiceRemoved",
+	ServiceRenamed:         "ServiceRenamed",
+	MethodAdded:            "MethodAdded",
+	MethodRemoved:          "MethodRemoved",
+	MethodRenamed:          "MethodRenamed",
+	MethodInputChanged:     "MethodInputChanged",
+	MethodOutputChanged:    "MethodOutputChanged",
+	MethodStreamingChanged: "MethodStreamingChanged",
+}
+
+// String returns the name of the ChangeKind.
+func (k ChangeKind) String() string {
+	if k >= 0 && int(k) < len(changeKindNames) {
+		return changeKindNames[k]
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// A Change is a difference between two versions of the protos, as reported by
+// DiffAST.
+type Change struct {
+	// Kind identifies the type of the Change.
+	Kind ChangeKind
+
+	// Previous is the changed Entity in the previous AST, or nil if it was
+	// added.
+	Previous Entity
+
+	// Current is the changed Entity in the current AST, or nil if it was
+	// removed.
+	Current Entity
+
+	// Breakage describes how the Change breaks compatibility. It is empty for
+	// compatible changes, such as additions.
+	Breakage Breakage
+
+	// Message describes the Change.
+	Message string
+
+	// parent is the Entity in the current AST containing a removed Entity.
+	parent Entity
+}
+
+// Breaking returns true if the Change breaks compatibility in any way.
+func (c Change) Breaking() bool { return c.Breakage != 0 }
+
+// Entity returns the Entity in the current AST to attach the Change to, such
+// as when reporting it as a Diagnostic. For removals, this is the Entity that
+// contained the removed Entity. Nil is returned for removed Files, and
+// removals from Entities that were also removed.
+func (c Change) Entity() Entity {
+	if c.Current != nil {
+		return c.Current
+	}
+	return c.parent
+}
+
+// String renders the Change's Message, followed by its Breakage if it is
+// breaking.
+func (c Change) String() string {
+	if !c.Breaking() {
+		return c.Message
+	}
+	return fmt.Sprintf("%s (breaking: %s)", c.Message, c.Breakage)
+}
+
+// DiffAST compares all the Files, Messages, Enums, Services and Extensions of
+// the previous and current ASTs, returning the Changes between them in a
+// deterministic order. The previous AST is typically loaded from a
+// FileDescriptorSet with ProcessFileDescriptorSet.
+//
+// Declarations are matched by their fully qualified names, so a moved Message
+// is reported as removed and added. Fields are matched by number and then by
+// name, EnumValues by name and then by number, and Methods by name. A removed
+// Service or Method is reported as renamed if exactly one added Service or
+// Method has the same signature.
+func DiffAST(previous, current AST) []Change {
+	d := &differ{prev: previous, curr: current}
+
+	d.files()
+	d.messages()
+	d.enums()
+	d.extensions()
+	d.services()
+
+	return d.changes
+}
+
+type differ struct {
+	prev, curr AST
+	changes    []Change
+}
+
+func (d *differ) add(c Change) { d.changes = append(d.changes, c) }
+
+func (d *differ) lookup(ast AST, fqn string) Entity {
+	e, _ := ast.Lookup(fqn)
+	return e
+}
+
+// parent returns the Entity in the current AST containing the Entity e of
+// the previous AST, or its File if e is declared at the top level.
+func (d *differ) parent(e Entity) Entity {
+	if p := d.lookup(d.curr, parentScope(e.FullyQualifiedName())); p != nil {
+		return p
+	}
+	return d.lookup(d.curr, e.File().Name().String())
+}
+
+func (d *differ) files() {
+	prev, curr := astFiles(d.prev), astFiles(d.curr)
+
+	for _, f := range prev {
+		if d.lookup(d.curr, f.Name().String()) == nil {
+			d.add(Change{
+				Kind:     FileRemoved,
+				Previous: f,
+				Breakage: SourceBreaking,
+				Message:  fmt.Sprintf("file %s was removed", f.Name()),
+			})
+		}
+	}
+
+	for _, f := range curr {
+		if d.lookup(d.prev, f.Name().String()) == nil {
+			d.add(Change{
+				Kind:    FileAdded,
+				Current: f,
+				Message: fmt.Sprintf("file %s was added", f.Name()),
+			})
+		}
+	}
+}
+
+func (d *differ) messages() {
+	for _, pm := range astMessages(d.prev) {
+		cm, ok := d.lookup(d.curr, pm.FullyQualifiedName()).(Message)
+		if !ok {
+			d.add(Change{
+				Kind:     MessageRemoved,
+				Previous: pm,
+				Breakage: SourceBreaking,
+				Message:  fmt.Sprintf("message %s was removed", pm.FullyQualifiedName()),
+				parent:   d.parent(pm),
+			})
+			continue
+		}
+		d.fields(pm, cm)
+	}
+
+	for _, cm := range astMessages(d.curr) {
+		if _, ok := d.lookup(d.prev, cm.FullyQualifiedName()).(Message); !ok {
+			d.add(Change{
+				Kind:    MessageAdded,
+				Current: cm,
+				Message: fmt.Sprintf("message %s was added", cm.FullyQualifiedName()),
+			})
+		}
+	}
+}
+
+// fields compares the Fields of the previous and current versions of a
+// Message.
+func (d *differ) fields(pm, cm Message) {
+	matched := map[Field]bool{}
+
+	byNumber := func(flds []Field, n int32) Field {
+		for _, f := range flds {
+			if f.Descriptor().GetNumber() == n && !matched[f] {
+				return f
+			}
+		}
+		return nil
+	}
+
+	byName := func(flds []Field, name Name) Field {
+		for _, f := range flds {
+			if f.Name() == name && !matched[f] {
+				return f
+			}
+		}
+		return nil
+	}
+
+	var removed []Field
+	for _, pf := range pm.Fields() {
+		cf := byNumber(cm.Fields(), pf.Descriptor().GetNumber())
+		if cf == nil {
+			removed = append(removed, pf)
+			continue
+		}
+		matched[cf] = true
+
+		if pf.Name() != cf.Name() {
+			b := SourceBreaking
+			if pf.JSONName() != cf.JSONName() {
+				b |= JSONBreaking
+			}
+			d.add(Change{
+				Kind:     FieldRenamed,
+				Previous: pf,
+				Current:  cf,
+				Breakage: b,
+				Message:  fmt.Sprintf("field %s (%d) was renamed to %s", pf.FullyQualifiedName(), pf.Descriptor().GetNumber(), cf.Name()),
+			})
+		} else if pf.JSONName() != cf.JSONName() {
+			d.add(Change{
+				Kind:     FieldJSONNameChanged,
+				Previous: pf,
+				Current:  cf,
+				Breakage: JSONBreaking,
+				Message:  fmt.Sprintf("field %s changed JSON name from %q to %q", cf.FullyQualifiedName(), pf.JSONName(), cf.JSONName()),
+			})
+		}
+
+		d.field(pf, cf)
+
+		if po, co := oneOfName(pf), oneOfName(cf); po != co {
+			d.add(Change{
+				Kind:     FieldOneOfChanged,
+				Previous: pf,
+				Current:  cf,
+				Breakage: WireBreaking | JSONBreaking | SourceBreaking,
+				Message:  fmt.Sprintf("field %s moved from %s to %s", cf.FullyQualifiedName(), describeOneOf(po), describeOneOf(co)),
+			})
+		}
+	}
+
+	for _, pf := range removed {
+		if cf := byName(cm.Fields(), pf.Name()); cf != nil {
+			matched[cf] = true
+			d.add(Change{
+				Kind:     FieldNumberChanged,
+				Previous: pf,
+				Current:  cf,
+				Breakage: WireBreaking,
+				Message: fmt.Sprintf("field %s changed number from %d to %d",
+					cf.FullyQualifiedName(), pf.Descriptor().GetNumber(), cf.Descriptor().GetNumber()),
+			})
+			d.field(pf, cf)
+			continue
+		}
+
+		b := SourceBreaking
+		if !cm.IsReservedNumber(pf.Descriptor().GetNumber()) {
+			b |= WireBreaking
+		}
+		if !cm.IsReservedName(pf.Name().String()) {
+			b |= JSONBreaking
+		}
+		d.add(Change{
+			Kind:     FieldRemoved,
+			Previous: pf,
+			Breakage: b,
+			Message:  fmt.Sprintf("field %s (%d) was removed", pf.FullyQualifiedName(), pf.Descriptor().GetNumber()),
+			parent:   cm,
+		})
+	}
+
+	for _, cf := range cm.Fields() {
+		if !matched[cf] {
+			d.add(Change{
+				Kind:    FieldAdded,
+				Current: cf,
+				Message: fmt.Sprintf("field %s (%d) was added", cf.FullyQualifiedName(), cf.Descriptor().GetNumber()),
+			})
+		}
+	}
+}
+
+// field compares the type and label of the previous and current versions of
+// a Field or Extension. Changes in presence that follow from a change of type
+// or OneOf are not reported separately.
+func (d *differ) field(pf, cf Field) {
+	pt, ct := fieldTypeName(pf), fieldTypeName(cf)
+	if pt != ct {
+		b := JSONBreaking | SourceBreaking
+		if pg, cg := wireGroup(pf), wireGroup(cf); pg == "" || pg != cg {
+			b |= WireBreaking
+		}
+		d.add(Change{
+			Kind:     FieldTypeChanged,
+			Previous: pf,
+			Current:  cf,
+			Breakage: b,
+			Message:  fmt.Sprintf("field %s changed type from %s to %s", cf.FullyQualifiedName(), pt, ct),
+		})
+	}
+
+	pl, cl := fieldLabel(pf), fieldLabel(cf)
+	if pl == cl {
+		return
+	}
+
+	var b Breakage
+	switch {
+	case pl == "repeated" || cl == "repeated":
+		b = WireBreaking | JSONBreaking | SourceBreaking
+	case pl == "required" || cl == "required":
+		b = WireBreaking | SourceBreaking
+	case pt != ct || pf.InRealOneOf() || cf.InRealOneOf():
+		return
+	default:
+		b = SourceBreaking
+	}
+
+	d.add(Change{
+		Kind:     FieldLabelChanged,
+		Previous: pf,
+		Current:  cf,
+		Breakage: b,
+		Message:  fmt.Sprintf("field %s changed label from %s to %s", cf.FullyQualifiedName(), pl, cl),
+	})
+}
+
+// fieldTypeName describes the type of f, excluding its label.
+func fieldTypeName(f Field) string {
+	if ft := f.Type(); ft != nil && ft.IsMap() {
+		return fmt.Sprintf("map<%s, %s>", elemTypeName(ft.Key()), elemTypeName(ft.Element()))
+	}
+
+	if name := f.Descriptor().GetTypeName(); name != "" {
+		return name
+	}
+	return protoTypeName(f.Type().ProtoType())
+}
+
+func elemTypeName(el FieldTypeElem) string {
+	if e := fieldTypeElemEntity(el); e != nil {
+		return e.FullyQualifiedName()
+	}
+	return protoTypeName(el.ProtoType())
+}
+
+func protoTypeName(pt ProtoType) string {
+	return strings.ToLower(strings.TrimPrefix(pt.Proto().String(), "TYPE_"))
+}
+
+// wireGroup returns the name of the set of wire-compatible types that f's
+// type belongs to, or an empty string if it is only compatible with itself.
+func wireGroup(f Field) string {
+	if f.Type() != nil && f.Type().IsMap() {
+		return ""
+	}
+
+	switch f.Descriptor().GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_INT32, descriptor.FieldDescriptorProto_TYPE_INT64,
+		descriptor.FieldDescriptorProto_TYPE_UINT32, descriptor.FieldDescriptorProto_TYPE_UINT64,
+		descriptor.FieldDescriptorProto_TYPE_BOOL, descriptor.FieldDescriptorProto_TYPE_ENUM:
+		return "varint"
+	case descriptor.FieldDescriptorProto_TYPE_SINT32, descriptor.FieldDescriptorProto_TYPE_SINT64:
+		return "zigzag"
+	case descriptor.FieldDescriptorProto_TYPE_FIXED32, descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return "fixed32"
+	case descriptor.FieldDescriptorProto_TYPE_FIXED64, descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		return "fixed64"
+	case descriptor.FieldDescriptorProto_TYPE_STRING, descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return "bytes"
+	default:
+		return ""
+	}
+}
+
+// fieldLabel describes the cardinality of f: repeated (including maps),
+// required, optional (for explicit presence), or implicit.
+func fieldLabel(f Field) string {
+	switch {
+	case f.Descriptor().GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED:
+		return "repeated"
+	case f.Required():
+		return "required"
+	case f.HasPresence():
+		return "optional"
+	default:
+		return "implicit"
+	}
+}
+
+// oneOfName returns the name of the real OneOf containing f, if any.
+func oneOfName(f Field) Name {
+	if !f.InRealOneOf() {
+		return ""
+	}
+	return f.OneOf().Name()
+}
+
+func describeOneOf(n Name) string {
+	if n == "" {
+		return "no oneof"
+	}
+	return "oneof " + n.String()
+}
+
+func astEnums(ast AST) []Enum {
+	var enums []Enum
+	for _, f := range astFiles(ast) {
+		enums = append(enums, f.AllEnums()...)
+	}
+	return enums
+}
+
+func (d *differ) enums() {
+	for _, pe := range astEnums(d.prev) {
+		ce, ok := d.lookup(d.curr, pe.FullyQualifiedName()).(Enum)
+		if !ok {
+			d.add(Change{
+				Kind:     EnumRemoved,
+				Previous: pe,
+				Breakage: SourceBreaking,
+				Message:  fmt.Sprintf("enum %s was removed", pe.FullyQualifiedName()),
+				parent:   d.parent(pe),
+			})
+			continue
+		}
+		d.values(pe, ce)
+	}
+
+	for _, ce := range astEnums(d.curr) {
+		if _, ok := d.lookup(d.prev, ce.FullyQualifiedName()).(Enum); !ok {
+			d.add(Change{
+				Kind:    EnumAdded,
+				Current: ce,
+				Message: fmt.Sprintf("enum %s was added", ce.FullyQualifiedName()),
+			})
+		}
+	}
+}
+
+// values compares the EnumValues of the previous and current versions of an
+// Enum.
+func (d *differ) values(pe, ce Enum) {
+	matched := map[EnumValue]bool{}
+
+	find := func(match func(v EnumValue) bool) EnumValue {
+		for _, v := range ce.Values() {
+			if !matched[v] && match(v) {
+				return v
+			}
+		}
+		return nil
+	}
+
+	var removed []EnumValue
+	for _, pv := range pe.Values() {
+		cv := find(func(v EnumValue) bool { return v.Name() == pv.Name() })
+		if cv == nil {
+			removed = append(removed, pv)
+			continue
+		}
+		matched[cv] = true
+
+		if pv.Value() != cv.Value() {
+			d.add(Change{
+				Kind:     EnumValueNumberChanged,
+				Previous: pv,
+				Current:  cv,
+				Breakage: WireBreaking,
+				Message:  fmt.Sprintf("enum value %s changed number from %d to %d", cv.FullyQualifiedName(), pv.Value(), cv.Value()),
+			})
+		}
+	}
+
+	for _, pv := range removed {
+		if cv := find(func(v EnumValue) bool { return v.Value() == pv.Value() }); cv != nil {
+			matched[cv] = true
+			d.add(Change{
+				Kind:     EnumValueRenamed,
+				Previous: pv,
+				Current:  cv,
+				Breakage: JSONBreaking | SourceBreaking,
+				Message:  fmt.Sprintf("enum value %s (%d) was renamed to %s", pv.FullyQualifiedName(), pv.Value(), cv.Name()),
+			})
+			continue
+		}
+
+		b := SourceBreaking
+		if !ce.IsReservedNumber(pv.Value()) && indexOf(len(ce.Values()), func(i int) bool { return ce.Values()[i].Value() == pv.Value() }) < 0 {
+			b |= WireBreaking
+		}
+		if !ce.IsReservedName(pv.Name().String()) {
+			b |= JSONBreaking
+		}
+		d.add(Change{
+			Kind:     EnumValueRemoved,
+			Previous: pv,
+			Breakage: b,
+			Message:  fmt.Sprintf("enum value %s (%d) was removed", pv.FullyQualifiedName(), pv.Value()),
+			parent:   ce,
+		})
+	}
+
+	for _, cv := range ce.Values() {
+		if !matched[cv] {
+			d.add(Change{
+				Kind:    EnumValueAdded,
+				Current: cv,
+				Message: fmt.Sprintf("enum value %s (%d) was added", cv.FullyQualifiedName(), cv.Value()),
+			})
+		}
+	}
+}
+
+func astExtensions(ast AST) []Extension {
+	var exts []Extension
+	for _, f := range astFiles(ast) {
+		exts = append(exts, f.DefinedExtensions()...)
+		for _, m := range f.AllMessages() {
+			exts = append(exts, m.DefinedExtensions()...)
+		}
+	}
+	return exts
+}
+
+func (d *differ) extensions() {
+	for _, px := range astExtensions(d.prev) {
+		cx, ok := d.lookup(d.curr, px.FullyQualifiedName()).(Extension)
+		if !ok {
+			d.add(Change{
+				Kind:     ExtensionRemoved,
+				Previous: px,
+				Breakage: JSONBreaking | SourceBreaking,
+				Message:  fmt.Sprintf("extension %s (%d) was removed", px.FullyQualifiedName(), px.Descriptor().GetNumber()),
+				parent:   d.parent(px),
+			})
+			continue
+		}
+
+		if pn, cn := px.Descriptor().GetNumber(), cx.Descriptor().GetNumber(); pn != cn {
+			d.add(Change{
+				Kind:     FieldNumberChanged,
+				Previous: px,
+				Current:  cx,
+				Breakage: WireBreaking,
+				Message:  fmt.Sprintf("extension %s changed number from %d to %d", cx.FullyQualifiedName(), pn, cn),
+			})
+		}
+		d.field(px, cx)
+	}
+
+	for _, cx := range astExtensions(d.curr) {
+		if _, ok := d.lookup(d.prev, cx.FullyQualifiedName()).(Extension); !ok {
+			d.add(Change{
+				Kind:    ExtensionAdded,
+				Current: cx,
+				Message: fmt.Sprintf("extension %s (%d) was added", cx.FullyQualifiedName(), cx.Descriptor().GetNumber()),
+			})
+		}
+	}
+}
+
+func astServices(ast AST) []Service {
+	var svcs []Service
+	for _, f := range astFiles(ast) {
+		svcs = append(svcs, f.Services()...)
+	}
+	return svcs
+}
+
+// methodSignature describes the input, output and streaming modes of m.
+func methodSignature(m Method) string {
+	return fmt.Sprintf("(%s %s) returns (%s %s)",
+		streaming(m.ClientStreaming()), m.Input().FullyQualifiedName(),
+		streaming(m.ServerStreaming()), m.Output().FullyQualifiedName())
+}
+
+// serviceSignature describes the Methods of s, irrespective of their order.
+func serviceSignature(s Service) string {
+	sigs := make([]string, 0, len(s.Methods()))
+	for _, m := range s.Methods() {
+		sigs = append(sigs, m.Name().String()+methodSignature(m))
+	}
+	sort.Strings(sigs)
+	return strings.Join(sigs, ";")
+}
+
+func (d *differ) services() {
+	prev, curr := astServices(d.prev), astServices(d.curr)
+
+	var added []Service
+	for _, cs := range curr {
+		if _, ok := d.lookup(d.prev, cs.FullyQualifiedName()).(Service); !ok {
+			added = append(added, cs)
+		}
+	}
+
+	renamed := map[Service]bool{}
+	for _, ps := range prev {
+		if cs, ok := d.lookup(d.curr, ps.FullyQualifiedName()).(Service); ok {
+			d.methods(ps, cs)
+			continue
+		}
+
+		var candidates []Service
+		for _, cs := range added {
+			if !renamed[cs] && cs.Package().ProtoName() == ps.Package().ProtoName() && serviceSignature(cs) == serviceSignature(ps) {
+				candidates = append(candidates, cs)
+			}
+		}
+
+		if len(candidates) == 1 {
+			renamed[candidates[0]] = true
+			d.add(Change{
+				Kind:     ServiceRenamed,
+				Previous: ps,
+				Current:  candidates[0],
+				Breakage: WireBreaking | JSONBreaking | SourceBreaking,
+				Message:  fmt.Sprintf("service %s was renamed to %s", ps.FullyQualifiedName(), candidates[0].Name()),
+			})
+			continue
+		}
+
+		d.add(Change{
+			Kind:     ServiceRemoved,
+			Previous: ps,
+			Breakage: WireBreaking | JSONBreaking | SourceBreaking,
+			Message:  fmt.Sprintf("service %s was removed", ps.FullyQualifiedName()),
+			parent:   d.parent(ps),
+		})
+	}
+
+	for _, cs := range added {
+		if !renamed[cs] {
+			d.add(Change{
+				Kind:    ServiceAdded,
+				Current: cs,
+				Message: fmt.Sprintf("service %s was added", cs.FullyQualifiedName()),
+			})
+		}
+	}
+}
+
+// methods compares the Methods of the previous and current versions of a
+// Service.
+func (d *differ) methods(ps, cs Service) {
+	find := func(s Service, name Name) Method {
+		for _, m := range s.Methods() {
+			if m.Name() == name {
+				return m
+			}
+		}
+		return nil
+	}
+
+	var added []Method
+	for _, cm := range cs.Methods() {
+		if find(ps, cm.Name()) == nil {
+			added = append(added, cm)
+		}
+	}
+
+	renamed := map[Method]bool{}
+	for _, pm := range ps.Methods() {
+		cm := find(cs, pm.Name())
+		if cm == nil {
+			var candidates []Method
+			for _, am := range added {
+				if !renamed[am] && methodSignature(am) == methodSignature(pm) {
+					candidates = append(candidates, am)
+				}
+			}
+
+			if len(candidates) == 1 {
+				renamed[candidates[0]] = true
+				d.add(Change{
+					Kind:     MethodRenamed,
+					Previous: pm,
+					Current:  candidates[0],
+					Breakage: WireBreaking | JSONBreaking | SourceBreaking,
+					Message:  fmt.Sprintf("method %s was renamed to %s", pm.FullyQualifiedName(), candidates[0].Name()),
+				})
+				continue
+			}
+
+			d.add(Change{
+				Kind:     MethodRemoved,
+				Previous: pm,
+				Breakage: WireBreaking | JSONBreaking | SourceBreaking,
+				Message:  fmt.Sprintf("method %s was removed", pm.FullyQualifiedName()),
+				parent:   cs,
+			})
+			continue
+		}
+
+		if pi, ci := pm.Input().FullyQualifiedName(), cm.Input().FullyQualifiedName(); pi != ci {
+			d.add(Change{
+				Kind:     MethodInputChanged,
+				Previous: pm,
+				Current:  cm,
+				Breakage: WireBreaking | JSONBreaking | SourceBreaking,
+				Message:  fmt.Sprintf("method %s changed input from %s to %s", cm.FullyQualifiedName(), pi, ci),
+			})
+		}
+
+		if po, co := pm.Output().FullyQualifiedName(), cm.Output().FullyQualifiedName(); po != co {
+			d.add(Change{
+				Kind:     MethodOutputChanged,
+				Previous: pm,
+				Current:  cm,
+				Breakage: WireBreaking | JSONBreaking | SourceBreaking,
+				Message:  fmt.Sprintf("method %s changed output from %s to %s", cm.FullyQualifiedName(), po, co),
+			})
+		}
+
+		if pm.ClientStreaming() != cm.ClientStreaming() || pm.ServerStreaming() != cm.ServerStreaming() {
+			d.add(Change{
+				Kind:     MethodStreamingChanged,
+				Previous: pm,
+				Current:  cm,
+				Breakage: WireBreaking | JSONBreaking | SourceBreaking,
+				Message: fmt.Sprintf("method %s changed streaming from %s to %s",
+					cm.FullyQualifiedName(), streamingMode(pm), streamingMode(cm)),
+			})
+		}
+	}
+
+	for _, cm := range added {
+		if !renamed[cm] {
+			d.add(Change{
+				Kind:    MethodAdded,
+				Current: cm,
+				Message: fmt.Sprintf("method %s was added", cm.FullyQualifiedName()),
+			})
+		}
+	}
+}
+
+// streamingMode describes the streaming mode of m.
+func streamingMode(m Method) string {
+	switch {
+	case m.ClientStreaming() && m.ServerStreaming():
+		return "bidirectional"
+	case m.ClientStreaming():
+		return "client"
+	case m.ServerStreaming():
+		return "server"
+	default:
+		return "unary"
+	}
+}
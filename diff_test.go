@@ -0,0 +1,147 @@
+package pgs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+// diffVersion loads the files of a testdata directory as a FileDescriptorSet,
+// stripping the directory from their names so that both versions of the
+// protos share the same file names.
+func diffVersion(t *testing.T, dir string) AST {
+	prefix := dir + "/"
+	rename := func(name string) string { return "diff/" + strings.TrimPrefix(name, prefix) }
+
+	fdset := &descriptor.FileDescriptorSet{}
+	for _, fd := range readCodeGenReq(t, dir).GetProtoFile() {
+		if strings.HasPrefix(fd.GetName(), prefix) {
+			fd.Name = proto.String(rename(fd.GetName()))
+		}
+		for i, dep := range fd.GetDependency() {
+			if strings.HasPrefix(dep, prefix) {
+				fd.Dependency[i] = rename(dep)
+			}
+		}
+		fdset.File = append(fdset.File, fd)
+	}
+
+	d := InitMockDebugger()
+	ast := ProcessFileDescriptorSet(d, fdset)
+	require.False(t, d.Failed(), "failed to build graph (see previous log statements)")
+	return ast
+}
+
+func TestDiffAST(t *testing.T) {
+	t.Parallel()
+
+	prev, curr := diffVersion(t, "diff_previous"), diffVersion(t, "diff_current")
+
+	const (
+		w = WireBreaking
+		j = JSONBreaking
+		s = SourceBreaking
+	)
+
+	type change struct {
+		kind     ChangeKind
+		breakage Breakage
+		msg      string
+	}
+
+	expected := []change{
+		{FileRemoved, s, "file diff/legacy.proto was removed"},
+		{FileAdded, 0, "file diff/image.proto was added"},
+		{FieldRenamed, j | s, "field .graph.diff.User.name (2) was renamed to display_name"},
+		{FieldTypeChanged, j | s, "field .graph.diff.User.age changed type from int32 to int64"},
+		{FieldLabelChanged, w | j | s, "field .graph.diff.User.tags changed label from repeated to implicit"},
+		{FieldOneOfChanged, w | j | s, "field .graph.diff.User.phone moved from oneof contact to no oneof"},
+		{FieldTypeChanged, w | j | s, "field .graph.diff.User.avatar changed type from bytes to .graph.diff.Image"},
+		{FieldJSONNameChanged, j, `field .graph.diff.User.nickname changed JSON name from "nick" to "alias"`},
+		{FieldLabelChanged, s, "field .graph.diff.User.bio changed label from optional to implicit"},
+		{FieldTypeChanged, w | j | s, "field .graph.diff.User.counts changed type from map<string, int32> to map<string, int64>"},
+		{FieldRemoved, s, "field .graph.diff.User.email (4) was removed"},
+		{FieldNumberChanged, w, "field .graph.diff.User.status changed number from 6 to 16"},
+		{FieldRemoved, j | s, "field .graph.diff.User.password (12) was removed"},
+		{FieldAdded, 0, "field .graph.diff.User.locale (13) was added"},
+		{MessageRemoved, s, "message .graph.diff.Legacy was removed"},
+		{MessageAdded, 0, "message .graph.diff.LookupRequest was added"},
+		{MessageAdded, 0, "message .graph.diff.Image was added"},
+		{EnumValueNumberChanged, w, "enum value .graph.diff.Status.STATUS_PENDING changed number from 4 to 5"},
+		{EnumValueRemoved, w | j | s, "enum value .graph.diff.Status.STATUS_BANNED (2) was removed"},
+		{EnumValueRenamed, j | s, "enum value .graph.diff.Status.STATUS_DELETED (3) was renamed to STATUS_REMOVED"},
+		{EnumValueAdded, 0, "enum value .graph.diff.Status.STATUS_INVITED (6) was added"},
+		{FieldNumberChanged, w, "extension .graph.diff.doc changed number from 50000 to 50001"},
+		{MethodInputChanged, w | j | s, "method .graph.diff.Users.GetUser changed input from .graph.diff.GetUserRequest to .graph.diff.LookupRequest"},
+		{MethodStreamingChanged, w | j | s, "method .graph.diff.Users.ListUsers changed streaming from server to unary"},
+		{MethodRenamed, w | j | s, "method .graph.diff.Users.DeleteUser was renamed to RemoveUser"},
+		{MethodRemoved, w | j | s, "method .graph.diff.Users.Migrate was removed"},
+		{ServiceRenamed, w | j | s, "service .graph.diff.Admin was renamed to Moderation"},
+		{ServiceRemoved, w | j | s, "service .graph.diff.Old was removed"},
+	}
+
+	changes := DiffAST(prev, curr)
+
+	actual := make([]change, len(changes))
+	for i, c := range changes {
+		actual[i] = change{c.Kind, c.Breakage, c.Message}
+	}
+	assert.Equal(t, expected, actual)
+
+	for _, c := range changes {
+		switch c.Kind {
+		case FileRemoved:
+			assert.Nil(t, c.Entity())
+		case FieldRemoved:
+			assert.Equal(t, ".graph.diff.User", c.Entity().FullyQualifiedName())
+		case EnumValueRemoved:
+			assert.Equal(t, ".graph.diff.Status", c.Entity().FullyQualifiedName())
+		case MethodRemoved:
+			assert.Equal(t, ".graph.diff.Users", c.Entity().FullyQualifiedName())
+		case ServiceRemoved:
+			assert.Equal(t, "diff/api.proto", c.Entity().Name().String())
+		case MessageRemoved:
+			assert.Nil(t, c.Entity())
+		default:
+			assert.Equal(t, c.Current, c.Entity())
+		}
+	}
+}
+
+func TestDiffAST_Unchanged(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, DiffAST(diffVersion(t, "diff_current"), diffVersion(t, "diff_current")))
+}
+
+func TestBreakage_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "none", Breakage(0).String())
+	assert.Equal(t, "wire", WireBreaking.String())
+	assert.Equal(t, "json|source", (JSONBreaking | SourceBreaking).String())
+}
+
+func TestChangeKind_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "FieldRemoved", FieldRemoved.String())
+	assert.Equal(t, "MethodStreamingChanged", MethodStreamingChanged.String())
+	assert.Equal(t, "ChangeKind(-1)", ChangeKind(-1).String())
+}
+
+func TestChange_String(t *testing.T) {
+	t.Parallel()
+
+	c := Change{Message: "field .foo.Bar.baz (1) was removed", Breakage: JSONBreaking | SourceBreaking}
+	assert.True(t, c.Breaking())
+	assert.Equal(t, "field .foo.Bar.baz (1) was removed (breaking: json|source)", c.String())
+
+	c = Change{Message: "field .foo.Bar.baz (1) was added"}
+	assert.False(t, c.Breaking())
+	assert.Equal(t, "field .foo.Bar.baz (1) was added", c.String())
+}
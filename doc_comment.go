@@ -0,0 +1,191 @@
+package pgs
+
+import (
+	"strings"
+	"unicode"
+)
+
+// A DocComment is the structured form of an entity's comments, as parsed by
+// ParseDocComment. Its text is normalized: common indentation is removed from
+// all lines, as are trailing whitespace and leading and trailing blank lines.
+type DocComment struct {
+	// Summary is the first paragraph of the leading comment, joined into a
+	// single line. It is empty if the comment begins with a fenced code block.
+	Summary string
+
+	// Body is the remainder of the leading comment following the Summary and
+	// preceding any tags. Line breaks are preserved so that Markdown, such as
+	// lists and code blocks, remains intact.
+	Body string
+
+	// Tags holds the values of the "@tag value" annotations in the leading
+	// comment, in the order they appear. A tag begins a line outside of a
+	// fenced code block, and its value extends until the next tag or the end
+	// of the comment. Tags without a value, such as "@deprecated", map to an
+	// empty string.
+	Tags map[string][]string
+
+	// Detached holds each of the normalized leading detached comments, which
+	// are separated from the entity by a blank line.
+	Detached []string
+}
+
+// ParseDocComment parses the comments of info into a DocComment. The zero
+// DocComment is returned if info is nil.
+func ParseDocComment(info SourceCodeInfo) DocComment {
+	if info == nil {
+		return DocComment{}
+	}
+
+	doc := ParseComment(info.LeadingComments())
+	for _, c := range info.LeadingDetachedComments() {
+		if c = normalizeComment(c); c != "" {
+			doc.Detached = append(doc.Detached, c)
+		}
+	}
+	return doc
+}
+
+// ParseComment parses the raw text of a single comment, as returned by
+// SourceCodeInfo.LeadingComments, into a DocComment. The Detached comments of
+// the result are always empty.
+func ParseComment(text string) DocComment {
+	var (
+		doc           DocComment
+		summary, body []string
+		tag, fence    string
+		value         []string
+		pastSummary   bool
+	)
+
+	endTag := func() {
+		if tag != "" {
+			// continuation lines are dedented relative to each other
+			value = append(value[:1], commentLines(strings.Join(value[1:], "\n"))...)
+			doc.Tags[tag] = append(doc.Tags[tag], trimBlankLines(value))
+		}
+	}
+
+	for _, ln := range commentLines(text) {
+		if fence == "" {
+			if name, val, ok := parseDocTag(ln); ok {
+				if doc.Tags == nil {
+					doc.Tags = map[string][]string{}
+				}
+				endTag()
+				tag, value, pastSummary = name, []string{val}, true
+				continue
+			}
+		}
+
+		opening := false
+		if f := codeFence(ln); f != "" {
+			if fence == "" {
+				fence, opening = f, true
+			} else if strings.HasPrefix(f, fence) && strings.TrimSpace(ln) == f {
+				fence = ""
+			}
+		}
+
+		switch {
+		case tag != "":
+			value = append(value, ln)
+		case pastSummary:
+			body = append(body, ln)
+		case opening:
+			// a code block ends the summary, or replaces it if the comment
+			// begins with one
+			pastSummary = true
+			body = append(body, ln)
+		case ln == "":
+			pastSummary = len(summary) > 0
+		default:
+			summary = append(summary, strings.TrimSpace(ln))
+		}
+	}
+	endTag()
+
+	doc.Summary = strings.Join(summary, " ")
+	doc.Body = trimBlankLines(body)
+	return doc
+}
+
+// normalizeComment returns the text of a comment with its common indentation,
+// trailing whitespace and leading and trailing blank lines removed.
+func normalizeComment(text string) string { return trimBlankLines(commentLines(text)) }
+
+// commentLines splits the text of a comment into lines, removing trailing
+// whitespace and the indentation common to all non-blank lines. The "*" left
+// over from the opening of a /** */ comment is also removed.
+func commentLines(text string) []string {
+	if text == "*" || strings.HasPrefix(text, "*\n") || strings.HasPrefix(text, "* ") {
+		text = text[1:]
+	}
+	lines := strings.Split(strings.TrimRightFunc(text, unicode.IsSpace), "\n")
+
+	indent := -1
+	for i, ln := range lines {
+		lines[i] = strings.TrimRightFunc(ln, unicode.IsSpace)
+		if lines[i] == "" {
+			continue
+		}
+
+		n := len(lines[i]) - len(strings.TrimLeft(lines[i], " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+
+	for i, ln := range lines {
+		if len(ln) >= indent && indent > 0 {
+			lines[i] = ln[indent:]
+		}
+	}
+	return lines
+}
+
+// trimBlankLines joins lines after removing any leading and trailing blank
+// lines.
+func trimBlankLines(lines []string) string {
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseDocTag parses a line of the form "@name value". Only lines beginning
+// with a tag, without any indentation, are considered, so tags within indented
+// code blocks and email addresses are ignored.
+func parseDocTag(ln string) (name, value string, ok bool) {
+	if !strings.HasPrefix(ln, "@") {
+		return "", "", false
+	}
+
+	end := 1
+	for ; end < len(ln); end++ {
+		c := ln[end]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || end > 1 && (c >= '0' && c <= '9' || c == '_' || c == '-' || c == '.')) {
+			break
+		}
+	}
+
+	if end == 1 || end < len(ln) && ln[end] != ' ' && ln[end] != '\t' {
+		return "", "", false
+	}
+	return ln[1:end], strings.TrimSpace(ln[end:]), true
+}
+
+// codeFence returns the fence (``` or ~~~, possibly longer) opening or closing
+// a Markdown fenced code block on ln, or an empty string if there is none.
+func codeFence(ln string) string {
+	ln = strings.TrimLeft(ln, " ")
+	for _, c := range []string{"`", "~"} {
+		if n := len(ln) - len(strings.TrimLeft(ln, c)); n >= 3 {
+			return ln[:n]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,131 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseComment(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		text     string
+		expected DocComment
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:     "summary",
+			text:     " Foo does\n a thing.\n",
+			expected: DocComment{Summary: "Foo does a thing."},
+		},
+		{
+			name: "body",
+			text: "\n Foo.\n\n - one\n - two\n\n     code\n",
+			expected: DocComment{
+				Summary: "Foo.",
+				Body:    "- one\n- two\n\n    code",
+			},
+		},
+		{
+			name: "javadoc",
+			text: "*\n   Foo.\n\n   Bar.\n",
+			expected: DocComment{
+				Summary: "Foo.",
+				Body:    "Bar.",
+			},
+		},
+		{
+			name: "leading code block",
+			text: " ```\n foo\n\n @bar\n ```\n More.\n",
+			expected: DocComment{
+				Body: "```\nfoo\n\n@bar\n```\nMore.",
+			},
+		},
+		{
+			name: "code block ends summary",
+			text: " Foo.\n ~~~~proto\n message Bar {}\n ~~~\n ~~~~\n",
+			expected: DocComment{
+				Summary: "Foo.",
+				Body:    "~~~~proto\nmessage Bar {}\n~~~\n~~~~",
+			},
+		},
+		{
+			name: "tags",
+			text: " Foo.\n\n Bar.\n\n @deprecated\n @visibility  internal \n @see a@b.com\n @example\n   {\n     \"a\": 1\n   }\n\n @example 2\n",
+			expected: DocComment{
+				Summary: "Foo.",
+				Body:    "Bar.",
+				Tags: map[string][]string{
+					"deprecated": {""},
+					"visibility": {"internal"},
+					"see":        {"a@b.com"},
+					"example":    {"{\n  \"a\": 1\n}", "2"},
+				},
+			},
+		},
+		{
+			name: "tag ends summary",
+			text: " Foo.\n @since 1.2\n continued\n",
+			expected: DocComment{
+				Summary: "Foo.",
+				Tags:    map[string][]string{"since": {"1.2\ncontinued"}},
+			},
+		},
+		{
+			name: "not tags",
+			text: " Foo.\n\n  @indented\n email@example.com\n @ alone\n @1st\n @foo:bar\n",
+			expected: DocComment{
+				Summary: "Foo.",
+				Body:    " @indented\nemail@example.com\n@ alone\n@1st\n@foo:bar",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		tc := test
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, ParseComment(tc.text))
+		})
+	}
+}
+
+func TestParseDocComment(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, DocComment{}, ParseDocComment(nil))
+
+	g := buildGraph(t, "doc")
+
+	lookup := func(name string) SourceCodeInfo {
+		ent, ok := g.Lookup(name)
+		require.True(t, ok, name)
+		return ent.SourceCodeInfo()
+	}
+
+	assert.Equal(t, DocComment{
+		Summary: "An Account holds a user's billing details.",
+		Body:    "Accounts are created with:\n\n```\nCreateAccount(name)\n@not-a-tag\n```",
+		Tags: map[string][]string{
+			"visibility": {"internal"},
+			"since":      {"2.1"},
+		},
+		Detached: []string{"Not attached to Account."},
+	}, ParseDocComment(lookup(".graph.doc.Account")))
+
+	assert.Equal(t, DocComment{
+		Summary: "The display name.",
+		Body:    "    indented code",
+		Tags: map[string][]string{
+			"deprecated": {""},
+			"example":    {"{\"name\": \"Jane\"}", "\"Joe\""},
+		},
+	}, ParseDocComment(lookup(".graph.doc.Account.name")))
+
+	assert.Equal(t, DocComment{}, ParseDocComment(lookup(".graph.doc.Account.id")))
+}
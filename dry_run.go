@@ -0,0 +1,122 @@
+package pgs
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/afero"
+	"google.golang.org/protobuf/proto"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+// diffContext is the number of unchanged lines surrounding each change in the
+// unified diffs produced in dry-run mode.
+const diffContext = 3
+
+// dryRunResult accumulates the changes a dry-run would have made to the file
+// system.
+type dryRunResult struct {
+	diff    bytes.Buffer
+	changed []string
+}
+
+// diffFile records the unified diff between the current contents of the file
+// name and content, if writing it would change the file system. It returns
+// false if the file would be skipped because it already exists.
+func (p *stdPersister) diffFile(res *dryRunResult, name string, content []byte, overwrite bool) bool {
+	exists, err := afero.Exists(p.fs, name)
+	p.CheckErr(err, "unable to check file exists:", name)
+
+	from := "/dev/null"
+	var existing []byte
+	if exists {
+		if !overwrite {
+			p.Debug("file", name, "exists, skipping")
+			return false
+		}
+
+		existing, err = afero.ReadFile(p.fs, name)
+		p.CheckErr(err, "unable to read file:", name)
+		from = name
+	}
+
+	if exists && bytes.Equal(existing, content) {
+		return true
+	}
+
+	p.addDiff(res, name, from, name, existing, content)
+	return true
+}
+
+// diffRemove records the unified diff for deleting the file name.
+func (p *stdPersister) diffRemove(res *dryRunResult, name string) {
+	existing, err := afero.ReadFile(p.fs, name)
+	p.CheckErr(err, "unable to read file:", name)
+
+	p.addDiff(res, name, name, "/dev/null", existing, nil)
+}
+
+func (p *stdPersister) addDiff(res *dryRunResult, name, from, to string, a, b []byte) {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(a),
+		B:        splitLines(b),
+		FromFile: from,
+		ToFile:   to,
+		Context:  diffContext,
+	})
+	p.CheckErr(err, "unable to diff file:", name)
+
+	p.Debug("file", name, "would change")
+	res.diff.WriteString(diff)
+	res.changed = append(res.changed, name)
+}
+
+// reportDryRun emits the diff accumulated during a dry-run, either to stderr
+// or as a generated report file. If the persister is configured to fail on
+// changes, an error listing the changed files is added to resp.
+func (p *stdPersister) reportDryRun(resp *plugin_go.CodeGeneratorResponse, res *dryRunResult) {
+	if p.dryRunReport == "" {
+		out := p.diffOut
+		if out == nil {
+			out = os.Stderr
+		}
+		_, err := res.diff.WriteTo(out)
+		p.CheckErr(err, "unable to write dry-run diff")
+	} else {
+		f, err := GeneratorFile{Name: p.dryRunReport, Contents: res.diff.String()}.ProtoFile()
+		p.CheckErr(err, "unable to convert dry-run report ", p.dryRunReport, " to proto")
+		p.insertFile(resp, f, true)
+	}
+
+	if !p.dryRunFail || len(res.changed) == 0 {
+		return
+	}
+
+	msg := fmt.Sprintf("dry run: %d custom file(s) out of date: %s",
+		len(res.changed), strings.Join(res.changed, ", "))
+
+	if resp.Error == nil {
+		resp.Error = proto.String(msg)
+		return
+	}
+
+	resp.Error = proto.String(strings.Join([]string{resp.GetError(), msg}, "; "))
+}
+
+// splitLines splits b into lines suitable for diffing. Unlike
+// difflib.SplitLines, empty input produces no lines.
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+
+	lines := difflib.SplitLines(string(b))
+	if strings.HasSuffix(string(b), "\n") {
+		// SplitLines appends a trailing newline to the final line
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
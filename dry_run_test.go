@@ -0,0 +1,112 @@
+package pgs
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPersister_Persist_DryRun(t *testing.T) {
+	t.Parallel()
+
+	arts := []Artifact{
+		CustomFile{Name: "/new", Contents: "foo\n", Perms: 0644},
+		CustomFile{Name: "/same", Contents: "bar\n", Perms: 0644, Overwrite: true},
+		CustomFile{Name: "/changed", Contents: "a\nB\nc\n", Perms: 0644, Overwrite: true},
+		CustomFile{Name: "/skipped", Contents: "nope\n", Perms: 0644},
+		CustomTemplateFile{
+			Name:             "/tpl",
+			TemplateArtifact: TemplateArtifact{Template: genTpl, Data: "baz"},
+			Perms:            0644,
+		},
+	}
+
+	setup := func() (*stdPersister, MockDebugger) {
+		d := InitMockDebugger()
+		p := dummyPersister(d)
+		assert.NoError(t, afero.WriteFile(p.fs, "/same", []byte("bar\n"), 0644))
+		assert.NoError(t, afero.WriteFile(p.fs, "/changed", []byte("a\nb\nc\n"), 0644))
+		assert.NoError(t, afero.WriteFile(p.fs, "/skipped", []byte("yep\n"), 0644))
+		return p, d
+	}
+
+	const expected = `--- /dev/null
++++ /new
+@@ -0,0 +1 @@
++foo
+--- /changed
++++ /changed
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+--- /dev/null
++++ /tpl
+@@ -0,0 +1 @@
++baz
+`
+
+	t.Run("stderr", func(t *testing.T) {
+		t.Parallel()
+
+		p, d := setup()
+		buf := &bytes.Buffer{}
+		p.diffOut = buf
+		p.SetDryRun("", false)
+
+		resp := p.Persist(arts...)
+		assert.NoError(t, d.Err())
+		assert.Empty(t, resp.File)
+		assert.Nil(t, resp.Error)
+		assert.Equal(t, expected, buf.String())
+
+		exists, err := afero.Exists(p.fs, "/new")
+		assert.NoError(t, err)
+		assert.False(t, exists, "dry-run must not write files")
+
+		b, err := afero.ReadFile(p.fs, "/changed")
+		assert.NoError(t, err)
+		assert.Equal(t, "a\nb\nc\n", string(b), "dry-run must not modify files")
+	})
+
+	t.Run("report", func(t *testing.T) {
+		t.Parallel()
+
+		p, d := setup()
+		p.SetDryRun("out/report.diff", false)
+
+		resp := p.Persist(arts...)
+		assert.NoError(t, d.Err())
+		assert.Nil(t, resp.Error)
+		assert.Len(t, resp.File, 1)
+		assert.Equal(t, "out/report.diff", resp.File[0].GetName())
+		assert.Equal(t, expected, resp.File[0].GetContent())
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		t.Parallel()
+
+		p, _ := setup()
+		p.diffOut = &bytes.Buffer{}
+		p.SetDryRun("", true)
+
+		resp := p.Persist(append(arts, GeneratorError{Message: "fizz"})...)
+		assert.Equal(t, "fizz; dry run: 3 custom file(s) out of date: /new, /changed, /tpl", resp.GetError())
+	})
+
+	t.Run("up to date", func(t *testing.T) {
+		t.Parallel()
+
+		p, _ := setup()
+		buf := &bytes.Buffer{}
+		p.diffOut = buf
+		p.SetDryRun("", true)
+
+		resp := p.Persist(arts[1], arts[3])
+		assert.Nil(t, resp.Error)
+		assert.Empty(t, buf.String())
+	})
+}
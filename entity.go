@@ -1,6 +1,9 @@
 package pgs
 
-import "google.golang.org/protobuf/runtime/protoimpl"
+import (
+	"google.golang.org/protobuf/runtime/protoimpl"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
 
 // Entity describes any member of the proto AST that is extensible via
 // options. All components of a File are considered entities.
@@ -19,6 +22,12 @@ type Entity interface {
 	// syntax.
 	Syntax() Syntax
 
+	// Features returns the resolved FeatureSet for this entity. The features
+	// start from the defaults of the File's Edition and are overridden by any
+	// features explicitly set on the entity or its parents. Files using Proto2
+	// or Proto3 syntax resolve to the features equivalent to their semantics.
+	Features() *descriptor.FeatureSet
+
 	// Package returns the container package for this entity.
 	Package() Package
 
@@ -36,6 +45,17 @@ type Entity interface {
 	// is NOT found, ok will be false and err will be nil.
 	Extension(desc *protoimpl.ExtensionInfo, ext interface{}) (ok bool, err error)
 
+	// ExtensionByName extracts the extension with the fully qualified name
+	// (eg, "foo.bar" or ".foo.bar") from the entity's options, without
+	// requiring its generated Go type. The extension is resolved from the
+	// Extensions in the AST that extend the options' message, and its value is
+	// parsed from the options' encoded fields. Scalars are returned as their Go
+	// types (eg, int32 or string), enums as a protoreflect.EnumNumber, messages
+	// as a protoreflect.Message, and repeated values as a protoreflect.List. The
+	// ok value will be true if the extension was found. An error is returned if
+	// no such Extension exists or its value cannot be parsed.
+	ExtensionByName(name string) (val interface{}, ok bool, err error)
+
 	// BuildTarget identifies whether or not generation should be performed on
 	// this entity. Use this flag to determine if the file was targeted in the
 	// protoc run or if it was loaded as an external dependency.
@@ -45,6 +65,21 @@ type Entity interface {
 	// Primarily, this struct contains the comments associated with the Entity.
 	SourceCodeInfo() SourceCodeInfo
 
+	// OptionSourceCodeInfo returns the SourceCodeInfo of an option assignment
+	// on the entity, identified by the path of field numbers into the entity's
+	// options message. For instance, the number of a custom option Extension,
+	// or the numbers of a custom option and one of its message fields. If path
+	// is empty, the location of all the entity's options is returned (for
+	// Fields and EnumValues, the bracketed option list). Nil is returned if
+	// there is no such location.
+	OptionSourceCodeInfo(path ...int32) SourceCodeInfo
+
+	// SourcePath returns the path of the entity's descriptor within its File's
+	// descriptor, as used by SourceCodeInfo_Location and GeneratedCodeInfo
+	// annotations. For example, the path of the second field of the first
+	// message in a File is [4, 0, 2, 1]. A File's path is empty.
+	SourcePath() []int32
+
 	childAtPath(path []int32) Entity
 	addSourceCodeInfo(info SourceCodeInfo)
 }
@@ -1,6 +1,7 @@
 package pgs
 
 import (
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/runtime/protoimpl"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
@@ -13,6 +14,35 @@ type Enum interface {
 	// Descriptor returns the proto descriptor for this Enum
 	Descriptor() *descriptor.EnumDescriptorProto
 
+	// Reflect returns the protoreflect descriptor for this Enum, built lazily
+	// from its File's descriptor. Nil is returned if the descriptor cannot be
+	// built.
+	Reflect() protoreflect.EnumDescriptor
+
+	// ReservedRangeSourceCodeInfo returns the SourceCodeInfo of the reserved
+	// range at index i of the Enum's descriptor. Nil is returned if there is no
+	// such location.
+	ReservedRangeSourceCodeInfo(i int) SourceCodeInfo
+
+	// ReservedNameSourceCodeInfo returns the SourceCodeInfo of the reserved name
+	// at index i of the Enum's descriptor. Nil is returned if there is no such
+	// location.
+	ReservedNameSourceCodeInfo(i int) SourceCodeInfo
+
+	// ReservedNames returns the value names reserved by this Enum.
+	ReservedNames() []string
+
+	// ReservedRanges returns the value numbers reserved by this Enum, with
+	// inclusive bounds.
+	ReservedRanges() []ReservedRange
+
+	// IsReservedNumber returns true if the value number n is reserved by this
+	// Enum.
+	IsReservedNumber(n int32) bool
+
+	// IsReservedName returns true if the value name is reserved by this Enum.
+	IsReservedName(name string) bool
+
 	// Parent resolves to either a Message or File that directly contains this
 	// Enum.
 	Parent() ParentEntity
@@ -20,6 +50,11 @@ type Enum interface {
 	// Values returns each defined enumeration value.
 	Values() []EnumValue
 
+	// IsClosed returns true if this Enum is closed, meaning unknown values are
+	// not accepted for fields of this type. Enums are closed when their
+	// resolved enum_type feature is CLOSED, as is the case for all proto2 enums.
+	IsClosed() bool
+
 	// Dependents returns all of the messages where Enum is directly or
 	// transitively used.
 	Dependents() []Message
@@ -51,6 +86,19 @@ func (e *enum) Parent() ParentEntity                        { return e.parent }
 func (e *enum) Imports() []File                             { return nil }
 func (e *enum) Values() []EnumValue                         { return e.vals }
 
+func (e *enum) Reflect() protoreflect.EnumDescriptor {
+	d, _ := reflectDescriptor(e).(protoreflect.EnumDescriptor)
+	return d
+}
+
+func (e *enum) Features() *descriptor.FeatureSet {
+	return mergeFeatures(e.parent.Features(), e.desc.GetOptions().GetFeatures())
+}
+
+func (e *enum) IsClosed() bool {
+	return e.Features().GetEnumType() == descriptor.FeatureSet_CLOSED
+}
+
 func (e *enum) populateDependentsCache() {
 	if e.dependentsCache != nil {
 		return
@@ -72,6 +120,10 @@ func (e *enum) Extension(desc *protoimpl.ExtensionInfo, ext interface{}) (bool,
 	return extension(e.desc.GetOptions(), desc, &ext)
 }
 
+func (e *enum) ExtensionByName(name string) (interface{}, bool, error) {
+	return dynamicExtension(e, e.desc.GetOptions(), name)
+}
+
 func (e *enum) accept(v Visitor) (err error) {
 	if v == nil {
 		return nil
@@ -107,7 +159,7 @@ func (e *enum) childAtPath(path []int32) Entity {
 		return e
 	case len(path)%2 != 0:
 		return nil
-	case path[0] == enumTypeValuePath:
+	case path[0] == enumTypeValuePath && inRange(path[1], len(e.vals)):
 		return e.vals[path[1]].childAtPath(path[2:])
 	default:
 		return nil
@@ -116,4 +168,47 @@ func (e *enum) childAtPath(path []int32) Entity {
 
 func (e *enum) addSourceCodeInfo(info SourceCodeInfo) { e.info = info }
 
+func (e *enum) OptionSourceCodeInfo(path ...int32) SourceCodeInfo {
+	return sourceCodeInfoAt(e, append([]int32{enumTypeOptionsPath}, path...)...)
+}
+
+func (e *enum) ReservedRangeSourceCodeInfo(i int) SourceCodeInfo {
+	return sourceCodeInfoAt(e, enumTypeReservedRangePath, int32(i))
+}
+
+func (e *enum) ReservedNameSourceCodeInfo(i int) SourceCodeInfo {
+	return sourceCodeInfoAt(e, enumTypeReservedNamePath, int32(i))
+}
+
+func (e *enum) ReservedNames() []string { return e.desc.GetReservedName() }
+
+func (e *enum) ReservedRanges() []ReservedRange {
+	rs := e.desc.GetReservedRange()
+	if len(rs) == 0 {
+		return nil
+	}
+
+	out := make([]ReservedRange, len(rs))
+	for i, r := range rs {
+		out[i] = ReservedRange{Start: r.GetStart(), End: r.GetEnd()}
+	}
+	return out
+}
+
+func (e *enum) IsReservedNumber(n int32) bool { return inReservedRanges(e.ReservedRanges(), n) }
+
+func (e *enum) IsReservedName(name string) bool { return containsString(e.ReservedNames(), name) }
+
+func (e *enum) SourcePath() []int32 {
+	if p, ok := e.parent.(Message); ok {
+		enums := p.Descriptor().GetEnumType()
+		i := indexOf(len(enums), func(i int) bool { return enums[i] == e.desc })
+		return childPath(p.SourcePath(), messageTypeEnumTypePath, i)
+	}
+
+	enums := e.File().Descriptor().GetEnumType()
+	i := indexOf(len(enums), func(i int) bool { return enums[i] == e.desc })
+	return childPath(nil, enumTypePath, i)
+}
+
 var _ Enum = (*enum)(nil)
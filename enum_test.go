@@ -33,6 +33,26 @@ func TestEnum_Syntax(t *testing.T) {
 	assert.Equal(t, f.Syntax(), e.Syntax())
 }
 
+func TestEnum_IsClosed(t *testing.T) {
+	t.Parallel()
+
+	e := dummyEnum()
+	assert.False(t, e.IsClosed())
+
+	e.File().(*file).desc.Syntax = proto.String(string(Proto2))
+	assert.True(t, e.IsClosed())
+
+	f := dummyEditionsFile()
+	e = &enum{desc: &descriptor.EnumDescriptorProto{Name: proto.String("enum")}}
+	f.addEnum(e)
+	assert.False(t, e.IsClosed())
+
+	e.desc.Options = &descriptor.EnumOptions{Features: &descriptor.FeatureSet{
+		EnumType: descriptor.FeatureSet_CLOSED.Enum(),
+	}}
+	assert.True(t, e.IsClosed())
+}
+
 func TestEnum_Package(t *testing.T) {
 	t.Parallel()
 
@@ -98,6 +118,33 @@ func TestEnum_Values(t *testing.T) {
 	assert.Len(t, e.Values(), 1)
 }
 
+func TestEnum_Reserved(t *testing.T) {
+	t.Parallel()
+
+	e := &enum{desc: &descriptor.EnumDescriptorProto{}}
+	assert.Empty(t, e.ReservedNames())
+	assert.Empty(t, e.ReservedRanges())
+	assert.False(t, e.IsReservedNumber(0))
+	assert.False(t, e.IsReservedName("FOO"))
+
+	e.desc = &descriptor.EnumDescriptorProto{
+		ReservedName: []string{"FOO"},
+		ReservedRange: []*descriptor.EnumDescriptorProto_EnumReservedRange{
+			{Start: proto.Int32(2), End: proto.Int32(2)},
+			{Start: proto.Int32(10), End: proto.Int32(20)},
+		},
+	}
+
+	assert.Equal(t, []string{"FOO"}, e.ReservedNames())
+	assert.Equal(t, []ReservedRange{{Start: 2, End: 2}, {Start: 10, End: 20}}, e.ReservedRanges())
+	assert.True(t, e.IsReservedNumber(2))
+	assert.False(t, e.IsReservedNumber(3))
+	assert.True(t, e.IsReservedNumber(20))
+	assert.False(t, e.IsReservedNumber(21))
+	assert.True(t, e.IsReservedName("FOO"))
+	assert.False(t, e.IsReservedName("BAR"))
+}
+
 func TestEnum_Dependents(t *testing.T) {
 	t.Parallel()
 
@@ -1,6 +1,7 @@
 package pgs
 
 import (
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/runtime/protoimpl"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
@@ -12,6 +13,11 @@ type EnumValue interface {
 	// Descriptor returns the proto descriptor for this Enum Value
 	Descriptor() *descriptor.EnumValueDescriptorProto
 
+	// Reflect returns the protoreflect descriptor for this Enum Value, built
+	// lazily from its File's descriptor. Nil is returned if the descriptor
+	// cannot be built.
+	Reflect() protoreflect.EnumValueDescriptor
+
 	// Enum returns the parent Enum for this value
 	Enum() Enum
 
@@ -41,10 +47,26 @@ func (ev *enumVal) Enum() Enum                                       { return ev
 func (ev *enumVal) Value() int32                                     { return ev.desc.GetNumber() }
 func (ev *enumVal) Imports() []File                                  { return nil }
 
+func (ev *enumVal) Reflect() protoreflect.EnumValueDescriptor {
+	e := ev.Enum().Reflect()
+	if e == nil {
+		return nil
+	}
+	return e.Values().ByName(protoreflect.Name(ev.Name()))
+}
+
+func (ev *enumVal) Features() *descriptor.FeatureSet {
+	return mergeFeatures(ev.enum.Features(), ev.desc.GetOptions().GetFeatures())
+}
+
 func (ev *enumVal) Extension(desc *protoimpl.ExtensionInfo, ext interface{}) (bool, error) {
 	return extension(ev.desc.GetOptions(), desc, &ext)
 }
 
+func (ev *enumVal) ExtensionByName(name string) (interface{}, bool, error) {
+	return dynamicExtension(ev, ev.desc.GetOptions(), name)
+}
+
 func (ev *enumVal) accept(v Visitor) (err error) {
 	if v == nil {
 		return nil
@@ -66,4 +88,14 @@ func (ev *enumVal) childAtPath(path []int32) Entity {
 
 func (ev *enumVal) addSourceCodeInfo(info SourceCodeInfo) { ev.info = info }
 
+func (ev *enumVal) OptionSourceCodeInfo(path ...int32) SourceCodeInfo {
+	return sourceCodeInfoAt(ev, append([]int32{enumValueOptionsPath}, path...)...)
+}
+
+func (ev *enumVal) SourcePath() []int32 {
+	vals := ev.enum.Descriptor().GetValue()
+	i := indexOf(len(vals), func(i int) bool { return vals[i] == ev.desc })
+	return childPath(ev.enum.SourcePath(), enumTypeValuePath, i)
+}
+
 var _ EnumValue = (*enumVal)(nil)
@@ -4,9 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/runtime/protoimpl"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
 )
 
 // An Extension is a custom option annotation that can be applied to an Entity to provide additional
@@ -44,6 +49,52 @@ func (e *ext) OneOf() OneOf               { return nil }
 func (e *ext) setMessage(m Message)       {} // noop
 func (e *ext) setOneOf(o OneOf)           {} // noop
 func (e *ext) setExtendee(m Message)      { e.extendee = m }
+func (e *ext) Required() bool             { return false } // extensions cannot be required
+func (e *ext) IsPacked() bool             { return isPacked(e) }
+
+func (e *ext) JSONName() string {
+	return "[" + strings.TrimPrefix(e.fqn, ".") + "]"
+}
+
+func (e *ext) Features() *descriptor.FeatureSet {
+	return fieldFeatures(e.parent, e.desc)
+}
+
+func (e *ext) Reflect() protoreflect.FieldDescriptor {
+	d, _ := reflectDescriptor(e).(protoreflect.ExtensionDescriptor)
+	return d
+}
+
+func (e *ext) ExtensionByName(name string) (interface{}, bool, error) {
+	return dynamicExtension(e, e.desc.GetOptions(), name)
+}
+
+func (e *ext) OptionSourceCodeInfo(path ...int32) SourceCodeInfo {
+	return sourceCodeInfoAt(e, append([]int32{fieldOptionsPath}, path...)...)
+}
+
+func (e *ext) childAtPath(path []int32) Entity {
+	if len(path) == 0 {
+		return e
+	}
+	return nil
+}
+
+func (e *ext) SourcePath() []int32 {
+	if p, ok := e.parent.(Message); ok {
+		exts := p.Descriptor().GetExtension()
+		i := indexOf(len(exts), func(i int) bool { return exts[i] == e.desc })
+		return childPath(p.SourcePath(), messageTypeExtensionPath, i)
+	}
+
+	exts := e.File().Descriptor().GetExtension()
+	i := indexOf(len(exts), func(i int) bool { return exts[i] == e.desc })
+	return childPath(nil, extensionPath, i)
+}
+
+func (e *ext) HasPresence() bool {
+	return e.desc.GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED
+}
 
 func (e *ext) accept(v Visitor) (err error) {
 	if v == nil {
@@ -54,6 +105,76 @@ func (e *ext) accept(v Visitor) (err error) {
 	return
 }
 
+// dynamicExtension extracts the extension with the fully qualified name from
+// opts, the options of the Entity e, using the extension's definition in the
+// AST instead of its generated Go type.
+func dynamicExtension(e Entity, opts proto.Message, name string) (interface{}, bool, error) {
+	if opts == nil || reflect.ValueOf(opts).IsNil() {
+		return nil, false, nil
+	}
+
+	x, err := optionExtension(e.File(), opts, name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	xd := x.Reflect()
+	if xd == nil {
+		return nil, false, fmt.Errorf("unable to build descriptor for extension %q", name)
+	}
+
+	xt := dynamicpb.NewExtensionType(xd)
+	types := new(protoregistry.Types)
+	if err = types.RegisterExtension(xt); err != nil {
+		return nil, false, err
+	}
+
+	// the options are re-parsed so that the extension's value is decoded
+	// regardless of whether it was retained as unknown fields or as a known
+	// extension of a linked type
+	b, err := proto.Marshal(opts)
+	if err != nil {
+		return nil, false, err
+	}
+
+	m := dynamicpb.NewMessage(xd.ContainingMessage())
+	if err = (proto.UnmarshalOptions{Resolver: types}).Unmarshal(b, m); err != nil {
+		return nil, false, fmt.Errorf("unable to parse extension %q: %v", name, err)
+	}
+
+	if !m.Has(xt.TypeDescriptor()) {
+		return nil, false, nil
+	}
+
+	return m.Get(xt.TypeDescriptor()).Interface(), true, nil
+}
+
+// optionExtension returns the Extension with the fully qualified name that
+// extends the message of opts. The Extensions are resolved from the options
+// message in f or its transitive imports. An error is returned if there is no
+// such Extension, including if the options message itself is not present, in
+// which case no custom options can be set on the File's entities.
+func optionExtension(f File, opts proto.Message, name string) (Extension, error) {
+	optsName := "." + string(opts.ProtoReflect().Descriptor().FullName())
+	name = "." + strings.TrimPrefix(name, ".")
+
+	for _, fl := range append([]File{f}, f.TransitiveImports()...) {
+		for _, m := range fl.Messages() {
+			if m.FullyQualifiedName() != optsName {
+				continue
+			}
+
+			for _, x := range m.Extensions() {
+				if x.FullyQualifiedName() == name {
+					return x, nil
+				}
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("extension %q of %s not found", name, optsName)
+}
+
 var extractor extExtractor
 
 func init() { extractor = protoExtExtractor{} }
@@ -6,8 +6,11 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/runtime/protoimpl"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
 
 func TestExt_FullyQualifiedName(t *testing.T) {
@@ -25,6 +28,35 @@ func TestExt_Syntax(t *testing.T) {
 	assert.Equal(t, msg.Syntax(), e.Syntax())
 }
 
+func TestExt_Features(t *testing.T) {
+	t.Parallel()
+
+	f := dummyEditionsFile()
+	f.desc.Options = &descriptor.FileOptions{Features: &descriptor.FeatureSet{
+		RepeatedFieldEncoding: descriptor.FeatureSet_EXPANDED.Enum(),
+	}}
+
+	rep := descriptor.FieldDescriptorProto_LABEL_REPEATED
+	i32 := descriptor.FieldDescriptorProto_TYPE_INT32
+
+	e := &ext{parent: f}
+	e.desc = &descriptor.FieldDescriptorProto{Type: &i32}
+
+	assert.Equal(t, descriptor.FeatureSet_EXPANDED, e.Features().GetRepeatedFieldEncoding())
+	assert.True(t, e.HasPresence())
+	assert.False(t, e.Required())
+	assert.False(t, e.IsPacked())
+
+	e.desc.Label = &rep
+	assert.False(t, e.HasPresence())
+	assert.False(t, e.IsPacked())
+
+	e.desc.Options = &descriptor.FieldOptions{Features: &descriptor.FeatureSet{
+		RepeatedFieldEncoding: descriptor.FeatureSet_PACKED.Enum(),
+	}}
+	assert.True(t, e.IsPacked())
+}
+
 func TestExt_Package(t *testing.T) {
 	t.Parallel()
 
@@ -191,3 +223,99 @@ func (e *mockExtension) accept(v Visitor) error {
 	}
 	return err
 }
+
+func TestExtensionByName(t *testing.T) {
+	t.Parallel()
+
+	g := buildGraph(t, "extensions")
+
+	lookup := func(name string) Entity {
+		ent, ok := g.Lookup(name)
+		require.True(t, ok, name)
+		return ent
+	}
+
+	tests := []struct {
+		entity, ext string
+		expected    interface{}
+	}{
+		{"extensions/everything.proto", "extensions.ext.owner", "IDL Tools"},
+		{".extensions.RootMessage", ".extensions.ext.annotated", true},
+		{".extensions.RootMessage.nested_msg", "extensions.ext.name", "reflection"},
+		{".extensions.RootMessage.union", "extensions.ext.float", 5.67},
+		{".extensions.API", "extensions.ext.host", "Alex Trebek"},
+		{".extensions.API.Do", "extensions.ext.header", "X-Foo=BAR"},
+	}
+
+	for _, test := range tests {
+		tc := test
+		t.Run(tc.entity, func(t *testing.T) {
+			t.Parallel()
+
+			val, ok, err := lookup(tc.entity).ExtensionByName(tc.ext)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, tc.expected, val)
+		})
+	}
+
+	t.Run("message", func(t *testing.T) {
+		t.Parallel()
+
+		val, ok, err := lookup(".extensions.RootMessage.NestedEnum").ExtensionByName("extensions.ext.ext")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		require.Implements(t, (*protoreflect.Message)(nil), val)
+		assert.Equal(t, protoreflect.FullName("extensions.ext.EnumExtension"),
+			val.(protoreflect.Message).Descriptor().FullName())
+	})
+
+	t.Run("repeated", func(t *testing.T) {
+		t.Parallel()
+
+		ev := lookup(".extensions.RootMessage.NestedEnum").(Enum).Values()[0]
+		val, ok, err := ev.ExtensionByName("extensions.ext.numbers")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		require.Implements(t, (*protoreflect.List)(nil), val)
+		list := val.(protoreflect.List)
+		require.Equal(t, 1, list.Len())
+		assert.Equal(t, int32(1), list.Get(0).Interface())
+	})
+
+	t.Run("not set", func(t *testing.T) {
+		t.Parallel()
+
+		val, ok, err := lookup(".extensions.RootMessage.nested_enum").ExtensionByName("extensions.ext.name")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.Nil(t, val)
+
+		_, ok, err = lookup(".extensions.RootMessage.nested_msg").ExtensionByName("extensions.Request.footer")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		t.Parallel()
+
+		_, ok, err := lookup(".extensions.RootMessage.nested_msg").ExtensionByName("extensions.ext.owner")
+		assert.EqualError(t, err, `extension ".extensions.ext.owner" of .google.protobuf.FieldOptions not found`)
+		assert.False(t, ok)
+
+		// the options message is not imported by the file
+		doc, ok := buildGraph(t, "doc").Lookup("doc/doc.proto")
+		require.True(t, ok)
+		_, ok, err = doc.ExtensionByName("extensions.ext.owner")
+		assert.EqualError(t, err, `extension ".extensions.ext.owner" of .google.protobuf.FileOptions not found`)
+		assert.False(t, ok)
+	})
+
+	t.Run("no options", func(t *testing.T) {
+		t.Parallel()
+
+		_, ok, err := lookup(".extensions.Response").ExtensionByName("extensions.ext.annotated")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
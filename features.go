@@ -0,0 +1,116 @@
+package pgs
+
+import (
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+// featureDefaults are the default FeatureSet values for each edition, ordered
+// by ascending edition. An edition without an entry uses the defaults of the
+// closest preceding edition.
+var featureDefaults = []struct {
+	edition  Edition
+	features *descriptor.FeatureSet
+}{
+	{
+		edition: EditionProto2,
+		features: &descriptor.FeatureSet{
+			FieldPresence:         descriptor.FeatureSet_EXPLICIT.Enum(),
+			EnumType:              descriptor.FeatureSet_CLOSED.Enum(),
+			RepeatedFieldEncoding: descriptor.FeatureSet_EXPANDED.Enum(),
+			Utf8Validation:        descriptor.FeatureSet_NONE.Enum(),
+			MessageEncoding:       descriptor.FeatureSet_LENGTH_PREFIXED.Enum(),
+			JsonFormat:            descriptor.FeatureSet_LEGACY_BEST_EFFORT.Enum(),
+		},
+	},
+	{
+		edition: EditionProto3,
+		features: &descriptor.FeatureSet{
+			FieldPresence:         descriptor.FeatureSet_IMPLICIT.Enum(),
+			EnumType:              descriptor.FeatureSet_OPEN.Enum(),
+			RepeatedFieldEncoding: descriptor.FeatureSet_PACKED.Enum(),
+			Utf8Validation:        descriptor.FeatureSet_VERIFY.Enum(),
+			MessageEncoding:       descriptor.FeatureSet_LENGTH_PREFIXED.Enum(),
+			JsonFormat:            descriptor.FeatureSet_ALLOW.Enum(),
+		},
+	},
+	{
+		edition: Edition2023,
+		features: &descriptor.FeatureSet{
+			FieldPresence:         descriptor.FeatureSet_EXPLICIT.Enum(),
+			EnumType:              descriptor.FeatureSet_OPEN.Enum(),
+			RepeatedFieldEncoding: descriptor.FeatureSet_PACKED.Enum(),
+			Utf8Validation:        descriptor.FeatureSet_VERIFY.Enum(),
+			MessageEncoding:       descriptor.FeatureSet_LENGTH_PREFIXED.Enum(),
+			JsonFormat:            descriptor.FeatureSet_ALLOW.Enum(),
+		},
+	},
+}
+
+// defaultFeatures returns a copy of the default FeatureSet for edition e.
+func defaultFeatures(e Edition) *descriptor.FeatureSet {
+	def := featureDefaults[0].features
+	for _, d := range featureDefaults {
+		if d.edition > e {
+			break
+		}
+		def = d.features
+	}
+
+	return proto.Clone(def).(*descriptor.FeatureSet)
+}
+
+// mergeFeatures returns a new FeatureSet with the explicitly set values of
+// child overriding those of the resolved parent FeatureSet.
+func mergeFeatures(parent, child *descriptor.FeatureSet) *descriptor.FeatureSet {
+	out := proto.Clone(parent).(*descriptor.FeatureSet)
+	if child != nil {
+		proto.Merge(out, child)
+	}
+	return out
+}
+
+// fieldFeatures resolves the features of a field (or extension) declared in
+// the scope of parent. Legacy proto2 and proto3 constructs that predate
+// editions (the required label, proto3 optional, the packed option and group
+// types) are translated to their equivalent features.
+func fieldFeatures(parent Entity, fd *descriptor.FieldDescriptorProto) *descriptor.FeatureSet {
+	fs := mergeFeatures(parent.Features(), fd.GetOptions().GetFeatures())
+
+	switch {
+	case fd.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REQUIRED &&
+		parent.Syntax().SupportsRequiredPrefix():
+		fs.FieldPresence = descriptor.FeatureSet_LEGACY_REQUIRED.Enum()
+	case fd.GetProto3Optional():
+		fs.FieldPresence = descriptor.FeatureSet_EXPLICIT.Enum()
+	}
+
+	if opts := fd.GetOptions(); opts != nil && opts.Packed != nil {
+		if opts.GetPacked() {
+			fs.RepeatedFieldEncoding = descriptor.FeatureSet_PACKED.Enum()
+		} else {
+			fs.RepeatedFieldEncoding = descriptor.FeatureSet_EXPANDED.Enum()
+		}
+	}
+
+	if fd.GetType() == descriptor.FieldDescriptorProto_TYPE_GROUP {
+		fs.MessageEncoding = descriptor.FeatureSet_DELIMITED.Enum()
+	}
+
+	return fs
+}
+
+// isPacked returns true if f is a repeated field of a packable scalar or enum
+// type that is encoded using the packed wire format.
+func isPacked(f Field) bool {
+	if f.Descriptor().GetLabel() != descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		return false
+	}
+
+	switch pt := ProtoType(f.Descriptor().GetType()); {
+	case pt.IsNumeric(), pt == BoolT, pt == EnumT:
+		return f.Features().GetRepeatedFieldEncoding() == descriptor.FeatureSet_PACKED
+	default:
+		return false
+	}
+}
@@ -0,0 +1,137 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestDefaultFeatures(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		edition  Edition
+		presence descriptor.FeatureSet_FieldPresence
+		enum     descriptor.FeatureSet_EnumType
+	}{
+		{EditionUnknown, descriptor.FeatureSet_EXPLICIT, descriptor.FeatureSet_CLOSED},
+		{EditionProto2, descriptor.FeatureSet_EXPLICIT, descriptor.FeatureSet_CLOSED},
+		{EditionProto3, descriptor.FeatureSet_IMPLICIT, descriptor.FeatureSet_OPEN},
+		{Edition2023, descriptor.FeatureSet_EXPLICIT, descriptor.FeatureSet_OPEN},
+		{Edition2024, descriptor.FeatureSet_EXPLICIT, descriptor.FeatureSet_OPEN},
+	}
+
+	for _, test := range tests {
+		fs := defaultFeatures(test.edition)
+		assert.Equal(t, test.presence, fs.GetFieldPresence(), test.edition.String())
+		assert.Equal(t, test.enum, fs.GetEnumType(), test.edition.String())
+	}
+
+	fs := defaultFeatures(Edition2023)
+	fs.FieldPresence = descriptor.FeatureSet_IMPLICIT.Enum()
+	assert.Equal(t, descriptor.FeatureSet_EXPLICIT, defaultFeatures(Edition2023).GetFieldPresence(),
+		"defaults must not be mutated")
+}
+
+func TestMergeFeatures(t *testing.T) {
+	t.Parallel()
+
+	parent := defaultFeatures(Edition2023)
+	child := &descriptor.FeatureSet{EnumType: descriptor.FeatureSet_CLOSED.Enum()}
+
+	fs := mergeFeatures(parent, child)
+	assert.Equal(t, descriptor.FeatureSet_CLOSED, fs.GetEnumType())
+	assert.Equal(t, descriptor.FeatureSet_EXPLICIT, fs.GetFieldPresence())
+	assert.Equal(t, descriptor.FeatureSet_OPEN, parent.GetEnumType())
+
+	assert.True(t, proto.Equal(parent, mergeFeatures(parent, nil)))
+}
+
+func TestFieldFeatures(t *testing.T) {
+	t.Parallel()
+
+	m := dummyMsg()
+	m.File().(*file).desc.Syntax = proto.String(string(Proto2))
+
+	req := descriptor.FieldDescriptorProto_LABEL_REQUIRED
+	fs := fieldFeatures(m, &descriptor.FieldDescriptorProto{Label: &req})
+	assert.Equal(t, descriptor.FeatureSet_LEGACY_REQUIRED, fs.GetFieldPresence())
+
+	fs = fieldFeatures(m, &descriptor.FieldDescriptorProto{
+		Options: &descriptor.FieldOptions{Packed: proto.Bool(true)},
+	})
+	assert.Equal(t, descriptor.FeatureSet_PACKED, fs.GetRepeatedFieldEncoding())
+
+	grp := descriptor.FieldDescriptorProto_TYPE_GROUP
+	fs = fieldFeatures(m, &descriptor.FieldDescriptorProto{Type: &grp})
+	assert.Equal(t, descriptor.FeatureSet_DELIMITED, fs.GetMessageEncoding())
+
+	m.File().(*file).desc.Syntax = proto.String(string(Proto3))
+
+	fs = fieldFeatures(m, &descriptor.FieldDescriptorProto{Proto3Optional: proto.Bool(true)})
+	assert.Equal(t, descriptor.FeatureSet_EXPLICIT, fs.GetFieldPresence())
+
+	fs = fieldFeatures(m, &descriptor.FieldDescriptorProto{
+		Options: &descriptor.FieldOptions{Packed: proto.Bool(false)},
+	})
+	assert.Equal(t, descriptor.FeatureSet_EXPANDED, fs.GetRepeatedFieldEncoding())
+}
+
+func TestFeatures_Editions(t *testing.T) {
+	t.Parallel()
+
+	f := dummyEditionsFile()
+	f.desc.Options = &descriptor.FileOptions{Features: &descriptor.FeatureSet{
+		FieldPresence: descriptor.FeatureSet_IMPLICIT.Enum(),
+	}}
+
+	m := &msg{desc: &descriptor.DescriptorProto{
+		Name: proto.String("msg"),
+		Options: &descriptor.MessageOptions{Features: &descriptor.FeatureSet{
+			RepeatedFieldEncoding: descriptor.FeatureSet_EXPANDED.Enum(),
+		}},
+	}}
+	f.addMessage(m)
+
+	o := &oneof{desc: &descriptor.OneofDescriptorProto{
+		Name: proto.String("oneof"),
+		Options: &descriptor.OneofOptions{Features: &descriptor.FeatureSet{
+			Utf8Validation: descriptor.FeatureSet_NONE.Enum(),
+		}},
+	}}
+	m.addOneOf(o)
+
+	fld := &field{desc: &descriptor.FieldDescriptorProto{
+		Name: proto.String("fld"),
+		Options: &descriptor.FieldOptions{Features: &descriptor.FeatureSet{
+			FieldPresence: descriptor.FeatureSet_EXPLICIT.Enum(),
+		}},
+	}}
+	m.addField(fld)
+
+	ofld := &field{desc: &descriptor.FieldDescriptorProto{Name: proto.String("ofld")}}
+	m.addField(ofld)
+	o.addField(ofld)
+
+	assert.Equal(t, descriptor.FeatureSet_IMPLICIT, f.Features().GetFieldPresence())
+	assert.Equal(t, descriptor.FeatureSet_PACKED, f.Features().GetRepeatedFieldEncoding())
+
+	assert.Equal(t, descriptor.FeatureSet_IMPLICIT, m.Features().GetFieldPresence())
+	assert.Equal(t, descriptor.FeatureSet_EXPANDED, m.Features().GetRepeatedFieldEncoding())
+
+	assert.Equal(t, descriptor.FeatureSet_EXPLICIT, fld.Features().GetFieldPresence())
+	assert.Equal(t, descriptor.FeatureSet_EXPANDED, fld.Features().GetRepeatedFieldEncoding())
+	assert.Equal(t, descriptor.FeatureSet_VERIFY, fld.Features().GetUtf8Validation())
+
+	assert.Equal(t, descriptor.FeatureSet_NONE, ofld.Features().GetUtf8Validation())
+	assert.Equal(t, descriptor.FeatureSet_IMPLICIT, ofld.Features().GetFieldPresence())
+}
+
+func dummyEditionsFile() *file {
+	f := dummyFile()
+	f.desc.Syntax = proto.String(string(Editions))
+	f.desc.Edition = Edition2023.ProtoPtr()
+	return f
+}
@@ -1,6 +1,7 @@
 package pgs
 
 import (
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/runtime/protoimpl"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
@@ -13,6 +14,11 @@ type Field interface {
 	// Descriptor returns the proto descriptor for this field
 	Descriptor() *descriptor.FieldDescriptorProto
 
+	// Reflect returns the protoreflect descriptor for this field, built lazily
+	// from its File's descriptor. For Extensions, this is an
+	// ExtensionDescriptor. Nil is returned if the descriptor cannot be built.
+	Reflect() protoreflect.FieldDescriptor
+
 	// Message returns the Message containing this Field.
 	Message() Message
 
@@ -34,15 +40,45 @@ type Field interface {
 
 	// HasPresence returns true for all fields that have explicit presence as defined by:
 	// See: https://github.com/protocolbuffers/protobuf/blob/v3.17.0/docs/field_presence.md
+	// For singular scalar fields, this is determined by the resolved
+	// field_presence feature.
 	HasPresence() bool
 
 	// HasOptionalKeyword returns whether the field is labeled as optional.
+	// Fields in files using Editions syntax never have the optional keyword.
 	HasOptionalKeyword() bool
 
-	// Required returns whether the field is labeled as required. This
-	// will only be true if the syntax is proto2.
+	// Required returns whether the field is labeled as required. This will
+	// only be true if the syntax is proto2 or the resolved field_presence
+	// feature is LEGACY_REQUIRED.
 	Required() bool
 
+	// IsPacked returns true if this is a repeated scalar or enum field that
+	// uses the packed wire encoding, as determined by the resolved
+	// repeated_field_encoding feature.
+	IsPacked() bool
+
+	// HasDefault returns true if the field has an explicit default value, which
+	// is only supported for singular scalar and enum fields with explicit
+	// presence (eg, in proto2).
+	HasDefault() bool
+
+	// DefaultValue returns the typed default value of a singular scalar or enum
+	// field: the explicit default if HasDefault is true, or else the zero value
+	// of its type. The value is one of int32, int64, uint32, uint64, float32,
+	// float64, bool, string or []byte (matching the types used by protoreflect),
+	// or for enum fields, the EnumValue of the default (the Enum's first value if
+	// not explicit). Nil is returned for message, repeated and map fields, or if
+	// the explicit default cannot be parsed.
+	DefaultValue() interface{}
+
+	// JSONName returns the name of the field in the JSON encoding, as used by
+	// protojson. This is the json_name option if set, otherwise the name is
+	// derived as protoc does, by removing underscores and capitalizing the
+	// letter following each. For Extensions, this is the fully qualified name of
+	// the Extension, enclosed in brackets.
+	JSONName() string
+
 	setMessage(m Message)
 	setOneOf(o OneOf)
 	addType(t FieldType)
@@ -74,10 +110,39 @@ func (f *field) Type() FieldType                              { return f.typ }
 func (f *field) setMessage(m Message)                         { f.msg = m }
 func (f *field) setOneOf(o OneOf)                             { f.oneof = o }
 
+func (f *field) Reflect() protoreflect.FieldDescriptor {
+	d, _ := reflectDescriptor(f).(protoreflect.FieldDescriptor)
+	return d
+}
+
+func (f *field) HasDefault() bool { return f.desc.DefaultValue != nil }
+
+func (f *field) DefaultValue() interface{} {
+	v, err := defaultValue(f)
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+func (f *field) JSONName() string {
+	if f.desc.JsonName != nil {
+		return f.desc.GetJsonName()
+	}
+	return jsonCamelCase(f.desc.GetName())
+}
+
 func (f *field) InRealOneOf() bool {
 	return f.InOneOf() && !f.desc.GetProto3Optional()
 }
 
+func (f *field) Features() *descriptor.FeatureSet {
+	if f.oneof != nil {
+		return fieldFeatures(f.oneof, f.desc)
+	}
+	return fieldFeatures(f.msg, f.desc)
+}
+
 func (f *field) HasPresence() bool {
 	if f.InOneOf() {
 		return true
@@ -88,26 +153,28 @@ func (f *field) HasPresence() bool {
 	}
 
 	if !f.Type().IsRepeated() && !f.Type().IsMap() {
-		if f.Syntax() == Proto2 {
-			return true
-		}
-		return f.HasOptionalKeyword()
+		return f.Features().GetFieldPresence() != descriptor.FeatureSet_IMPLICIT
 	}
 	return false
 }
 
 func (f *field) HasOptionalKeyword() bool {
-	if f.Syntax() == Proto3 {
+	switch f.Syntax() {
+	case Proto3:
 		return f.desc.GetProto3Optional()
+	case Editions:
+		return false
+	default:
+		return f.desc.GetLabel() == descriptor.FieldDescriptorProto_LABEL_OPTIONAL
 	}
-	return f.desc.GetLabel() == descriptor.FieldDescriptorProto_LABEL_OPTIONAL
 }
 
 func (f *field) Required() bool {
-	return f.Syntax().SupportsRequiredPrefix() &&
-		f.desc.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REQUIRED
+	return f.Features().GetFieldPresence() == descriptor.FeatureSet_LEGACY_REQUIRED
 }
 
+func (f *field) IsPacked() bool { return isPacked(f) }
+
 func (f *field) addType(t FieldType) {
 	t.setField(f)
 	f.typ = t
@@ -117,6 +184,10 @@ func (f *field) Extension(desc *protoimpl.ExtensionInfo, ext interface{}) (ok bo
 	return extension(f.desc.GetOptions(), desc, &ext)
 }
 
+func (f *field) ExtensionByName(name string) (interface{}, bool, error) {
+	return dynamicExtension(f, f.desc.GetOptions(), name)
+}
+
 func (f *field) accept(v Visitor) (err error) {
 	if v == nil {
 		return
@@ -135,4 +206,14 @@ func (f *field) childAtPath(path []int32) Entity {
 
 func (f *field) addSourceCodeInfo(info SourceCodeInfo) { f.info = info }
 
+func (f *field) OptionSourceCodeInfo(path ...int32) SourceCodeInfo {
+	return sourceCodeInfoAt(f, append([]int32{fieldOptionsPath}, path...)...)
+}
+
+func (f *field) SourcePath() []int32 {
+	flds := f.msg.Descriptor().GetField()
+	i := indexOf(len(flds), func(i int) bool { return flds[i] == f.desc })
+	return childPath(f.msg.SourcePath(), messageTypeFieldPath, i)
+}
+
 var _ Field = (*field)(nil)
@@ -2,9 +2,11 @@ package pgs
 
 import (
 	"errors"
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/proto"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
@@ -130,6 +132,56 @@ func TestField_HasPresence(t *testing.T) {
 	assert.True(t, f.HasPresence())
 }
 
+func TestField_HasPresence_Editions(t *testing.T) {
+	t.Parallel()
+
+	f := dummyEditionsFile()
+	m := &msg{desc: &descriptor.DescriptorProto{Name: proto.String("msg")}}
+	f.addMessage(m)
+
+	fld := &field{desc: &descriptor.FieldDescriptorProto{Name: proto.String("fld")}}
+	m.addField(fld)
+	fld.addType(&scalarT{})
+	assert.True(t, fld.HasPresence())
+
+	fld.desc.Options = &descriptor.FieldOptions{Features: &descriptor.FeatureSet{
+		FieldPresence: descriptor.FeatureSet_IMPLICIT.Enum(),
+	}}
+	assert.False(t, fld.HasPresence())
+
+	fld.desc.Options.Features.FieldPresence = descriptor.FeatureSet_LEGACY_REQUIRED.Enum()
+	assert.True(t, fld.HasPresence())
+	assert.True(t, fld.Required())
+	assert.False(t, fld.HasOptionalKeyword())
+}
+
+func TestField_IsPacked(t *testing.T) {
+	t.Parallel()
+
+	rep := descriptor.FieldDescriptorProto_LABEL_REPEATED
+	i32 := descriptor.FieldDescriptorProto_TYPE_INT32
+	str := descriptor.FieldDescriptorProto_TYPE_STRING
+
+	f := dummyField()
+	assert.False(t, f.IsPacked(), "singular fields are never packed")
+
+	f.desc = &descriptor.FieldDescriptorProto{Label: &rep, Type: &str}
+	assert.False(t, f.IsPacked(), "strings are not packable")
+
+	f.desc = &descriptor.FieldDescriptorProto{Label: &rep, Type: &i32}
+	assert.True(t, f.IsPacked(), "proto3 packs by default")
+
+	f.desc.Options = &descriptor.FieldOptions{Packed: proto.Bool(false)}
+	assert.False(t, f.IsPacked(), "proto3 + packed=false")
+
+	f.File().(*file).desc.Syntax = proto.String(string(Proto2))
+	f.desc.Options = nil
+	assert.False(t, f.IsPacked(), "proto2 expands by default")
+
+	f.desc.Options = &descriptor.FieldOptions{Packed: proto.Bool(true)}
+	assert.True(t, f.IsPacked(), "proto2 + packed=true")
+}
+
 func TestField_HasOptionalKeyword(t *testing.T) {
 	t.Parallel()
 
@@ -160,6 +212,108 @@ func TestField_Type(t *testing.T) {
 	assert.Equal(t, f.typ, f.Type())
 }
 
+func TestField_DefaultValue(t *testing.T) {
+	t.Parallel()
+
+	ast := buildGraph(t, "defaults")
+	e, ok := ast.Lookup(".graph.defaults.Defaults")
+	require.True(t, ok)
+	m := e.(Message)
+
+	values := map[string]interface{}{}
+	for _, f := range m.Fields() {
+		values[f.Name().String()] = f.DefaultValue()
+	}
+
+	enumName := func(v interface{}) string { return v.(EnumValue).Name().String() }
+
+	assert.Equal(t, int32(31), values["int32_hex"])
+	assert.Equal(t, int32(-42), values["sint32_neg"])
+	assert.Equal(t, int64(math.MaxInt64), values["int64_max"])
+	assert.Equal(t, uint32(15), values["uint32_oct"])
+	assert.Equal(t, uint64(math.MaxUint64), values["fixed64_val"])
+	assert.Equal(t, float32(math.Inf(1)), values["float_inf"])
+	assert.Equal(t, math.Inf(-1), values["double_neg_inf"])
+	assert.True(t, math.IsNaN(values["double_nan"].(float64)))
+	assert.Equal(t, 1.5e-3, values["double_exp"])
+	assert.Equal(t, true, values["bool_val"])
+	assert.Equal(t, "he said \"hi\"\n", values["string_val"])
+	assert.Equal(t, []byte("\x00\x01\xffa\\b"), values["bytes_val"])
+	assert.Equal(t, "GREEN", enumName(values["color"]))
+
+	assert.Equal(t, int32(0), values["no_default"])
+	assert.Equal(t, "RED", enumName(values["no_default_color"]))
+	assert.Equal(t, []byte(nil), values["no_default_bytes"])
+	assert.Nil(t, values["list"])
+	assert.Nil(t, values["msg"])
+
+	fields := append([]Field{}, m.Fields()...)
+	for _, x := range m.Extensions() {
+		fields = append(fields, x)
+	}
+	require.Len(t, fields, 21)
+
+	for _, f := range fields {
+		rd := f.Reflect()
+		require.NotNil(t, rd, f.FullyQualifiedName())
+
+		assert.Equal(t, rd.HasDefault(), f.HasDefault(), f.FullyQualifiedName())
+		if f.Type().IsRepeated() || f.Type().IsEmbed() {
+			continue
+		}
+
+		switch v := f.DefaultValue().(type) {
+		case EnumValue:
+			assert.Equal(t, int32(rd.Default().Enum()), v.Value())
+		case float64:
+			if math.IsNaN(v) {
+				assert.True(t, math.IsNaN(rd.Default().Float()))
+				continue
+			}
+			assert.Equal(t, rd.Default().Interface(), v, f.FullyQualifiedName())
+		default:
+			assert.Equal(t, rd.Default().Interface(), v, f.FullyQualifiedName())
+		}
+	}
+
+	x := m.Extensions()[0]
+	assert.True(t, x.HasDefault())
+	assert.Equal(t, int32(7), x.DefaultValue())
+
+	f := &field{desc: &descriptor.FieldDescriptorProto{
+		Type:         descriptor.FieldDescriptorProto_TYPE_INT32.Enum(),
+		DefaultValue: proto.String("abc"),
+	}, typ: &scalarT{}}
+	assert.True(t, f.HasDefault())
+	assert.Nil(t, f.DefaultValue())
+}
+
+func TestField_JSONName(t *testing.T) {
+	t.Parallel()
+
+	f := &field{desc: &descriptor.FieldDescriptorProto{Name: proto.String("foo_bar")}}
+	assert.Equal(t, "fooBar", f.JSONName())
+
+	f.desc.JsonName = proto.String("custom")
+	assert.Equal(t, "custom", f.JSONName())
+
+	ast := buildGraph(t, "defaults")
+	e, ok := ast.Lookup(".graph.defaults.Defaults")
+	require.True(t, ok)
+
+	for _, fld := range e.(Message).Fields() {
+		assert.Equal(t, fld.Reflect().JSONName(), fld.JSONName())
+		fld.Descriptor().JsonName = nil
+		if fld.Name() != "custom_json" {
+			assert.Equal(t, fld.Reflect().JSONName(), fld.JSONName())
+		}
+	}
+
+	x, ok := ast.Lookup(".graph.defaults.ext_val")
+	require.True(t, ok)
+	assert.Equal(t, "[graph.defaults.ext_val]", x.(Extension).JSONName())
+}
+
 func TestField_Extension(t *testing.T) {
 	// cannot be parallel
 
@@ -83,12 +83,13 @@ func (s *scalarT) Element() FieldTypeElem { return nil }
 func (s *scalarT) Key() FieldTypeElem     { return nil }
 
 func (s *scalarT) IsOptional() bool {
+	if s.IsRequired() {
+		return false
+	}
 	return !s.fld.Syntax().SupportsRequiredPrefix() || s.ProtoLabel() == Optional
 }
 
-func (s *scalarT) IsRequired() bool {
-	return s.fld.Syntax().SupportsRequiredPrefix() && s.ProtoLabel() == Required
-}
+func (s *scalarT) IsRequired() bool { return s.fld.Required() }
 
 func (s *scalarT) toElem() FieldTypeElem {
 	return &scalarE{
@@ -1,6 +1,7 @@
 package pgs
 
 import (
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/runtime/protoimpl"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
@@ -16,6 +17,15 @@ type File interface {
 	// Descriptor returns the underlying descriptor for the proto file
 	Descriptor() *descriptor.FileDescriptorProto
 
+	// Reflect returns the protoreflect descriptor for the proto file, built
+	// lazily (along with its imports) from the AST's descriptors using
+	// protodesc. Nil is returned if the descriptor cannot be built.
+	Reflect() protoreflect.FileDescriptor
+
+	// Edition returns the Edition of this file. Files using Proto2 or Proto3
+	// syntax return EditionProto2 or EditionProto3, respectively.
+	Edition() Edition
+
 	// TransitiveImports returns all direct and transitive dependencies of this
 	// File. Use Imports to obtain only direct dependencies.
 	TransitiveImports() []File
@@ -39,6 +49,17 @@ type File interface {
 	// stanza of the file.
 	PackageSourceCodeInfo() SourceCodeInfo
 
+	// EntityAtPath returns the innermost Entity containing the element at the
+	// path within the File's descriptor (see Entity.SourcePath). Paths into an
+	// entity's options, reserved ranges or other non-entity elements resolve to
+	// that entity; for instance, [4, 0, 7] resolves to the first Message. Nil is
+	// returned if the path does not exist in the File.
+	EntityAtPath(path []int32) Entity
+
+	// SourceLocations returns every location in the File's SourceCodeInfo,
+	// resolved to the Entity containing it, in the order emitted by protoc.
+	SourceLocations() []SourceLocation
+
 	setPackage(p Package)
 
 	addFileDependency(fl File)
@@ -63,6 +84,8 @@ type file struct {
 	srvs                    []Service
 	buildTarget             bool
 	syntaxInfo, packageInfo SourceCodeInfo
+	reg                     *reflectRegistry
+	locs                    map[string]SourceCodeInfo
 }
 
 func (f *file) Name() Name                                  { return Name(f.desc.GetName()) }
@@ -78,6 +101,23 @@ func (f *file) SourceCodeInfo() SourceCodeInfo              { return f.SyntaxSou
 func (f *file) SyntaxSourceCodeInfo() SourceCodeInfo        { return f.syntaxInfo }
 func (f *file) PackageSourceCodeInfo() SourceCodeInfo       { return f.packageInfo }
 
+func (f *file) Reflect() protoreflect.FileDescriptor { return f.reg.file(f) }
+
+func (f *file) Edition() Edition {
+	switch f.Syntax() {
+	case Editions:
+		return Edition(f.desc.GetEdition())
+	case Proto3:
+		return EditionProto3
+	default:
+		return EditionProto2
+	}
+}
+
+func (f *file) Features() *descriptor.FeatureSet {
+	return mergeFeatures(defaultFeatures(f.Edition()), f.desc.GetOptions().GetFeatures())
+}
+
 func (f *file) Enums() []Enum {
 	return f.enums
 }
@@ -185,6 +225,10 @@ func (f *file) Extension(desc *protoimpl.ExtensionInfo, ext interface{}) (bool,
 	return extension(f.desc.GetOptions(), desc, &ext)
 }
 
+func (f *file) ExtensionByName(name string) (interface{}, bool, error) {
+	return dynamicExtension(f, f.desc.GetOptions(), name)
+}
+
 func (f *file) DefinedExtensions() []Extension {
 	return f.defExts
 }
@@ -265,13 +309,15 @@ func (f *file) childAtPath(path []int32) Entity {
 	}
 
 	var child Entity
-	switch path[0] {
-	case messageTypePath:
-		child = f.msgs[path[1]]
-	case enumTypePath:
-		child = f.enums[path[1]]
-	case servicePath:
-		child = f.srvs[path[1]]
+	switch i := path[1]; {
+	case path[0] == messageTypePath && inRange(i, len(f.msgs)):
+		child = f.msgs[i]
+	case path[0] == enumTypePath && inRange(i, len(f.enums)):
+		child = f.enums[i]
+	case path[0] == servicePath && inRange(i, len(f.srvs)):
+		child = f.srvs[i]
+	case path[0] == extensionPath && inRange(i, len(f.defExts)):
+		child = f.defExts[i]
 	default:
 		return nil
 	}
@@ -287,4 +333,48 @@ func (f *file) addPackageSourceCodeInfo(info SourceCodeInfo) {
 	f.packageInfo = info
 }
 
+func (f *file) OptionSourceCodeInfo(path ...int32) SourceCodeInfo {
+	return sourceCodeInfoAt(f, append([]int32{fileOptionsPath}, path...)...)
+}
+
+func (f *file) SourcePath() []int32 { return []int32{} }
+
+func (f *file) EntityAtPath(path []int32) Entity {
+	var e Entity = f
+	for len(path) >= 2 {
+		child := e.childAtPath(path[:2])
+		if child == nil {
+			if entityPath(e, path[0]) {
+				// the path refers to a child entity that does not exist
+				return nil
+			}
+			// the remainder of the path points into e's descriptor
+			break
+		}
+		e, path = child, path[2:]
+	}
+
+	return e
+}
+
+func (f *file) SourceLocations() []SourceLocation {
+	locs := f.desc.GetSourceCodeInfo().GetLocation()
+	out := make([]SourceLocation, 0, len(locs))
+
+	for _, loc := range locs {
+		e := f.EntityAtPath(loc.GetPath())
+		if e == nil {
+			continue
+		}
+
+		out = append(out, SourceLocation{
+			SourceCodeInfo: sci{desc: loc, file: f.Name().String()},
+			Entity:         e,
+			Path:           loc.GetPath()[len(e.SourcePath()):],
+		})
+	}
+
+	return out
+}
+
 var _ File = (*file)(nil)
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/proto"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
@@ -34,6 +35,38 @@ func TestFile_Syntax(t *testing.T) {
 	assert.Equal(t, Proto2, f.Syntax())
 }
 
+func TestFile_Edition(t *testing.T) {
+	t.Parallel()
+
+	f := &file{desc: &descriptor.FileDescriptorProto{}}
+	assert.Equal(t, EditionProto2, f.Edition())
+
+	f.desc.Syntax = proto.String(string(Proto3))
+	assert.Equal(t, EditionProto3, f.Edition())
+
+	f.desc.Syntax = proto.String(string(Editions))
+	f.desc.Edition = Edition2023.ProtoPtr()
+	assert.Equal(t, Edition2023, f.Edition())
+}
+
+func TestFile_Features(t *testing.T) {
+	t.Parallel()
+
+	f := &file{desc: &descriptor.FileDescriptorProto{}}
+	assert.Equal(t, descriptor.FeatureSet_EXPLICIT, f.Features().GetFieldPresence())
+	assert.Equal(t, descriptor.FeatureSet_CLOSED, f.Features().GetEnumType())
+
+	f.desc.Syntax = proto.String(string(Proto3))
+	assert.Equal(t, descriptor.FeatureSet_IMPLICIT, f.Features().GetFieldPresence())
+
+	f = dummyEditionsFile()
+	f.desc.Options = &descriptor.FileOptions{Features: &descriptor.FeatureSet{
+		EnumType: descriptor.FeatureSet_CLOSED.Enum(),
+	}}
+	assert.Equal(t, descriptor.FeatureSet_EXPLICIT, f.Features().GetFieldPresence())
+	assert.Equal(t, descriptor.FeatureSet_CLOSED, f.Features().GetEnumType())
+}
+
 func TestFile_Package(t *testing.T) {
 	t.Parallel()
 
@@ -364,3 +397,84 @@ func dummyFile() *file {
 
 	return f
 }
+
+func TestFile_EntityAtPath(t *testing.T) {
+	t.Parallel()
+
+	g := buildGraph(t, "locations")
+	f := g.Targets()["locations/locations.proto"]
+	require.NotNil(t, f)
+
+	lookup := func(name string) Entity {
+		ent, ok := g.Lookup(name)
+		require.True(t, ok, name)
+		return ent
+	}
+
+	rng := lookup(".graph.locations.Range")
+	status := lookup(".graph.locations.Status")
+
+	tests := []struct {
+		path     []int32
+		expected Entity
+	}{
+		{nil, f},
+		{[]int32{syntaxPath}, f},
+		{[]int32{fileOptionsPath, 50000}, f},
+		{[]int32{extensionPath}, f},
+		{[]int32{extensionPath, 1}, f.DefinedExtensions()[1]},
+		{[]int32{extensionPath, 1, 6}, f.DefinedExtensions()[1]},
+		{[]int32{messageTypePath, 1}, rng},
+		{[]int32{messageTypePath, 1, messageTypeOptionsPath, 3}, rng},
+		{[]int32{messageTypePath, 1, messageTypeReservedRangePath, 1, 2}, rng},
+		{[]int32{messageTypePath, 1, messageTypeFieldPath, 0}, lookup(".graph.locations.Range.value")},
+		{[]int32{messageTypePath, 1, messageTypeFieldPath, 0, fieldOptionsPath, 50001, 1}, lookup(".graph.locations.Range.value")},
+		{[]int32{messageTypePath, 1, messageTypeExtensionPath, 0}, lookup(".graph.locations.Range.nested_opt")},
+		{[]int32{enumTypePath, 0, enumTypeReservedNamePath, 0}, status},
+		{[]int32{enumTypePath, 0, enumTypeValuePath, 1, enumValueOptionsPath, 1}, lookup(".graph.locations.Status.ACTIVE")},
+		{[]int32{messageTypePath, 5}, nil},
+		{[]int32{messageTypePath, -1}, nil},
+		{[]int32{messageTypePath, 1, messageTypeFieldPath, 3}, nil},
+		{[]int32{enumTypePath, 0, enumTypeValuePath, 3, 1}, nil},
+		{[]int32{servicePath, 0}, nil},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, f.EntityAtPath(test.path), "%v", test.path)
+	}
+}
+
+func TestFile_SourceLocations(t *testing.T) {
+	t.Parallel()
+
+	g := buildGraph(t, "locations")
+	f := g.Targets()["locations/locations.proto"]
+	require.NotNil(t, f)
+
+	locs := f.SourceLocations()
+	require.Len(t, locs, len(f.Descriptor().GetSourceCodeInfo().GetLocation()))
+
+	for _, loc := range locs {
+		require.NotNil(t, loc.Entity)
+		assert.Equal(t, append([]int32{}, loc.Location().GetPath()...), append(loc.Entity.SourcePath(), loc.Path...))
+		if len(loc.Path) == 0 && loc.Entity != f {
+			assert.Equal(t, loc.Entity.SourceCodeInfo(), loc.SourceCodeInfo)
+		}
+	}
+
+	value, ok := g.Lookup(".graph.locations.Range.value")
+	require.True(t, ok)
+
+	var opts []string
+	for _, loc := range locs {
+		if loc.Entity == value && len(loc.Path) > 0 && loc.Path[0] == fieldOptionsPath {
+			opts = append(opts, loc.Position())
+		}
+	}
+	assert.Equal(t, []string{
+		"locations/locations.proto:28:21",
+		"locations/locations.proto:28:22",
+		"locations/locations.proto:28:41",
+		"locations/locations.proto:28:57",
+	}, opts)
+}
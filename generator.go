@@ -20,6 +20,8 @@ type Generator struct {
 
 	debug bool // whether or not to print debug messages
 
+	workers int // max number of modules executed concurrently
+
 	params        Parameters     // CLI parameters passed in from protoc
 	paramMutators []ParamMutator // registered param mutators
 }
@@ -38,7 +40,9 @@ func Init(opts ...InitOption) *Generator {
 		opt(g)
 	}
 
-	g.Debugger = initDebugger(g.debug, log.New(os.Stderr, "", 0))
+	if g.Debugger == nil {
+		g.Debugger = initDebugger(g.debug, log.New(os.Stderr, "", 0))
+	}
 	g.persister.SetDebugger(g.Debugger)
 
 	return g
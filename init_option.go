@@ -3,6 +3,7 @@ package pgs
 import (
 	"io"
 	"os"
+	"runtime"
 
 	"github.com/spf13/afero"
 )
@@ -32,6 +33,12 @@ func DebugMode() InitOption { return func(g *Generator) { g.debug = true } }
 // is non-empty.
 func DebugEnv(f string) InitOption { return func(g *Generator) { g.debug = os.Getenv(f) != "" } }
 
+// UseDebugger replaces the Generator's root Debugger with d. This is primarily
+// useful for testing a plugin end to end with a MockDebugger, which does not
+// exit the process on failure. DebugMode and DebugEnv have no effect if this
+// option is used.
+func UseDebugger(d Debugger) InitOption { return func(g *Generator) { g.Debugger = d } }
+
 // MutateParams applies pm to the parameters passed in from protoc.
 func MutateParams(pm ...ParamMutator) InitOption {
 	return func(g *Generator) { g.paramMutators = append(g.paramMutators, pm...) }
@@ -48,6 +55,92 @@ func BiDirectional() InitOption {
 	return func(g *Generator) { g.workflow = &onceWorkflow{workflow: &standardWorkflow{BiDi: true}} }
 }
 
+// ParallelModules executes independent modules concurrently, with at most n
+// modules executing at the same time. If n is less than 1, the number of CPUs
+// is used. The Artifacts of each module are persisted in the same order as
+// without this option (registration order, adjusted for any dependencies), so
+// output remains deterministic. Modules implementing DependentModule are
+// executed only after the modules they depend on have completed. Modules must
+// not modify shared state without synchronization.
+func ParallelModules(n int) InitOption {
+	return func(g *Generator) {
+		if n < 1 {
+			n = runtime.NumCPU()
+		}
+		g.workers = n
+	}
+}
+
+// ParallelRendering renders Template Artifacts and applies PostProcessors
+// concurrently, using at most n goroutines. If n is less than 1, the number of
+// CPUs is used. Artifacts are still written in the order they were returned by
+// the Modules, so appends, injections, and overwrites behave the same as
+// without this option. Templates from text/template and html/template execute
+// in parallel, while other Template implementations are executed one at a
+// time. Registered PostProcessors must be safe for concurrent use.
+func ParallelRendering(n int) InitOption {
+	return func(g *Generator) {
+		if n < 1 {
+			n = runtime.NumCPU()
+		}
+		g.persister.SetWorkers(n)
+	}
+}
+
+// DryRun prevents CustomFile and CustomTemplateFile Artifacts from being
+// written to the file system. Instead, a unified diff between each file's
+// current contents and the contents that would have been written is produced.
+// If report is empty, the diff is written to stderr; otherwise, it is emitted
+// as a generated file named report. If failOnChange is true, code generation
+// fails when any custom file would change, which is useful to verify generated
+// code is up to date. This mode can also be enabled via the "dry_run",
+// "dry_run_report" and "dry_run_fail" plugin parameters.
+func DryRun(report string, failOnChange bool) InitOption {
+	return func(g *Generator) { g.persister.SetDryRun(report, failOnChange) }
+}
+
+// CleanStaleFiles removes custom files generated by a previous run that are no
+// longer emitted by any Module. A manifest named ManifestFileName is kept in
+// each of the output roots, listing the CustomFile and CustomTemplateFile
+// Artifacts generated within it. Only files recorded in a manifest are ever
+// deleted; files outside of every root are neither tracked nor removed.
+//
+// Each file is recorded with the Source proto of its Artifact, or with all of
+// the files to generate if it has none, and is only considered stale when the
+// current request generates all of those protos. This allows several
+// invocations, such as one per package or directory, to share a root. Stale
+// files edited by hand are subject to the HandEdits policy of the Artifact
+// that generated them. Stale files are not removed, and the manifests are not
+// updated, if the run fails with an error. If no roots are provided, the
+// current working directory is used. In DryRun mode, stale files are reported
+// as deletions in the diff instead.
+func CleanStaleFiles(roots ...string) InitOption {
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	return func(g *Generator) { g.persister.SetCleanRoots(roots...) }
+}
+
+// ResolveInsertionPoints applies GeneratorInjection and
+// GeneratorTemplateInjection Artifacts directly to their target file if it was
+// generated earlier in the same run, rather than deferring to protoc. This is
+// useful when there is no protoc to perform the splice, such as in tests.
+// Code generation fails if the insertion point does not exist in the file.
+// Injections into files generated by other plugins are still handled by protoc.
+func ResolveInsertionPoints() InitOption {
+	return func(g *Generator) { g.persister.SetResolveInsertionPoints(true) }
+}
+
+// AnnotateGeneratedCode enables the annotation markers produced by Annotate,
+// AnnotationBegin and AnnotationEnd. The markers are extracted from the
+// contents of Generator Artifacts and recorded in the GeneratedCodeInfo of the
+// response. Without this option, all contents are persisted as-is, including
+// any characters that coincide with the markers. CustomFile and
+// CustomTemplateFile contents are never modified.
+func AnnotateGeneratedCode() InitOption {
+	return func(g *Generator) { g.persister.SetAnnotations(true) }
+}
+
 // SupportedFeatures allows defining protoc features to enable / disable.
 // See: https://github.com/protocolbuffers/protobuf/blob/v3.17.0/docs/implementing_proto3_presence.md#signaling-that-your-code-generator-supports-proto3-optional
 func SupportedFeatures(feat *uint64) InitOption {
@@ -55,3 +148,13 @@ func SupportedFeatures(feat *uint64) InitOption {
 		g.persister.SetSupportedFeatures(feat)
 	}
 }
+
+// SupportedEditions advertises to protoc that the plugin supports files using
+// Editions syntax, from min to max inclusive. The FEATURE_SUPPORTS_EDITIONS
+// flag is added to any features provided via SupportedFeatures.
+// See: https://protobuf.dev/editions/implementation/
+func SupportedEditions(min, max Edition) InitOption {
+	return func(g *Generator) {
+		g.persister.SetSupportedEditions(min, max)
+	}
+}
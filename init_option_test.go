@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"math/rand"
 	"os"
+	"runtime"
 	"strconv"
 	"testing"
 
@@ -38,6 +39,16 @@ func TestDebugEnv(t *testing.T) {
 	assert.True(t, g.debug)
 }
 
+func TestUseDebugger(t *testing.T) {
+	t.Parallel()
+
+	d := InitMockDebugger()
+	g := Init(UseDebugger(d))
+
+	assert.Equal(t, d, g.Debugger)
+	assert.Equal(t, d, g.persister.(*stdPersister).Debugger)
+}
+
 func TestFileSystem(t *testing.T) {
 	t.Parallel()
 
@@ -50,6 +61,93 @@ func TestFileSystem(t *testing.T) {
 	assert.Equal(t, fs, p.fs)
 }
 
+func TestParallelModules(t *testing.T) {
+	t.Parallel()
+
+	g := &Generator{}
+	assert.Zero(t, g.workers)
+
+	ParallelModules(3)(g)
+	assert.Equal(t, 3, g.workers)
+
+	ParallelModules(0)(g)
+	assert.Equal(t, runtime.NumCPU(), g.workers)
+}
+
+func TestParallelRendering(t *testing.T) {
+	t.Parallel()
+
+	p := dummyPersister(InitMockDebugger())
+	g := &Generator{persister: p}
+	assert.Zero(t, p.workers)
+
+	ParallelRendering(3)(g)
+	assert.Equal(t, 3, p.workers)
+
+	ParallelRendering(0)(g)
+	assert.Equal(t, runtime.NumCPU(), p.workers)
+}
+
+func TestDryRun(t *testing.T) {
+	t.Parallel()
+
+	p := dummyPersister(InitMockDebugger())
+	g := &Generator{persister: p}
+	assert.False(t, p.dryRun)
+
+	DryRun("report.diff", true)(g)
+	assert.True(t, p.dryRun)
+	assert.Equal(t, "report.diff", p.dryRunReport)
+	assert.True(t, p.dryRunFail)
+}
+
+func TestCleanStaleFiles(t *testing.T) {
+	t.Parallel()
+
+	p := dummyPersister(InitMockDebugger())
+	g := &Generator{persister: p}
+
+	CleanStaleFiles()(g)
+	assert.Equal(t, []string{"."}, p.cleanRoots)
+
+	CleanStaleFiles("foo", "bar")(g)
+	assert.Equal(t, []string{"foo", "bar"}, p.cleanRoots)
+}
+
+func TestResolveInsertionPoints(t *testing.T) {
+	t.Parallel()
+
+	p := dummyPersister(InitMockDebugger())
+	g := &Generator{persister: p}
+	assert.False(t, p.resolveInjections)
+
+	ResolveInsertionPoints()(g)
+	assert.True(t, p.resolveInjections)
+}
+
+func TestAnnotateGeneratedCode(t *testing.T) {
+	t.Parallel()
+
+	p := dummyPersister(InitMockDebugger())
+	g := &Generator{persister: p}
+	assert.False(t, p.annotate)
+
+	AnnotateGeneratedCode()(g)
+	assert.True(t, p.annotate)
+}
+
+func TestSupportedEditions(t *testing.T) {
+	t.Parallel()
+
+	p := dummyPersister(InitMockDebugger())
+	g := &Generator{persister: p}
+
+	SupportedEditions(EditionProto2, Edition2023)(g)
+
+	assert.Equal(t, EditionProto2, p.minEdition)
+	assert.Equal(t, Edition2023, p.maxEdition)
+}
+
 func TestProtocInput(t *testing.T) {
 	t.Parallel()
 
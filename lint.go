@@ -0,0 +1,331 @@
+package pgs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// lintParamPrefix prefixes the Parameters that configure LintRules, in the
+	// form "lint.{rule}={value}".
+	lintParamPrefix = "lint."
+
+	// LintIgnoreDirective suppresses LintRules for an Entity and all Entities
+	// it contains when it begins a line of the Entity's leading or trailing
+	// comments. It is followed by the names of the suppressed rules, or by
+	// nothing to suppress all rules:
+	//
+	//	// pgs:lint-ignore field_names enum_zero_value
+	//	message legacy_message { ... }
+	//
+	// For Files, the directive is read from the comments of the syntax and
+	// package statements.
+	LintIgnoreDirective = "pgs:lint-ignore"
+)
+
+// A LintRule checks the Entities of the target Files for a single convention,
+// such as a naming or style rule. Rules are registered per entity kind by
+// setting the corresponding check functions; Entities of kinds without a check
+// are skipped. Each check reports its findings via the LintContext.
+type LintRule struct {
+	// Name identifies the rule in Parameters and LintIgnoreDirectives. It
+	// should be lower_snake_case.
+	Name string
+
+	// Severity of the rule's findings, unless overridden by Parameters.
+	Severity Severity
+
+	// Disabled rules only run if enabled via Parameters.
+	Disabled bool
+
+	File      func(ctx LintContext, f File)
+	Message   func(ctx LintContext, m Message)
+	Enum      func(ctx LintContext, e Enum)
+	EnumValue func(ctx LintContext, ev EnumValue)
+	Field     func(ctx LintContext, f Field)
+	Extension func(ctx LintContext, ext Extension)
+	OneOf     func(ctx LintContext, o OneOf)
+	Service   func(ctx LintContext, s Service)
+	Method    func(ctx LintContext, m Method)
+}
+
+// A LintContext is provided to the checks of a LintRule.
+type LintContext interface {
+	// Parameters returns the plugin's Parameters, which rules may use for their
+	// own configuration.
+	Parameters() Parameters
+
+	// Report records a finding of the current rule for Entity e. Findings for
+	// Entities suppressed with a LintIgnoreDirective are dropped.
+	Report(e Entity, format string, args ...interface{})
+}
+
+// Lint checks files against rules, configured by params, returning the
+// findings as Diagnostics in the order the Files (sorted by name) and their
+// Entities are walked. If no rules are provided, DefaultLintRules are used.
+//
+// Each rule is configured with a parameter named "lint.{rule}", with one of
+// the following values:
+//
+//	off, false  disables the rule
+//	on, true    enables the rule with its default severity
+//	error       enables the rule, reporting errors
+//	warning     enables the rule, reporting warnings
+//
+// An error is returned if a "lint." parameter names an unknown rule or has an
+// invalid value.
+func Lint(files map[string]File, params Parameters, rules ...LintRule) ([]Diagnostic, error) {
+	if len(rules) == 0 {
+		rules = DefaultLintRules()
+	}
+
+	enabled, err := configureLintRules(params, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	v := &lintVisitor{params: params, rules: enabled}
+	for _, name := range names {
+		if err = Walk(v, files[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	return v.diags, nil
+}
+
+func configureLintRules(params Parameters, rules []LintRule) ([]LintRule, error) {
+	known := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		known[r.Name] = true
+	}
+
+	for k := range params {
+		if name := strings.TrimPrefix(k, lintParamPrefix); name != k && !known[name] {
+			return nil, fmt.Errorf("unknown lint rule %q", name)
+		}
+	}
+
+	enabled := make([]LintRule, 0, len(rules))
+	for _, r := range rules {
+		switch v := params.Str(lintParamPrefix + r.Name); v {
+		case "":
+		case "off", "false":
+			r.Disabled = true
+		case "on", "true":
+			r.Disabled = false
+		case "error":
+			r.Disabled, r.Severity = false, SeverityError
+		case "warning":
+			r.Disabled, r.Severity = false, SeverityWarning
+		default:
+			return nil, fmt.Errorf("invalid value %q for lint rule %q", v, r.Name)
+		}
+
+		if !r.Disabled {
+			enabled = append(enabled, r)
+		}
+	}
+
+	return enabled, nil
+}
+
+type lintVisitor struct {
+	params Parameters
+	rules  []LintRule
+	diags  []Diagnostic
+}
+
+// lintCtx is the LintContext of a single LintRule.
+type lintCtx struct {
+	v    *lintVisitor
+	rule *LintRule
+}
+
+func (c lintCtx) Parameters() Parameters { return c.v.params }
+
+func (c lintCtx) Report(e Entity, format string, args ...interface{}) {
+	if lintSuppressed(e, c.rule.Name) {
+		return
+	}
+
+	c.v.diags = append(c.v.diags, Diagnostic{
+		Entity:   e,
+		Severity: c.rule.Severity,
+		Message:  fmt.Sprintf("%s (%s)", fmt.Sprintf(format, args...), c.rule.Name),
+	})
+}
+
+// each calls check with the context of each rule.
+func (v *lintVisitor) each(check func(ctx LintContext, r *LintRule)) (Visitor, error) {
+	for i := range v.rules {
+		check(lintCtx{v: v, rule: &v.rules[i]}, &v.rules[i])
+	}
+	return v, nil
+}
+
+func (v *lintVisitor) VisitPackage(Package) (Visitor, error) { return v, nil }
+
+func (v *lintVisitor) VisitFile(f File) (Visitor, error) {
+	return v.each(func(ctx LintContext, r *LintRule) {
+		if r.File != nil {
+			r.File(ctx, f)
+		}
+	})
+}
+
+func (v *lintVisitor) VisitMessage(m Message) (Visitor, error) {
+	return v.each(func(ctx LintContext, r *LintRule) {
+		if r.Message != nil {
+			r.Message(ctx, m)
+		}
+	})
+}
+
+func (v *lintVisitor) VisitEnum(e Enum) (Visitor, error) {
+	return v.each(func(ctx LintContext, r *LintRule) {
+		if r.Enum != nil {
+			r.Enum(ctx, e)
+		}
+	})
+}
+
+func (v *lintVisitor) VisitEnumValue(ev EnumValue) (Visitor, error) {
+	return v.each(func(ctx LintContext, r *LintRule) {
+		if r.EnumValue != nil {
+			r.EnumValue(ctx, ev)
+		}
+	})
+}
+
+func (v *lintVisitor) VisitField(f Field) (Visitor, error) {
+	return v.each(func(ctx LintContext, r *LintRule) {
+		if r.Field != nil {
+			r.Field(ctx, f)
+		}
+	})
+}
+
+func (v *lintVisitor) VisitExtension(ext Extension) (Visitor, error) {
+	return v.each(func(ctx LintContext, r *LintRule) {
+		if r.Extension != nil {
+			r.Extension(ctx, ext)
+		}
+	})
+}
+
+func (v *lintVisitor) VisitOneOf(o OneOf) (Visitor, error) {
+	return v.each(func(ctx LintContext, r *LintRule) {
+		if r.OneOf != nil {
+			r.OneOf(ctx, o)
+		}
+	})
+}
+
+func (v *lintVisitor) VisitService(s Service) (Visitor, error) {
+	return v.each(func(ctx LintContext, r *LintRule) {
+		if r.Service != nil {
+			r.Service(ctx, s)
+		}
+	})
+}
+
+func (v *lintVisitor) VisitMethod(m Method) (Visitor, error) {
+	return v.each(func(ctx LintContext, r *LintRule) {
+		if r.Method != nil {
+			r.Method(ctx, m)
+		}
+	})
+}
+
+// lintSuppressed returns true if rule is suppressed by a LintIgnoreDirective
+// of e or any Entity containing it.
+func lintSuppressed(e Entity, rule string) bool {
+	for e != nil {
+		infos := []SourceCodeInfo{e.SourceCodeInfo()}
+		if f, ok := e.(File); ok {
+			infos = append(infos, f.PackageSourceCodeInfo())
+		}
+
+		for _, info := range infos {
+			if info != nil && (lintIgnores(info.LeadingComments(), rule) || lintIgnores(info.TrailingComments(), rule)) {
+				return true
+			}
+		}
+
+		e = lintParent(e)
+	}
+	return false
+}
+
+// lintIgnores returns true if comments contain a LintIgnoreDirective for rule.
+func lintIgnores(comments, rule string) bool {
+	for _, ln := range strings.Split(comments, "\n") {
+		fields := strings.Fields(ln)
+		if len(fields) == 0 || fields[0] != LintIgnoreDirective {
+			continue
+		}
+
+		if len(fields) == 1 || containsString(fields[1:], rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// lintParent returns the Entity containing e, or nil for a File.
+func lintParent(e Entity) Entity {
+	switch e := e.(type) {
+	case Extension:
+		return e.DefinedIn()
+	case Field:
+		return e.Message()
+	case OneOf:
+		return e.Message()
+	case EnumValue:
+		return e.Enum()
+	case Enum:
+		return e.Parent()
+	case Message:
+		return e.Parent()
+	case Method:
+		return e.Service()
+	case Service:
+		return e.File()
+	default:
+		return nil
+	}
+}
+
+// LintModule returns a Module that checks the target Files against rules (or
+// DefaultLintRules if none are provided), as configured by the plugin's
+// Parameters (see Lint). Findings are reported as Diagnostics, so error
+// findings fail the code generation once all Modules have executed.
+func LintModule(rules ...LintRule) Module {
+	return &lintModule{ModuleBase: &ModuleBase{}, rules: rules}
+}
+
+type lintModule struct {
+	*ModuleBase
+	rules []LintRule
+}
+
+func (m *lintModule) Name() string { return "lint" }
+
+func (m *lintModule) Execute(targets map[string]File, packages map[string]Package) []Artifact {
+	diags, err := Lint(targets, m.Parameters(), m.rules...)
+	m.CheckErr(err, "invalid lint configuration")
+
+	for _, d := range diags {
+		m.AddArtifact(d)
+	}
+
+	return m.Artifacts()
+}
@@ -0,0 +1,225 @@
+package pgs
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultLintRules returns the starter set of LintRules:
+//
+//	message_names     Messages are UpperCamelCase
+//	field_names       Fields are lower_snake_case
+//	oneof_names       OneOfs are lower_snake_case
+//	extension_names   Extensions are lower_snake_case
+//	enum_names        Enums are UpperCamelCase
+//	enum_value_names  EnumValues are UPPER_SNAKE_CASE
+//	service_names     Services are UpperCamelCase
+//	method_names      Methods are UpperCamelCase
+//	enum_zero_value   Enums have a zero value named {ENUM_NAME}_UNSPECIFIED
+//	field_number_gaps Messages do not skip field numbers without reserving them
+//	service_comments  Services have a leading comment
+//	method_comments   Methods have a leading comment
+//
+// The naming and zero value rules report errors, while the others report
+// warnings.
+func DefaultLintRules() []LintRule {
+	return []LintRule{
+		{
+			Name: "message_names",
+			Message: func(ctx LintContext, m Message) {
+				checkUpperCamelCase(ctx, m, "message")
+			},
+		},
+		{
+			Name: "field_names",
+			Field: func(ctx LintContext, f Field) {
+				checkLowerSnakeCase(ctx, f, "field")
+			},
+		},
+		{
+			Name: "oneof_names",
+			OneOf: func(ctx LintContext, o OneOf) {
+				if !o.IsSynthetic() {
+					checkLowerSnakeCase(ctx, o, "oneof")
+				}
+			},
+		},
+		{
+			Name: "extension_names",
+			Extension: func(ctx LintContext, ext Extension) {
+				checkLowerSnakeCase(ctx, ext, "extension")
+			},
+		},
+		{
+			Name: "enum_names",
+			Enum: func(ctx LintContext, e Enum) {
+				checkUpperCamelCase(ctx, e, "enum")
+			},
+		},
+		{
+			Name: "enum_value_names",
+			EnumValue: func(ctx LintContext, ev EnumValue) {
+				if n := ev.Name().String(); !isUpperSnakeCase(n) {
+					ctx.Report(ev, "enum value name %q should be UPPER_SNAKE_CASE, such as %q", n, ev.Name().ScreamingSnakeCase())
+				}
+			},
+		},
+		{
+			Name: "service_names",
+			Service: func(ctx LintContext, s Service) {
+				checkUpperCamelCase(ctx, s, "service")
+			},
+		},
+		{
+			Name: "method_names",
+			Method: func(ctx LintContext, m Method) {
+				checkUpperCamelCase(ctx, m, "method")
+			},
+		},
+		{
+			Name: "enum_zero_value",
+			Enum: checkEnumZeroValue,
+		},
+		{
+			Name:     "field_number_gaps",
+			Severity: SeverityWarning,
+			Message:  checkFieldNumberGaps,
+		},
+		{
+			Name:     "service_comments",
+			Severity: SeverityWarning,
+			Service: func(ctx LintContext, s Service) {
+				checkComment(ctx, s, "service")
+			},
+		},
+		{
+			Name:     "method_comments",
+			Severity: SeverityWarning,
+			Method: func(ctx LintContext, m Method) {
+				checkComment(ctx, m, "method")
+			},
+		},
+	}
+}
+
+func checkUpperCamelCase(ctx LintContext, e Entity, kind string) {
+	if n := e.Name().String(); !isUpperCamelCase(n) {
+		ctx.Report(e, "%s name %q should be UpperCamelCase, such as %q", kind, n, e.Name().UpperCamelCase())
+	}
+}
+
+func checkLowerSnakeCase(ctx LintContext, e Entity, kind string) {
+	if n := e.Name().String(); !isLowerSnakeCase(n) {
+		ctx.Report(e, "%s name %q should be lower_snake_case, such as %q", kind, n, e.Name().LowerSnakeCase())
+	}
+}
+
+func checkEnumZeroValue(ctx LintContext, e Enum) {
+	want := e.Name().ScreamingSnakeCase().String() + "_UNSPECIFIED"
+
+	for _, ev := range e.Values() {
+		if ev.Value() != 0 {
+			continue
+		}
+
+		if n := ev.Name().String(); n != want {
+			ctx.Report(ev, "zero value %q of enum %s should be named %q", n, e.Name(), want)
+		}
+		return
+	}
+
+	ctx.Report(e, "enum %s should have a zero value named %q", e.Name(), want)
+}
+
+func checkFieldNumberGaps(ctx LintContext, m Message) {
+	nums := make([]int32, 0, len(m.Fields()))
+	for _, f := range m.Fields() {
+		nums = append(nums, f.Descriptor().GetNumber())
+	}
+	sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+
+	covered := m.ReservedRanges()
+	for _, r := range m.ExtensionRanges() {
+		covered = append(covered, ReservedRange{Start: r.Start, End: r.End})
+	}
+	sort.Slice(covered, func(i, j int) bool { return covered[i].Start < covered[j].Start })
+
+	var gaps []string
+	prev := int32(0)
+	for _, n := range nums {
+		for _, r := range uncoveredRanges(prev+1, n-1, covered) {
+			gaps = append(gaps, formatRange(r.Start, r.End, maxFieldNumber))
+		}
+		prev = n
+	}
+
+	if len(gaps) > 0 {
+		ctx.Report(m, "field numbers %s of message %s are unused but not reserved", strings.Join(gaps, ", "), m.Name())
+	}
+}
+
+// uncoveredRanges returns the ranges of numbers from start to end (inclusive)
+// that are not within any of the covered ranges, which are sorted by Start.
+func uncoveredRanges(start, end int32, covered []ReservedRange) []ReservedRange {
+	var out []ReservedRange
+	for _, r := range covered {
+		if start > end || r.Start > end {
+			break
+		}
+		if r.End < start {
+			continue
+		}
+		if r.Start > start {
+			out = append(out, ReservedRange{Start: start, End: r.Start - 1})
+		}
+		start = r.End + 1
+	}
+
+	if start <= end {
+		out = append(out, ReservedRange{Start: start, End: end})
+	}
+	return out
+}
+
+func checkComment(ctx LintContext, e Entity, kind string) {
+	info := e.SourceCodeInfo()
+	if info == nil {
+		// without source info, comments cannot be checked
+		return
+	}
+
+	if strings.TrimSpace(info.LeadingComments()) == "" {
+		ctx.Report(e, "%s %s should have a leading comment", kind, e.Name())
+	}
+}
+
+func isUpperCamelCase(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= 'A' && c <= 'Z':
+		case i > 0 && (c >= 'a' && c <= 'z' || c >= '0' && c <= '9'):
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isLowerSnakeCase(s string) bool { return isSnakeCase(s, 'a', 'z') }
+
+func isUpperSnakeCase(s string) bool { return isSnakeCase(s, 'A', 'Z') }
+
+// isSnakeCase returns true if s consists of underscore-separated words of
+// letters from lo to hi and digits, beginning with a letter.
+func isSnakeCase(s string, lo, hi byte) bool {
+	if s == "" || s[0] < lo || s[0] > hi || s[len(s)-1] == '_' || strings.Contains(s, "__") {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !(c >= lo && c <= hi || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
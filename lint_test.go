@@ -0,0 +1,183 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lintStrings(diags []Diagnostic) []string {
+	out := make([]string, len(diags))
+	for i, d := range diags {
+		out[i] = d.String()
+	}
+	return out
+}
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	ast := buildGraph(t, "lint")
+
+	diags, err := Lint(ast.Targets(), Parameters{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`lint/lint.proto:26:5: zero value "UNKNOWN" of enum Status should be named "STATUS_UNSPECIFIED" (enum_zero_value)`,
+		`lint/lint.proto:27:5: enum value name "Active" should be UPPER_SNAKE_CASE, such as "ACTIVE" (enum_value_names)`,
+		`lint/lint.proto:6:1: warning: field numbers 4, 7 of message User are unused but not reserved (field_number_gaps)`,
+		`lint/lint.proto:8:5: field name "displayName" should be lower_snake_case, such as "display_name" (field_names)`,
+		`lint/lint.proto:9:5: oneof name "Contact" should be lower_snake_case, such as "contact" (oneof_names)`,
+		`lint/lint.proto:18:1: message name "snake_message" should be UpperCamelCase, such as "SnakeMessage" (message_names)`,
+		`lint/lint.proto:18:1: warning: field numbers 5 to 9 of message snake_message are unused but not reserved (field_number_gaps)`,
+		`lint/lint.proto:54:5: warning: method list_users should have a leading comment (method_comments)`,
+		`lint/lint.proto:57:1: service name "admin" should be UpperCamelCase, such as "Admin" (service_names)`,
+		`lint/lint.proto:57:1: warning: service admin should have a leading comment (service_comments)`,
+		`lint/lint.proto:47:5: extension name "fieldDoc" should be lower_snake_case, such as "field_doc" (extension_names)`,
+	}, lintStrings(diags))
+}
+
+func TestLint_Parameters(t *testing.T) {
+	t.Parallel()
+
+	ast := buildGraph(t, "lint")
+
+	t.Run("disable and override", func(t *testing.T) {
+		t.Parallel()
+
+		diags, err := Lint(ast.Targets(), Parameters{
+			"lint.enum_zero_value":   "off",
+			"lint.enum_value_names":  "false",
+			"lint.field_number_gaps": "error",
+			"lint.field_names":       "warning",
+			"lint.oneof_names":       "on",
+		})
+		require.NoError(t, err)
+		require.Len(t, diags, 9)
+
+		assert.Equal(t, "field_number_gaps", lintRuleOf(diags[0]))
+		assert.Equal(t, SeverityError, diags[0].Severity)
+		assert.Equal(t, "field_names", lintRuleOf(diags[1]))
+		assert.Equal(t, SeverityWarning, diags[1].Severity)
+		assert.Equal(t, "oneof_names", lintRuleOf(diags[2]))
+		assert.Equal(t, SeverityError, diags[2].Severity)
+	})
+
+	t.Run("unknown rule", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Lint(ast.Targets(), Parameters{"lint.fields": "off"})
+		assert.EqualError(t, err, `unknown lint rule "fields"`)
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Lint(ast.Targets(), Parameters{"lint.field_names": "fatal"})
+		assert.EqualError(t, err, `invalid value "fatal" for lint rule "field_names"`)
+	})
+}
+
+func lintRuleOf(d Diagnostic) string {
+	i := len(d.Message) - 1
+	for d.Message[i] != '(' {
+		i--
+	}
+	return d.Message[i+1 : len(d.Message)-1]
+}
+
+func TestLint_CustomRule(t *testing.T) {
+	t.Parallel()
+
+	ast := buildGraph(t, "lint")
+
+	rule := LintRule{
+		Name:     "max_fields",
+		Severity: SeverityWarning,
+		Disabled: true,
+		Message: func(ctx LintContext, m Message) {
+			max, err := ctx.Parameters().IntDefault("max_fields", 3)
+			if err == nil && len(m.Fields()) > max {
+				for _, f := range m.Fields()[max:] {
+					ctx.Report(f, "too many fields")
+				}
+			}
+		},
+	}
+
+	diags, err := Lint(ast.Targets(), Parameters{}, rule)
+	require.NoError(t, err)
+	assert.Empty(t, diags)
+
+	diags, err = Lint(ast.Targets(), Parameters{"lint.max_fields": "on", "max_fields": "1"}, rule)
+	require.NoError(t, err)
+
+	// the fields of legacy_message are suppressed by its directive
+	assert.Equal(t, []string{
+		"lint/lint.proto:8:5: warning: too many fields (max_fields)",
+		"lint/lint.proto:10:9: warning: too many fields (max_fields)",
+		"lint/lint.proto:11:9: warning: too many fields (max_fields)",
+		"lint/lint.proto:13:5: warning: too many fields (max_fields)",
+		"lint/lint.proto:22:5: warning: too many fields (max_fields)",
+	}, lintStrings(diags))
+}
+
+func TestLintIgnores(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, lintIgnores(" pgs:lint-ignore\n", "field_names"))
+	assert.True(t, lintIgnores(" some docs\n pgs:lint-ignore enum_names field_names\n", "field_names"))
+	assert.False(t, lintIgnores(" pgs:lint-ignore enum_names\n", "field_names"))
+	assert.False(t, lintIgnores(" see pgs:lint-ignore\n", "field_names"))
+	assert.False(t, lintIgnores("", "field_names"))
+}
+
+func TestLintModule(t *testing.T) {
+	t.Parallel()
+
+	ast := buildGraph(t, "lint")
+
+	m := LintModule()
+	assert.Equal(t, "lint", m.Name())
+
+	d := InitMockDebugger()
+	m.InitContext(Context(d, Parameters{"lint.field_number_gaps": "off"}, "."))
+
+	arts := m.Execute(ast.Targets(), ast.Packages())
+	assert.Len(t, arts, 9)
+	for _, a := range arts {
+		assert.IsType(t, Diagnostic{}, a)
+	}
+
+	m.InitContext(Context(d, Parameters{"lint.nope": "off"}, "."))
+	m.Execute(ast.Targets(), ast.Packages())
+	assert.True(t, d.Exited())
+}
+
+func TestNameCaseChecks(t *testing.T) {
+	t.Parallel()
+
+	for s, ok := range map[string]bool{"Foo": true, "FooBar2": true, "HTTPRequest": true, "fooBar": false, "Foo_Bar": false, "": false} {
+		assert.Equal(t, ok, isUpperCamelCase(s), s)
+	}
+
+	for s, ok := range map[string]bool{"foo": true, "foo_bar2": true, "foo__bar": false, "foo_": false, "_foo": false, "fooBar": false, "2foo": false} {
+		assert.Equal(t, ok, isLowerSnakeCase(s), s)
+	}
+
+	for s, ok := range map[string]bool{"FOO": true, "FOO_BAR_2": true, "Foo": false, "FOO__BAR": false} {
+		assert.Equal(t, ok, isUpperSnakeCase(s), s)
+	}
+}
+
+func TestUncoveredRanges(t *testing.T) {
+	t.Parallel()
+
+	covered := []ReservedRange{{Start: 3, End: 4}, {Start: 6, End: 6}, {Start: 10, End: 20}}
+
+	assert.Equal(t, []ReservedRange{{Start: 1, End: 2}, {Start: 5, End: 5}, {Start: 7, End: 9}}, uncoveredRanges(1, 12, covered))
+	assert.Empty(t, uncoveredRanges(3, 4, covered))
+	assert.Empty(t, uncoveredRanges(5, 4, covered))
+	assert.Equal(t, []ReservedRange{{Start: 21, End: 25}}, uncoveredRanges(15, 25, covered))
+}
@@ -0,0 +1,287 @@
+package pgs
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// ManifestFileName is the name of the file, placed in each output root, that
+// records the custom files generated into that root. See CleanStaleFiles.
+//
+// Each line of the manifest is the slash-separated path of a file relative to
+// the root, followed by a tab and its HandEditPolicy if it is not
+// HandEditsOverwrite, then by a tab before each of the proto files it was
+// generated from.
+const ManifestFileName = ".pgs_manifest"
+
+const manifestHeader = "# Code generated by protoc-gen-star. DO NOT EDIT.\n"
+
+// manifest tracks the custom files generated within an output root.
+type manifest struct {
+	root string
+
+	// the files generated by the previous and current runs
+	prev map[string]manifestEntry
+	next map[string]manifestEntry
+}
+
+// manifestEntry describes a file tracked by a manifest.
+type manifestEntry struct {
+	// edits is the HandEditPolicy the file was generated with.
+	edits HandEditPolicy
+
+	// sources are the names of the proto files the file was generated from.
+	sources []string
+}
+
+// manifestPolicies are the names of the HandEditPolicies in a manifest.
+var manifestPolicies = map[HandEditPolicy]string{
+	HandEditsOverwrite: "overwrite",
+	HandEditsWarn:      "warn",
+	HandEditsSkip:      "skip",
+}
+
+// loadManifests reads the manifest of each output root configured on the
+// persister. A missing manifest is treated as empty.
+func (p *stdPersister) loadManifests() []*manifest {
+	ms := make([]*manifest, 0, len(p.cleanRoots))
+
+	for _, root := range p.cleanRoots {
+		m := &manifest{
+			root: filepath.Clean(root),
+			prev: map[string]manifestEntry{},
+			next: map[string]manifestEntry{},
+		}
+		ms = append(ms, m)
+
+		name := filepath.Join(m.root, ManifestFileName)
+		exists, err := afero.Exists(p.fs, name)
+		p.CheckErr(err, "unable to check manifest exists:", name)
+		if !exists {
+			continue
+		}
+
+		b, err := afero.ReadFile(p.fs, name)
+		p.CheckErr(err, "unable to read manifest:", name)
+
+		s := bufio.NewScanner(bytes.NewReader(b))
+		for s.Scan() {
+			ln := strings.TrimSpace(s.Text())
+			if ln == "" || strings.HasPrefix(ln, "#") {
+				continue
+			}
+
+			parts := strings.Split(ln, "\t")
+			entry := manifestEntry{edits: HandEditsOverwrite}
+			if len(parts) > 1 {
+				for pol, name := range manifestPolicies {
+					if name == parts[1] {
+						entry.edits = pol
+					}
+				}
+			}
+			if len(parts) > 2 {
+				entry.sources = parts[2:]
+			}
+			m.prev[filepath.FromSlash(parts[0])] = entry
+		}
+		p.CheckErr(s.Err(), "unable to parse manifest:", name)
+	}
+
+	return ms
+}
+
+// trackFile records that the custom file name was emitted with the
+// HandEditPolicy edits, from the proto file source. If source is nil, the file
+// is attributed to all of the files to generate. Files that were not written
+// because they already existed are only tracked if a previous run generated
+// them. Files outside of every output root are ignored.
+func (p *stdPersister) trackFile(ms []*manifest, name string, written bool, edits HandEditPolicy, source File) {
+	m, rel := manifestFor(ms, name)
+	if m == nil {
+		return
+	}
+
+	if _, ok := m.prev[rel]; !ok && !written {
+		return
+	}
+
+	entry := manifestEntry{edits: edits, sources: p.targets}
+	if source != nil {
+		entry.sources = []string{source.Name().String()}
+	}
+	m.next[rel] = entry
+}
+
+// cleanStale deletes the files listed in each previous manifest that were not
+// emitted by the current run, then saves the updated manifests. Only files the
+// persister generated itself are ever deleted, subject to the HandEditPolicy
+// they were generated with; stale files that are kept because they were edited
+// by hand remain in the manifest. Files generated from protos outside of the
+// current files to generate belong to another invocation, and are neither
+// deleted nor dropped from the manifest. If dry is not nil, the deletions are
+// recorded instead and the file system is left untouched.
+func (p *stdPersister) cleanStale(ms []*manifest, dry *dryRunResult) {
+	for _, m := range ms {
+		stale := make([]string, 0, len(m.prev))
+		for rel, entry := range m.prev {
+			if _, ok := m.next[rel]; ok || !safeManifestPath(rel) {
+				continue
+			}
+
+			if p.targeted(entry) {
+				stale = append(stale, rel)
+			} else {
+				m.next[rel] = entry
+			}
+		}
+		sort.Strings(stale)
+
+		for _, rel := range stale {
+			name := filepath.Join(m.root, rel)
+
+			exists, err := afero.Exists(p.fs, name)
+			p.CheckErr(err, "unable to check file exists:", name)
+			if !exists {
+				continue
+			}
+
+			if entry := m.prev[rel]; !p.checkHandEdits(name, entry.edits, "removing") {
+				m.next[rel] = entry
+				continue
+			}
+
+			if dry != nil {
+				p.diffRemove(dry, name)
+				continue
+			}
+
+			p.Debug("removing stale file", name)
+			p.CheckErr(p.fs.Remove(name), "unable to remove stale file:", name)
+		}
+
+		if dry == nil {
+			p.saveManifest(m)
+		}
+	}
+}
+
+// saveManifest writes the manifest of the files generated by the current run.
+// The manifest is only written if its contents changed, and is removed if no
+// files are tracked.
+func (p *stdPersister) saveManifest(m *manifest) {
+	name := filepath.Join(m.root, ManifestFileName)
+
+	exists, err := afero.Exists(p.fs, name)
+	p.CheckErr(err, "unable to check manifest exists:", name)
+
+	if len(m.next) == 0 {
+		if exists {
+			p.Debug("removing empty manifest", name)
+			p.CheckErr(p.fs.Remove(name), "unable to remove manifest:", name)
+		}
+		return
+	}
+
+	files := make([]string, 0, len(m.next))
+	for rel := range m.next {
+		files = append(files, rel)
+	}
+	sort.Strings(files)
+
+	buf := &bytes.Buffer{}
+	buf.WriteString(manifestHeader)
+	for _, rel := range files {
+		entry := m.next[rel]
+		buf.WriteString(filepath.ToSlash(rel))
+		if entry.edits != HandEditsOverwrite || len(entry.sources) > 0 {
+			buf.WriteByte('\t')
+			buf.WriteString(manifestPolicies[entry.edits])
+		}
+		for _, src := range entry.sources {
+			buf.WriteByte('\t')
+			buf.WriteString(src)
+		}
+		buf.WriteByte('\n')
+	}
+
+	if exists {
+		existing, err := afero.ReadFile(p.fs, name)
+		p.CheckErr(err, "unable to read manifest:", name)
+		if bytes.Equal(existing, buf.Bytes()) {
+			return
+		}
+	}
+
+	p.writeFile(name, buf.Bytes(), true, 0644)
+}
+
+// targeted returns true if every proto file the manifest entry was generated
+// from is among the current files to generate, in which case the file is
+// stale if it was not emitted. All entries are targeted if the files to
+// generate are unknown, while entries without sources are only targeted then.
+func (p *stdPersister) targeted(entry manifestEntry) bool {
+	if len(p.targets) == 0 {
+		return true
+	}
+
+	if len(entry.sources) == 0 {
+		return false
+	}
+
+	for _, src := range entry.sources {
+		found := false
+		for _, t := range p.targets {
+			if t == src {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// manifestFor returns the manifest of the innermost output root containing
+// name, as well as the path of name relative to that root.
+func manifestFor(ms []*manifest, name string) (out *manifest, rel string) {
+	name = filepath.Clean(name)
+
+	for _, m := range ms {
+		r, err := filepath.Rel(m.root, name)
+		if err != nil || !safeManifestPath(r) {
+			continue
+		}
+
+		if out == nil || len(m.root) > len(out.root) {
+			out, rel = m, r
+		}
+	}
+
+	return
+}
+
+// safeManifestPath returns true if the relative path rel refers to a file
+// within its output root, other than the manifest itself.
+func safeManifestPath(rel string) bool {
+	rel = filepath.Clean(rel)
+
+	switch {
+	case filepath.IsAbs(rel),
+		rel == ".",
+		rel == "..",
+		strings.HasPrefix(rel, ".."+string(filepath.Separator)),
+		rel == ManifestFileName:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,318 @@
+package pgs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestPersister_Persist_CleanStale(t *testing.T) {
+	t.Parallel()
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+	p.SetCleanRoots("/out")
+
+	assert.NoError(t, afero.WriteFile(p.fs, "/out/handwritten.txt", []byte("mine"), 0644))
+	assert.NoError(t, afero.WriteFile(p.fs, "/out/existing.txt", []byte("mine"), 0644))
+
+	p.Persist(
+		CustomFile{Name: "/out/a.txt", Contents: "a", Perms: 0644},
+		CustomFile{Name: "/out/sub/b.txt", Contents: "b", Perms: 0644},
+		CustomFile{Name: "/out/existing.txt", Contents: "nope", Perms: 0644},
+		CustomFile{Name: "/elsewhere/c.txt", Contents: "c", Perms: 0644},
+	)
+	assert.NoError(t, d.Err())
+
+	b, err := afero.ReadFile(p.fs, filepath.Join("/out", ManifestFileName))
+	assert.NoError(t, err)
+	assert.Equal(t, manifestHeader+"a.txt\nsub/b.txt\n", string(b))
+
+	p.Persist(
+		CustomFile{Name: "/out/a.txt", Contents: "a", Perms: 0644},
+		CustomFile{Name: "/elsewhere/d.txt", Contents: "d", Perms: 0644},
+	)
+	assert.NoError(t, d.Err())
+
+	for name, expected := range map[string]bool{
+		"/out/a.txt":           true,
+		"/out/sub/b.txt":       false,
+		"/out/existing.txt":    true,
+		"/out/handwritten.txt": true,
+		"/elsewhere/c.txt":     true,
+		"/elsewhere/d.txt":     true,
+	} {
+		exists, err := afero.Exists(p.fs, name)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, exists, name)
+	}
+
+	b, err = afero.ReadFile(p.fs, filepath.Join("/out", ManifestFileName))
+	assert.NoError(t, err)
+	assert.Equal(t, manifestHeader+"a.txt\n", string(b))
+}
+
+func TestPersister_Persist_CleanStale_Unsafe(t *testing.T) {
+	t.Parallel()
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+	p.SetCleanRoots("/out")
+
+	assert.NoError(t, afero.WriteFile(p.fs, "/secret.txt", []byte("keep"), 0644))
+	assert.NoError(t, afero.WriteFile(p.fs, "/out/"+ManifestFileName,
+		[]byte("# comment\n../secret.txt\n/secret.txt\n"+ManifestFileName+"\n"), 0644))
+
+	p.Persist()
+	assert.NoError(t, d.Err())
+
+	exists, err := afero.Exists(p.fs, "/secret.txt")
+	assert.NoError(t, err)
+	assert.True(t, exists, "files outside the root must not be deleted")
+
+	exists, err = afero.Exists(p.fs, "/out/"+ManifestFileName)
+	assert.NoError(t, err)
+	assert.False(t, exists, "a manifest without files is removed")
+}
+
+func TestPersister_Persist_CleanStale_Manifest(t *testing.T) {
+	t.Parallel()
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+	p.SetCleanRoots("/out", "/empty")
+
+	p.Persist(CustomFile{Name: "/out/a.txt", Contents: "a", Perms: 0644, HandEdits: HandEditsSkip})
+	assert.NoError(t, d.Err())
+
+	name := filepath.Join("/out", ManifestFileName)
+	b, err := afero.ReadFile(p.fs, name)
+	assert.NoError(t, err)
+	assert.Equal(t, manifestHeader+"a.txt\tskip\n", string(b))
+
+	exists, err := afero.Exists(p.fs, filepath.Join("/empty", ManifestFileName))
+	assert.NoError(t, err)
+	assert.False(t, exists, "roots without files have no manifest")
+
+	old := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, p.fs.Chtimes(name, old, old))
+
+	p.Persist(CustomFile{Name: "/out/a.txt", Contents: "a", Perms: 0644, HandEdits: HandEditsSkip})
+	assert.NoError(t, d.Err())
+
+	info, err := p.fs.Stat(name)
+	assert.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(old), "an unchanged manifest is not rewritten")
+}
+
+func TestPersister_Persist_CleanStale_HandEdits(t *testing.T) {
+	t.Parallel()
+
+	stamped, err := StampHeaders("protoc-gen-foo", "").
+		ProcessArtifact(CustomFile{Name: "foo.go"}, []byte("package foo\n"))
+	assert.NoError(t, err)
+	edited := append(stamped, "// edited\n"...)
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+	p.SetCleanRoots("/out")
+
+	for _, name := range []string{"skip.go", "warn.go", "overwrite.go", "clean.go"} {
+		content := edited
+		if name == "clean.go" {
+			content = stamped
+		}
+		assert.NoError(t, afero.WriteFile(p.fs, "/out/"+name, content, 0644))
+	}
+	assert.NoError(t, afero.WriteFile(p.fs, "/out/"+ManifestFileName,
+		[]byte(manifestHeader+"clean.go\tskip\noverwrite.go\nskip.go\tskip\nwarn.go\twarn\n"), 0644))
+
+	p.Persist()
+	assert.NoError(t, d.Err())
+
+	for name, expected := range map[string]bool{
+		"/out/skip.go":      true,
+		"/out/warn.go":      false,
+		"/out/overwrite.go": false,
+		"/out/clean.go":     false,
+	} {
+		exists, err := afero.Exists(p.fs, name)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, exists, name)
+	}
+
+	logs, err := ioutil.ReadAll(d.Output())
+	assert.NoError(t, err)
+	assert.Contains(t, string(logs), "warning: file /out/skip.go was edited by hand, skipping")
+	assert.Contains(t, string(logs), "warning: file /out/warn.go was edited by hand, removing")
+
+	b, err := afero.ReadFile(p.fs, "/out/"+ManifestFileName)
+	assert.NoError(t, err)
+	assert.Equal(t, manifestHeader+"skip.go\tskip\n", string(b), "kept files remain tracked")
+}
+
+func TestPersister_Persist_CleanStale_Targets(t *testing.T) {
+	t.Parallel()
+
+	src := func(name string) File {
+		f := dummyFile()
+		f.desc.Name = proto.String(name)
+		return f
+	}
+
+	d := InitMockDebugger()
+	fs := afero.NewMemMapFs()
+	run := func(targets []string, arts ...Artifact) {
+		p := dummyPersister(d)
+		p.fs = fs
+		p.SetCleanRoots("/out")
+		p.SetTargets(targets...)
+		p.Persist(arts...)
+		assert.NoError(t, d.Err())
+	}
+
+	assertFiles := func(expected map[string]bool) {
+		for name, exp := range expected {
+			exists, err := afero.Exists(fs, name)
+			assert.NoError(t, err)
+			assert.Equal(t, exp, exists, name)
+		}
+	}
+
+	// separate invocations per directory share the same root
+	run([]string{"a/a.proto"},
+		CustomFile{Name: "/out/a.txt", Contents: "a", Perms: 0644, Source: src("a/a.proto")},
+		CustomFile{Name: "/out/a_pkg.txt", Contents: "a", Perms: 0644},
+	)
+	run([]string{"b/b.proto", "b/c.proto"},
+		CustomFile{Name: "/out/b.txt", Contents: "b", Perms: 0644, Source: src("b/b.proto")},
+		CustomFile{Name: "/out/b_pkg.txt", Contents: "b", Perms: 0644},
+	)
+	assertFiles(map[string]bool{
+		"/out/a.txt":     true,
+		"/out/a_pkg.txt": true,
+		"/out/b.txt":     true,
+		"/out/b_pkg.txt": true,
+	})
+
+	b, err := afero.ReadFile(fs, "/out/"+ManifestFileName)
+	assert.NoError(t, err)
+	assert.Equal(t, manifestHeader+
+		"a.txt\toverwrite\ta/a.proto\n"+
+		"a_pkg.txt\toverwrite\ta/a.proto\n"+
+		"b.txt\toverwrite\tb/b.proto\n"+
+		"b_pkg.txt\toverwrite\tb/b.proto\tb/c.proto\n", string(b))
+
+	// regenerating a subset only removes files generated from it alone
+	run([]string{"b/b.proto"})
+	assertFiles(map[string]bool{
+		"/out/a.txt":     true,
+		"/out/a_pkg.txt": true,
+		"/out/b.txt":     false,
+		"/out/b_pkg.txt": true,
+	})
+
+	run([]string{"a/a.proto"})
+	assertFiles(map[string]bool{
+		"/out/a.txt":     false,
+		"/out/a_pkg.txt": false,
+		"/out/b_pkg.txt": true,
+	})
+
+	b, err = afero.ReadFile(fs, "/out/"+ManifestFileName)
+	assert.NoError(t, err)
+	assert.Equal(t, manifestHeader+"b_pkg.txt\toverwrite\tb/b.proto\tb/c.proto\n", string(b))
+}
+
+func TestPersister_Persist_CleanStale_Error(t *testing.T) {
+	t.Parallel()
+
+	for name, failure := range map[string]Artifact{
+		"generator error": GeneratorError{Message: "boom"},
+		"diagnostic":      Diagnostic{Severity: SeverityError, Message: "boom"},
+	} {
+		failure := failure
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			d := InitMockDebugger()
+			p := dummyPersister(d)
+			p.SetCleanRoots("/out")
+
+			manifest := []byte(manifestHeader + "stale.txt\n")
+			assert.NoError(t, afero.WriteFile(p.fs, "/out/"+ManifestFileName, manifest, 0644))
+			assert.NoError(t, afero.WriteFile(p.fs, "/out/stale.txt", []byte("old\n"), 0644))
+
+			resp := p.Persist(
+				CustomFile{Name: "/out/new.txt", Contents: "new", Perms: 0644},
+				failure,
+			)
+			assert.NoError(t, d.Err())
+			assert.NotEmpty(t, resp.GetError())
+
+			exists, err := afero.Exists(p.fs, "/out/stale.txt")
+			assert.NoError(t, err)
+			assert.True(t, exists, "a failed run must not delete files")
+
+			b, err := afero.ReadFile(p.fs, "/out/"+ManifestFileName)
+			assert.NoError(t, err)
+			assert.Equal(t, manifest, b, "a failed run must not update the manifest")
+		})
+	}
+}
+
+func TestPersister_Persist_CleanStale_DryRun(t *testing.T) {
+	t.Parallel()
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+	p.SetCleanRoots("/out")
+	p.SetDryRun("", true)
+	buf := &bytes.Buffer{}
+	p.diffOut = buf
+
+	manifest := []byte(manifestHeader + "stale.txt\n")
+	assert.NoError(t, afero.WriteFile(p.fs, "/out/"+ManifestFileName, manifest, 0644))
+	assert.NoError(t, afero.WriteFile(p.fs, "/out/stale.txt", []byte("old\n"), 0644))
+
+	resp := p.Persist()
+	assert.NoError(t, d.Err())
+	assert.Equal(t, "--- /out/stale.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-old\n", buf.String())
+	assert.Equal(t, "dry run: 1 custom file(s) out of date: /out/stale.txt", resp.GetError())
+
+	exists, err := afero.Exists(p.fs, "/out/stale.txt")
+	assert.NoError(t, err)
+	assert.True(t, exists, "dry-run must not delete files")
+
+	b, err := afero.ReadFile(p.fs, "/out/"+ManifestFileName)
+	assert.NoError(t, err)
+	assert.Equal(t, manifest, b, "dry-run must not update the manifest")
+}
+
+func TestManifestFor(t *testing.T) {
+	t.Parallel()
+
+	outer := &manifest{root: "out"}
+	inner := &manifest{root: "out/inner"}
+	ms := []*manifest{inner, outer}
+
+	m, rel := manifestFor(ms, "out/inner/foo.txt")
+	assert.Equal(t, inner, m)
+	assert.Equal(t, "foo.txt", rel)
+
+	m, rel = manifestFor(ms, "./out/bar/../foo.txt")
+	assert.Equal(t, outer, m)
+	assert.Equal(t, "foo.txt", rel)
+
+	m, _ = manifestFor(ms, "other/foo.txt")
+	assert.Nil(t, m)
+
+	m, _ = manifestFor(ms, "/out/foo.txt")
+	assert.Nil(t, m)
+}
@@ -1,6 +1,7 @@
 package pgs
 
 import (
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/runtime/protoimpl"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
@@ -15,6 +16,39 @@ type Message interface {
 	// Descriptor returns the underlying proto descriptor for this message
 	Descriptor() *descriptor.DescriptorProto
 
+	// Reflect returns the protoreflect descriptor for this message, built lazily
+	// from its File's descriptor. Nil is returned if the descriptor cannot be
+	// built.
+	Reflect() protoreflect.MessageDescriptor
+
+	// ReservedRangeSourceCodeInfo returns the SourceCodeInfo of the reserved
+	// range at index i of the Message's descriptor. Nil is returned if there is no
+	// such location.
+	ReservedRangeSourceCodeInfo(i int) SourceCodeInfo
+
+	// ReservedNameSourceCodeInfo returns the SourceCodeInfo of the reserved name
+	// at index i of the Message's descriptor. Nil is returned if there is no such
+	// location.
+	ReservedNameSourceCodeInfo(i int) SourceCodeInfo
+
+	// ReservedNames returns the field names reserved by this Message.
+	ReservedNames() []string
+
+	// ReservedRanges returns the field numbers reserved by this Message, with
+	// inclusive bounds.
+	ReservedRanges() []ReservedRange
+
+	// ExtensionRanges returns the field numbers of this Message available for
+	// Extensions, with inclusive bounds.
+	ExtensionRanges() []ExtensionRange
+
+	// IsReservedNumber returns true if the field number n is reserved by this
+	// Message.
+	IsReservedNumber(n int32) bool
+
+	// IsReservedName returns true if the field name is reserved by this Message.
+	IsReservedName(name string) bool
+
 	// Parent returns either the File or Message that directly contains this
 	// Message.
 	Parent() ParentEntity
@@ -48,6 +82,12 @@ type Message interface {
 	// transitively used.
 	Dependents() []Message
 
+	// IsRecursive returns true if this message references itself, directly or
+	// transitively, via the types of its fields (including repeated, map and
+	// OneOf fields). Unlike Dependents, this does not require the AST to be
+	// built in BiDirectional mode.
+	IsRecursive() bool
+
 	// IsMapEntry identifies this message as a MapEntry. If true, this message is
 	// not generated as code, and is used exclusively when marshaling a map field
 	// to the wire format.
@@ -104,6 +144,15 @@ func (m *msg) Fields() []Field                         { return m.fields }
 func (m *msg) OneOfs() []OneOf                         { return m.oneofs }
 func (m *msg) MapEntries() []Message                   { return m.maps }
 
+func (m *msg) Reflect() protoreflect.MessageDescriptor {
+	d, _ := reflectDescriptor(m).(protoreflect.MessageDescriptor)
+	return d
+}
+
+func (m *msg) Features() *descriptor.FeatureSet {
+	return mergeFeatures(m.parent.Features(), m.desc.GetOptions().GetFeatures())
+}
+
 func (m *msg) WellKnownType() WellKnownType {
 	if m.Package().ProtoName() == WellKnownTypePackage {
 		return LookupWKT(m.Name())
@@ -207,10 +256,16 @@ func (m *msg) Dependents() []Message {
 	return messageSetToSlice(m.FullyQualifiedName(), m.dependentsCache)
 }
 
+func (m *msg) IsRecursive() bool { return isRecursive(m) }
+
 func (m *msg) Extension(desc *protoimpl.ExtensionInfo, ext interface{}) (bool, error) {
 	return extension(m.desc.GetOptions(), desc, &ext)
 }
 
+func (m *msg) ExtensionByName(name string) (interface{}, bool, error) {
+	return dynamicExtension(m, m.desc.GetOptions(), name)
+}
+
 func (m *msg) Extensions() []Extension {
 	return m.exts
 }
@@ -309,15 +364,17 @@ func (m *msg) childAtPath(path []int32) Entity {
 	}
 
 	var child Entity
-	switch path[0] {
-	case messageTypeFieldPath:
-		child = m.fields[path[1]]
-	case messageTypeNestedTypePath:
-		child = m.preservedMsgs[path[1]]
-	case messageTypeEnumTypePath:
-		child = m.enums[path[1]]
-	case messageTypeOneofDeclPath:
-		child = m.oneofs[path[1]]
+	switch i := path[1]; {
+	case path[0] == messageTypeFieldPath && inRange(i, len(m.fields)):
+		child = m.fields[i]
+	case path[0] == messageTypeNestedTypePath && inRange(i, len(m.preservedMsgs)):
+		child = m.preservedMsgs[i]
+	case path[0] == messageTypeEnumTypePath && inRange(i, len(m.enums)):
+		child = m.enums[i]
+	case path[0] == messageTypeOneofDeclPath && inRange(i, len(m.oneofs)):
+		child = m.oneofs[i]
+	case path[0] == messageTypeExtensionPath && inRange(i, len(m.defExts)):
+		child = m.defExts[i]
 	default:
 		return nil
 	}
@@ -327,6 +384,62 @@ func (m *msg) childAtPath(path []int32) Entity {
 
 func (m *msg) addSourceCodeInfo(info SourceCodeInfo) { m.info = info }
 
+func (m *msg) OptionSourceCodeInfo(path ...int32) SourceCodeInfo {
+	return sourceCodeInfoAt(m, append([]int32{messageTypeOptionsPath}, path...)...)
+}
+
+func (m *msg) ReservedRangeSourceCodeInfo(i int) SourceCodeInfo {
+	return sourceCodeInfoAt(m, messageTypeReservedRangePath, int32(i))
+}
+
+func (m *msg) ReservedNameSourceCodeInfo(i int) SourceCodeInfo {
+	return sourceCodeInfoAt(m, messageTypeReservedNamePath, int32(i))
+}
+
+func (m *msg) ReservedNames() []string { return m.desc.GetReservedName() }
+
+func (m *msg) ReservedRanges() []ReservedRange {
+	rs := m.desc.GetReservedRange()
+	if len(rs) == 0 {
+		return nil
+	}
+
+	out := make([]ReservedRange, len(rs))
+	for i, r := range rs {
+		out[i] = ReservedRange{Start: r.GetStart(), End: r.GetEnd() - 1}
+	}
+	return out
+}
+
+func (m *msg) ExtensionRanges() []ExtensionRange {
+	rs := m.desc.GetExtensionRange()
+	if len(rs) == 0 {
+		return nil
+	}
+
+	out := make([]ExtensionRange, len(rs))
+	for i, r := range rs {
+		out[i] = ExtensionRange{Start: r.GetStart(), End: r.GetEnd() - 1, Options: r.GetOptions()}
+	}
+	return out
+}
+
+func (m *msg) IsReservedNumber(n int32) bool { return inReservedRanges(m.ReservedRanges(), n) }
+
+func (m *msg) IsReservedName(name string) bool { return containsString(m.ReservedNames(), name) }
+
+func (m *msg) SourcePath() []int32 {
+	if p, ok := m.parent.(Message); ok {
+		nested := p.Descriptor().GetNestedType()
+		i := indexOf(len(nested), func(i int) bool { return nested[i] == m.desc })
+		return childPath(p.SourcePath(), messageTypeNestedTypePath, i)
+	}
+
+	msgs := m.File().Descriptor().GetMessageType()
+	i := indexOf(len(msgs), func(i int) bool { return msgs[i] == m.desc })
+	return childPath(nil, messageTypePath, i)
+}
+
 func messageSetToSlice(name string, set map[string]Message) []Message {
 	dependents := make([]Message, 0, len(set))
 
@@ -99,6 +99,40 @@ func TestMsg_IsMapEntry(t *testing.T) {
 	assert.True(t, m.IsMapEntry())
 }
 
+func TestMsg_Reserved(t *testing.T) {
+	t.Parallel()
+
+	m := &msg{desc: &descriptor.DescriptorProto{}}
+	assert.Empty(t, m.ReservedNames())
+	assert.Empty(t, m.ReservedRanges())
+	assert.Empty(t, m.ExtensionRanges())
+	assert.False(t, m.IsReservedNumber(1))
+	assert.False(t, m.IsReservedName("foo"))
+
+	opts := &descriptor.ExtensionRangeOptions{Verification: descriptor.ExtensionRangeOptions_UNVERIFIED.Enum()}
+	m.desc = &descriptor.DescriptorProto{
+		ReservedName: []string{"foo", "bar"},
+		ReservedRange: []*descriptor.DescriptorProto_ReservedRange{
+			{Start: proto.Int32(2), End: proto.Int32(3)},
+			{Start: proto.Int32(15), End: proto.Int32(21)},
+		},
+		ExtensionRange: []*descriptor.DescriptorProto_ExtensionRange{
+			{Start: proto.Int32(100), End: proto.Int32(536870912), Options: opts},
+		},
+	}
+
+	assert.Equal(t, []string{"foo", "bar"}, m.ReservedNames())
+	assert.Equal(t, []ReservedRange{{Start: 2, End: 2}, {Start: 15, End: 20}}, m.ReservedRanges())
+	assert.Equal(t, []ExtensionRange{{Start: 100, End: 536870911, Options: opts}}, m.ExtensionRanges())
+
+	assert.True(t, m.IsReservedNumber(2))
+	assert.False(t, m.IsReservedNumber(3))
+	assert.True(t, m.IsReservedNumber(20))
+	assert.False(t, m.IsReservedNumber(21))
+	assert.True(t, m.IsReservedName("bar"))
+	assert.False(t, m.IsReservedName("baz"))
+}
+
 func TestMsg_Enums(t *testing.T) {
 	t.Parallel()
 
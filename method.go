@@ -1,6 +1,7 @@
 package pgs
 
 import (
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/runtime/protoimpl"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
@@ -12,6 +13,11 @@ type Method interface {
 	// Descriptor returns the underlying proto descriptor for this.
 	Descriptor() *descriptor.MethodDescriptorProto
 
+	// Reflect returns the protoreflect descriptor for this method, built lazily
+	// from its File's descriptor. Nil is returned if the descriptor cannot be
+	// built.
+	Reflect() protoreflect.MethodDescriptor
+
 	// Service returns the parent service for this.
 	Service() Service
 
@@ -55,6 +61,15 @@ func (m *method) ClientStreaming() bool                         { return m.desc.
 func (m *method) ServerStreaming() bool                         { return m.desc.GetServerStreaming() }
 func (m *method) BiDirStreaming() bool                          { return m.ClientStreaming() && m.ServerStreaming() }
 
+func (m *method) Reflect() protoreflect.MethodDescriptor {
+	d, _ := reflectDescriptor(m).(protoreflect.MethodDescriptor)
+	return d
+}
+
+func (m *method) Features() *descriptor.FeatureSet {
+	return mergeFeatures(m.service.Features(), m.desc.GetOptions().GetFeatures())
+}
+
 func (m *method) Imports() (i []File) {
 	mine := m.File().Name()
 	input := m.Input().File()
@@ -73,6 +88,10 @@ func (m *method) Extension(desc *protoimpl.ExtensionInfo, ext interface{}) (ok b
 	return extension(m.desc.GetOptions(), desc, &ext)
 }
 
+func (m *method) ExtensionByName(name string) (interface{}, bool, error) {
+	return dynamicExtension(m, m.desc.GetOptions(), name)
+}
+
 func (m *method) accept(v Visitor) (err error) {
 	if v == nil {
 		return
@@ -93,4 +112,14 @@ func (m *method) childAtPath(path []int32) Entity {
 
 func (m *method) addSourceCodeInfo(info SourceCodeInfo) { m.info = info }
 
+func (m *method) OptionSourceCodeInfo(path ...int32) SourceCodeInfo {
+	return sourceCodeInfoAt(m, append([]int32{methodOptionsPath}, path...)...)
+}
+
+func (m *method) SourcePath() []int32 {
+	mtds := m.service.Descriptor().GetMethod()
+	i := indexOf(len(mtds), func(i int) bool { return mtds[i] == m.desc })
+	return childPath(m.service.SourcePath(), serviceTypeMethodPath, i)
+}
+
 var _ Method = (*method)(nil)
@@ -19,6 +19,20 @@ type Module interface {
 	Execute(targets map[string]File, packages map[string]Package) []Artifact
 }
 
+// A DependentModule is a Module that must execute after one or more other
+// Modules have completed. Implementing this interface is optional. Modules are
+// still initialized in the order they are registered, but a DependentModule's
+// Artifacts are persisted after those of the Modules it depends on, permitting
+// it to append to or overwrite their files.
+type DependentModule interface {
+	Module
+
+	// DependsOn returns the names of the Modules that must finish executing
+	// before this Module's Execute method is called. Every name must match a
+	// registered Module, and the dependencies may not form a cycle.
+	DependsOn() []string
+}
+
 // ModuleBase provides utility methods and a base implementation for a
 // protoc-gen-star Module. ModuleBase should be used as an anonymously embedded
 // field of an actual Module implementation. The only methods that need to be
@@ -262,4 +276,28 @@ func (m *ModuleBase) AddError(message string) {
 	m.AddArtifact(GeneratorError{Message: message})
 }
 
+// AddEntityError reports an error attached to Entity e. The error includes
+// the position of e in its proto file and is emitted in the `error` field of
+// the CodeGeneratorResponse alongside any other errors reported by Modules
+// (separated by "; ").
+// Unlike Fail, this method does not halt execution, permitting all errors in
+// the input to be reported in a single run.
+func (m *ModuleBase) AddEntityError(e Entity, message string) {
+	m.AddArtifact(Diagnostic{
+		Entity:   e,
+		Severity: SeverityError,
+		Message:  message,
+	})
+}
+
+// AddEntityWarning behaves the same as AddEntityError, however the warning is
+// only logged and does not cause code generation to fail.
+func (m *ModuleBase) AddEntityWarning(e Entity, message string) {
+	m.AddArtifact(Diagnostic{
+		Entity:   e,
+		Severity: SeverityWarning,
+		Message:  message,
+	})
+}
+
 var _ Module = (*ModuleBase)(nil)
@@ -282,3 +282,18 @@ func TestModuleBase_AddError(t *testing.T) {
 	assert.Len(t, arts, 1)
 	assert.Equal(t, GeneratorError{Message: "bohoo"}, arts[0])
 }
+
+func TestModuleBase_AddEntityError(t *testing.T) {
+	t.Parallel()
+
+	e := dummyMsg()
+
+	m := new(ModuleBase)
+	m.AddEntityError(e, "foo")
+	m.AddEntityWarning(e, "bar")
+
+	arts := m.Artifacts()
+	assert.Len(t, arts, 2)
+	assert.Equal(t, Diagnostic{Entity: e, Severity: SeverityError, Message: "foo"}, arts[0])
+	assert.Equal(t, Diagnostic{Entity: e, Severity: SeverityWarning, Message: "bar"}, arts[1])
+}
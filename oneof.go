@@ -1,6 +1,7 @@
 package pgs
 
 import (
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/runtime/protoimpl"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
@@ -13,6 +14,11 @@ type OneOf interface {
 	// Descriptor returns the underlying proto descriptor for this OneOf
 	Descriptor() *descriptor.OneofDescriptorProto
 
+	// Reflect returns the protoreflect descriptor for this OneOf, built lazily
+	// from its File's descriptor. Nil is returned if the descriptor cannot be
+	// built.
+	Reflect() protoreflect.OneofDescriptor
+
 	// Message returns the parent message for this OneOf.
 	Message() Message
 
@@ -56,6 +62,15 @@ func (o *oneof) Descriptor() *descriptor.OneofDescriptorProto { return o.desc }
 func (o *oneof) Message() Message                             { return o.msg }
 func (o *oneof) setMessage(m Message)                         { o.msg = m }
 
+func (o *oneof) Reflect() protoreflect.OneofDescriptor {
+	d, _ := reflectDescriptor(o).(protoreflect.OneofDescriptor)
+	return d
+}
+
+func (o *oneof) Features() *descriptor.FeatureSet {
+	return mergeFeatures(o.msg.Features(), o.desc.GetOptions().GetFeatures())
+}
+
 func (o *oneof) IsSynthetic() bool {
 	return o.Syntax() == Proto3 &&
 		len(o.flds) == 1 &&
@@ -80,6 +95,10 @@ func (o *oneof) Extension(desc *protoimpl.ExtensionInfo, ext interface{}) (ok bo
 	return extension(o.desc.GetOptions(), desc, &ext)
 }
 
+func (o *oneof) ExtensionByName(name string) (interface{}, bool, error) {
+	return dynamicExtension(o, o.desc.GetOptions(), name)
+}
+
 func (o *oneof) Fields() []Field {
 	f := make([]Field, len(o.flds))
 	copy(f, o.flds)
@@ -100,4 +119,14 @@ func (o *oneof) childAtPath(path []int32) Entity {
 
 func (o *oneof) addSourceCodeInfo(info SourceCodeInfo) { o.info = info }
 
+func (o *oneof) OptionSourceCodeInfo(path ...int32) SourceCodeInfo {
+	return sourceCodeInfoAt(o, append([]int32{oneofOptionsPath}, path...)...)
+}
+
+func (o *oneof) SourcePath() []int32 {
+	decls := o.msg.Descriptor().GetOneofDecl()
+	i := indexOf(len(decls), func(i int) bool { return decls[i] == o.desc })
+	return childPath(o.msg.SourcePath(), messageTypeOneofDeclPath, i)
+}
+
 var _ OneOf = (*oneof)(nil)
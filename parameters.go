@@ -8,7 +8,12 @@ import (
 	"time"
 )
 
-const outputPathKey = "output_path"
+const (
+	outputPathKey   = "output_path"
+	dryRunKey       = "dry_run"
+	dryRunReportKey = "dry_run_report"
+	dryRunFailKey   = "dry_run_fail"
+)
 
 // Parameters provides a convenience for accessing and modifying the parameters
 // passed into the protoc-gen-star plugin.
@@ -51,6 +56,31 @@ func (p Parameters) OutputPath() string { return p.StrDefault(outputPathKey, "."
 // for overriding the behavior of the ImportPath at runtime.
 func (p Parameters) SetOutputPath(path string) { p.SetStr(outputPathKey, path) }
 
+// DryRun returns the protoc-gen-star "dry_run" special parameter. If true,
+// custom files are not written to disk; instead, a diff of the changes that
+// would have been made is produced. An error is returned if the value cannot
+// be parsed as a boolean. See the DryRun InitOption for details.
+func (p Parameters) DryRun() (bool, error) { return p.Bool(dryRunKey) }
+
+// SetDryRun sets the protoc-gen-star DryRun parameter.
+func (p Parameters) SetDryRun(b bool) { p.SetBool(dryRunKey, b) }
+
+// DryRunReport returns the protoc-gen-star "dry_run_report" special parameter,
+// the name of the generated file to which a dry-run's diff is written. If
+// empty, the diff is written to stderr.
+func (p Parameters) DryRunReport() string { return p.Str(dryRunReportKey) }
+
+// SetDryRunReport sets the protoc-gen-star DryRunReport parameter.
+func (p Parameters) SetDryRunReport(name string) { p.SetStr(dryRunReportKey, name) }
+
+// DryRunFail returns the protoc-gen-star "dry_run_fail" special parameter. If
+// true, a dry-run fails code generation if any custom file would change. An
+// error is returned if the value cannot be parsed as a boolean.
+func (p Parameters) DryRunFail() (bool, error) { return p.Bool(dryRunFailKey) }
+
+// SetDryRunFail sets the protoc-gen-star DryRunFail parameter.
+func (p Parameters) SetDryRunFail(b bool) { p.SetBool(dryRunFailKey, b) }
+
 // String satisfies the string.Stringer interface. This method returns p in the
 // format it is provided to the protoc execution. Output of this function is
 // always stable; parameters are sorted before the string is emitted.
@@ -7,6 +7,36 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func TestParameters_DryRun(t *testing.T) {
+	t.Parallel()
+
+	p := Parameters{}
+
+	dry, err := p.DryRun()
+	assert.NoError(t, err)
+	assert.False(t, dry)
+	fail, err := p.DryRunFail()
+	assert.NoError(t, err)
+	assert.False(t, fail)
+	assert.Empty(t, p.DryRunReport())
+
+	p.SetDryRun(true)
+	p.SetDryRunFail(true)
+	p.SetDryRunReport("diff.txt")
+
+	dry, err = p.DryRun()
+	assert.NoError(t, err)
+	assert.True(t, dry)
+	fail, err = p.DryRunFail()
+	assert.NoError(t, err)
+	assert.True(t, fail)
+	assert.Equal(t, "diff.txt", p.DryRunReport())
+
+	p = ParseParameters("dry_run=nope")
+	_, err = p.DryRun()
+	assert.Error(t, err)
+}
+
 func TestParameters_OutputPath(t *testing.T) {
 	t.Parallel()
 
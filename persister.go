@@ -1,12 +1,16 @@
 package pgs
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/spf13/afero"
 	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
 	plugin_go "google.golang.org/protobuf/types/pluginpb"
 )
 
@@ -14,8 +18,16 @@ type persister interface {
 	SetDebugger(d Debugger)
 	SetFS(fs afero.Fs)
 	SetSupportedFeatures(f *uint64)
+	SetSupportedEditions(min, max Edition)
+	SetWorkers(n int)
+	SetDryRun(report string, failOnChange bool)
+	SetCleanRoots(roots ...string)
+	SetTargets(files ...string)
+	SetResolveInsertionPoints(resolve bool)
+	SetAnnotations(enabled bool)
 	AddPostProcessor(proc ...PostProcessor)
 	Persist(a ...Artifact) *plugin_go.CodeGeneratorResponse
+	WriteResponse(dir string, resp *plugin_go.CodeGeneratorResponse)
 }
 
 type stdPersister struct {
@@ -24,6 +36,20 @@ type stdPersister struct {
 	fs                afero.Fs
 	procs             []PostProcessor
 	supportedFeatures *uint64
+	minEdition        Edition
+	maxEdition        Edition
+	workers           int
+
+	dryRun       bool
+	dryRunReport string
+	dryRunFail   bool
+	diffOut      io.Writer
+
+	cleanRoots []string
+	targets    []string
+
+	resolveInjections bool
+	annotate          bool
 }
 
 func newPersister() *stdPersister { return &stdPersister{fs: afero.NewOsFs()} }
@@ -31,77 +57,218 @@ func newPersister() *stdPersister { return &stdPersister{fs: afero.NewOsFs()} }
 func (p *stdPersister) SetDebugger(d Debugger)                 { p.Debugger = d }
 func (p *stdPersister) SetFS(fs afero.Fs)                      { p.fs = fs }
 func (p *stdPersister) SetSupportedFeatures(f *uint64)         { p.supportedFeatures = f }
+func (p *stdPersister) SetWorkers(n int)                       { p.workers = n }
 func (p *stdPersister) AddPostProcessor(proc ...PostProcessor) { p.procs = append(p.procs, proc...) }
 
+func (p *stdPersister) SetSupportedEditions(min, max Edition) {
+	p.minEdition, p.maxEdition = min, max
+}
+
+func (p *stdPersister) SetResolveInsertionPoints(resolve bool) { p.resolveInjections = resolve }
+
+func (p *stdPersister) SetAnnotations(enabled bool) { p.annotate = enabled }
+
+func (p *stdPersister) SetCleanRoots(roots ...string) { p.cleanRoots = roots }
+
+func (p *stdPersister) SetTargets(files ...string) { p.targets = files }
+
+func (p *stdPersister) SetDryRun(report string, failOnChange bool) {
+	p.dryRun, p.dryRunReport, p.dryRunFail = true, report, failOnChange
+}
+
 func (p *stdPersister) Persist(arts ...Artifact) *plugin_go.CodeGeneratorResponse {
 	resp := new(plugin_go.CodeGeneratorResponse)
 	resp.SupportedFeatures = p.supportedFeatures
 
-	for _, a := range arts {
+	if p.maxEdition != EditionUnknown {
+		feat := p.supportedFeatures
+		if feat == nil {
+			feat = new(uint64)
+		}
+		resp.SupportedFeatures = proto.Uint64(*feat | uint64(plugin_go.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS))
+		resp.MinimumEdition = proto.Int32(int32(p.minEdition))
+		resp.MaximumEdition = proto.Int32(int32(p.maxEdition))
+	}
+
+	var dry *dryRunResult
+	if p.dryRun {
+		dry = &dryRunResult{}
+	}
+
+	var manifests []*manifest
+	if len(p.cleanRoots) > 0 {
+		manifests = p.loadManifests()
+	}
+
+	out := p.renderAll(arts)
+
+	for i, a := range arts {
+		r := out[i]
+
 		switch a := a.(type) {
 		case GeneratorFile:
-			f, err := a.ProtoFile()
-			p.CheckErr(err, "unable to convert ", a.Name, " to proto")
-			f.Content = proto.String(p.postProcess(a, f.GetContent()))
-			p.insertFile(resp, f, a.Overwrite)
+			p.CheckErr(r.err, r.errMsg...)
+			p.insertFile(resp, r.file, a.Overwrite)
 		case GeneratorTemplateFile:
-			f, err := a.ProtoFile()
-			p.CheckErr(err, "unable to convert ", a.Name, " to proto")
-			f.Content = proto.String(p.postProcess(a, f.GetContent()))
-			p.insertFile(resp, f, a.Overwrite)
+			p.CheckErr(r.err, r.errMsg...)
+			p.insertFile(resp, r.file, a.Overwrite)
 		case GeneratorAppend:
-			f, err := a.ProtoFile()
-			p.CheckErr(err, "unable to convert append for ", a.FileName, " to proto")
-			f.Content = proto.String(p.postProcess(a, f.GetContent()))
+			p.CheckErr(r.err, r.errMsg...)
 			n, _ := cleanGeneratorFileName(a.FileName)
-			p.insertAppend(resp, n, f)
+			p.insertAppend(resp, n, r.file)
 		case GeneratorTemplateAppend:
-			f, err := a.ProtoFile()
-			p.CheckErr(err, "unable to convert append for ", a.FileName, " to proto")
-			f.Content = proto.String(p.postProcess(a, f.GetContent()))
+			p.CheckErr(r.err, r.errMsg...)
 			n, _ := cleanGeneratorFileName(a.FileName)
-			p.insertAppend(resp, n, f)
-		case GeneratorInjection:
-			f, err := a.ProtoFile()
-			p.CheckErr(err, "unable to convert injection ", a.InsertionPoint, " for ", a.FileName, " to proto")
-			f.Content = proto.String(p.postProcess(a, f.GetContent()))
-			p.insertFile(resp, f, false)
-		case GeneratorTemplateInjection:
-			f, err := a.ProtoFile()
-			p.CheckErr(err, "unable to convert injection ", a.InsertionPoint, " for ", a.FileName, " to proto")
-			f.Content = proto.String(p.postProcess(a, f.GetContent()))
-			p.insertFile(resp, f, false)
+			p.insertAppend(resp, n, r.file)
+		case GeneratorInjection, GeneratorTemplateInjection:
+			p.CheckErr(r.err, r.errMsg...)
+			p.insertInjection(resp, r.file)
 		case CustomFile:
-			p.writeFile(
-				a.Name,
-				[]byte(p.postProcess(a, a.Contents)),
-				a.Overwrite,
-				a.Perms,
-			)
+			p.CheckErr(r.err, r.errMsg...)
+			p.persistCustom(dry, manifests, a.Name, []byte(r.content), a.Overwrite, a.HandEdits, a.Perms, a.Source)
 		case CustomTemplateFile:
-			content, err := a.render()
-			p.CheckErr(err, "unable to render CustomTemplateFile: ", a.Name)
-			content = p.postProcess(a, content)
-			p.writeFile(
-				a.Name,
-				[]byte(content),
-				a.Overwrite,
-				a.Perms,
-			)
+			p.CheckErr(r.err, r.errMsg...)
+			p.persistCustom(dry, manifests, a.Name, []byte(r.content), a.Overwrite, a.HandEdits, a.Perms, a.Source)
 		case GeneratorError:
 			if resp.Error == nil {
 				resp.Error = proto.String(a.Message)
 				continue
 			}
 			resp.Error = proto.String(strings.Join([]string{resp.GetError(), a.Message}, "; "))
+		case Diagnostic:
+			p.addDiagnostic(resp, a)
 		default:
 			p.Failf("unrecognized artifact type: %T", a)
 		}
 	}
 
+	// a failed run may not have emitted all of its files, so nothing is
+	// considered stale and the manifests are left as they were
+	if manifests != nil && resp.Error == nil {
+		p.cleanStale(manifests, dry)
+	}
+
+	if dry != nil {
+		p.reportDryRun(resp, dry)
+	}
+
 	return resp
 }
 
+// rendered is the output of an Artifact after rendering and post-processing.
+// If err is not nil, errMsg describes the step that failed.
+type rendered struct {
+	file    *plugin_go.CodeGeneratorResponse_File
+	content string
+	err     error
+	errMsg  []interface{}
+}
+
+// renderAll renders and post-processes each of arts, returning the results in
+// the same order. If more than one worker is configured, the Artifacts are
+// rendered concurrently.
+func (p *stdPersister) renderAll(arts []Artifact) []rendered {
+	out := make([]rendered, len(arts))
+
+	if p.workers < 2 {
+		for i, a := range arts {
+			out[i] = p.render(a)
+		}
+		return out
+	}
+
+	idx := make(chan int)
+	wg := sync.WaitGroup{}
+	wg.Add(p.workers)
+	for w := 0; w < p.workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range idx {
+				out[i] = p.render(arts[i])
+			}
+		}()
+	}
+
+	for i := range arts {
+		idx <- i
+	}
+	close(idx)
+	wg.Wait()
+
+	return out
+}
+
+// render converts a to its final contents, applying any matching
+// PostProcessors. Artifacts that do not produce content are ignored. render
+// must not modify the persister, as it may be called concurrently.
+func (p *stdPersister) render(a Artifact) (r rendered) {
+	switch a := a.(type) {
+	case GeneratorFile:
+		r.file, r.err = a.ProtoFile()
+		r.errMsg = []interface{}{"unable to convert ", a.Name, " to proto"}
+	case GeneratorTemplateFile:
+		r.file, r.err = a.ProtoFile()
+		r.errMsg = []interface{}{"unable to convert ", a.Name, " to proto"}
+	case GeneratorAppend:
+		r.file, r.err = a.ProtoFile()
+		r.errMsg = []interface{}{"unable to convert append for ", a.FileName, " to proto"}
+	case GeneratorTemplateAppend:
+		r.file, r.err = a.ProtoFile()
+		r.errMsg = []interface{}{"unable to convert append for ", a.FileName, " to proto"}
+	case GeneratorInjection:
+		r.file, r.err = a.ProtoFile()
+		r.errMsg = []interface{}{"unable to convert injection ", a.InsertionPoint, " for ", a.FileName, " to proto"}
+	case GeneratorTemplateInjection:
+		r.file, r.err = a.ProtoFile()
+		r.errMsg = []interface{}{"unable to convert injection ", a.InsertionPoint, " for ", a.FileName, " to proto"}
+	case CustomFile:
+		r.content = a.Contents
+	case CustomTemplateFile:
+		r.content, r.err = a.render()
+		r.errMsg = []interface{}{"unable to render CustomTemplateFile: ", a.Name}
+	default:
+		return r
+	}
+
+	if r.err != nil {
+		return r
+	}
+
+	if r.file == nil {
+		if r.content, r.err = p.postProcess(a, r.content); r.err != nil {
+			r.errMsg = []interface{}{"failed post-processing"}
+		}
+		return r
+	}
+
+	raw := r.file.GetContent()
+	var anns []*descriptor.GeneratedCodeInfo_Annotation
+	if p.annotate {
+		var err error
+		if raw, anns, err = extractAnnotations(raw); err != nil {
+			r.err, r.errMsg = err, []interface{}{"invalid annotations"}
+			return r
+		}
+	}
+
+	content, err := p.postProcess(a, raw)
+	if err != nil {
+		r.err, r.errMsg = err, []interface{}{"failed post-processing"}
+		return r
+	}
+	r.file.Content = proto.String(content)
+
+	// appended content cannot be annotated, as protoc ignores the
+	// GeneratedCodeInfo of files without a name
+	if len(anns) > 0 && r.file.GetName() != "" {
+		r.file.GeneratedCodeInfo = &descriptor.GeneratedCodeInfo{
+			Annotation: remapAnnotations(raw, content, anns),
+		}
+	}
+
+	return r
+}
+
 func (p *stdPersister) tailOfFile(resp *plugin_go.CodeGeneratorResponse, name string) int {
 	tail := p.indexOfFile(resp, name)
 
@@ -156,7 +323,154 @@ func (p *stdPersister) insertAppend(resp *plugin_go.CodeGeneratorResponse,
 	)
 }
 
-func (p *stdPersister) writeFile(name string, content []byte, overwrite bool, perms os.FileMode) {
+// insertInjection adds the injection f to resp. If insertion points are
+// resolved by the persister and the target file was produced earlier in resp,
+// f is spliced into the file's contents (including any appends to it) instead.
+// An error is added to resp if the insertion point does not exist.
+func (p *stdPersister) insertInjection(resp *plugin_go.CodeGeneratorResponse, f *plugin_go.CodeGeneratorResponse_File) {
+	i := -1
+	if p.resolveInjections {
+		i = p.indexOfFile(resp, f.GetName())
+	}
+
+	if i < 0 {
+		p.insertFile(resp, f, false)
+		return
+	}
+
+	tail := p.tailOfFile(resp, f.GetName())
+
+	buf := &strings.Builder{}
+	for _, part := range resp.File[i : tail+1] {
+		buf.WriteString(part.GetContent())
+	}
+
+	target := buf.String()
+	content, err := insertAtPoint(target, f.GetInsertionPoint(), f.GetContent())
+	if err != nil {
+		msg := fmt.Sprintf("%s: %v", f.GetName(), err)
+		if resp.Error == nil {
+			resp.Error = proto.String(msg)
+			return
+		}
+		resp.Error = proto.String(strings.Join([]string{resp.GetError(), msg}, "; "))
+		return
+	}
+
+	start, indent, _ := findInsertionPoint(target, f.GetInsertionPoint())
+	resp.File[i].GeneratedCodeInfo = injectAnnotations(
+		resp.File[i].GetGeneratedCodeInfo(), f, start, indent, len(content)-len(target))
+
+	resp.File[i].Content = proto.String(content)
+	resp.File = append(resp.File[:i+1], resp.File[tail+1:]...)
+}
+
+// insertAtPoint inserts content into file immediately above the line containing
+// the insertion point marker, indenting each inserted line to match it.
+func insertAtPoint(file, point, content string) (string, error) {
+	start, indent, err := findInsertionPoint(file, point)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &strings.Builder{}
+	buf.WriteString(file[:start])
+	for _, ln := range strings.SplitAfter(content, "\n") {
+		if ln == "" {
+			continue
+		}
+		if ln != "\n" {
+			buf.WriteString(indent)
+		}
+		buf.WriteString(ln)
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		buf.WriteByte('\n')
+	}
+	buf.WriteString(file[start:])
+
+	return buf.String(), nil
+}
+
+// findInsertionPoint returns the offset of the start of the line containing
+// the insertion point marker in file, and the line's indentation.
+func findInsertionPoint(file, point string) (start int, indent string, err error) {
+	marker := fmt.Sprintf("@@protoc_insertion_point(%s)", point)
+
+	i := strings.Index(file, marker)
+	if i < 0 {
+		return 0, "", fmt.Errorf("insertion point %q not found", point)
+	}
+
+	start = strings.LastIndexByte(file[:i], '\n') + 1
+	indent = file[start:i]
+	return start, indent[:len(indent)-len(strings.TrimLeft(indent, " \t"))], nil
+}
+
+func (p *stdPersister) addDiagnostic(resp *plugin_go.CodeGeneratorResponse, d Diagnostic) {
+	if d.Severity != SeverityError {
+		p.Log(d.String())
+		return
+	}
+
+	if resp.Error == nil {
+		resp.Error = proto.String(d.String())
+		return
+	}
+
+	resp.Error = proto.String(strings.Join([]string{resp.GetError(), d.String()}, "; "))
+}
+
+// persistCustom writes a custom file to the file system, or, if dry is not
+// nil, records how it would change instead. The file is tracked in the
+// appropriate manifest, if any, along with the proto file it was generated
+// from.
+func (p *stdPersister) persistCustom(dry *dryRunResult, ms []*manifest,
+	name string, content []byte, overwrite bool, edits HandEditPolicy, perms os.FileMode, source File) {
+	overwrite = overwrite && p.checkHandEdits(name, edits, "overwriting")
+
+	var written bool
+	if dry != nil {
+		written = p.diffFile(dry, name, content, overwrite)
+	} else {
+		written = p.writeFile(name, content, overwrite, perms)
+	}
+
+	p.trackFile(ms, name, written, edits, source)
+}
+
+// checkHandEdits returns false if the existing file name must not be
+// overwritten (or removed) under policy because it was edited by hand. The
+// action is included in the warnings that are logged.
+func (p *stdPersister) checkHandEdits(name string, policy HandEditPolicy, action string) bool {
+	if policy == HandEditsOverwrite {
+		return true
+	}
+
+	exists, err := afero.Exists(p.fs, name)
+	p.CheckErr(err, "unable to check file exists:", name)
+	if !exists {
+		return true
+	}
+
+	existing, err := afero.ReadFile(p.fs, name)
+	p.CheckErr(err, "unable to read file:", name)
+	if !HandEdited(existing) {
+		return true
+	}
+
+	if policy == HandEditsSkip {
+		p.Logf("warning: file %s was edited by hand, skipping", name)
+		return false
+	}
+
+	p.Logf("warning: file %s was edited by hand, %s", name, action)
+	return true
+}
+
+// writeFile writes content to the file name, returning false if the file was
+// skipped because it already exists.
+func (p *stdPersister) writeFile(name string, content []byte, overwrite bool, perms os.FileMode) bool {
 	dir := filepath.Dir(name)
 	p.CheckErr(
 		p.fs.MkdirAll(dir, 0755),
@@ -168,7 +482,7 @@ func (p *stdPersister) writeFile(name string, content []byte, overwrite bool, pe
 	if exists {
 		if !overwrite {
 			p.Debug("file", name, "exists, skipping")
-			return
+			return false
 		}
 		p.Debug("file", name, "exists, overwriting")
 	}
@@ -176,17 +490,28 @@ func (p *stdPersister) writeFile(name string, content []byte, overwrite bool, pe
 	p.CheckErr(
 		afero.WriteFile(p.fs, name, content, perms),
 		"unable to write file:", name)
+
+	return true
 }
 
-func (p *stdPersister) postProcess(a Artifact, in string) string {
+func (p *stdPersister) postProcess(a Artifact, in string) (string, error) {
 	var err error
 	b := []byte(in)
 	for _, pp := range p.procs {
-		if pp.Match(a) {
+		if !pp.Match(a) {
+			continue
+		}
+
+		if app, ok := pp.(ArtifactPostProcessor); ok {
+			b, err = app.ProcessArtifact(a, b)
+		} else {
 			b, err = pp.Process(b)
-			p.CheckErr(err, "failed post-processing")
+		}
+
+		if err != nil {
+			return "", err
 		}
 	}
 
-	return string(b)
+	return string(b), nil
 }
@@ -1,13 +1,22 @@
 package pgs
 
 import (
+	"bytes"
+	"fmt"
 	"html/template"
+	"io"
+	"io/ioutil"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"errors"
 
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
 )
 
 func TestPersister_Persist_Unrecognized(t *testing.T) {
@@ -213,6 +222,57 @@ func TestPersister_Persist_GeneratorInjection(t *testing.T) {
 	assert.Equal(t, "baz", resp.File[0].GetContent())
 }
 
+func TestPersister_Persist_ResolveInsertionPoints(t *testing.T) {
+	t.Parallel()
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+	p.SetResolveInsertionPoints(true)
+
+	resp := p.Persist(
+		GeneratorFile{Name: "foo", Contents: "foo\n"},
+		GeneratorAppend{FileName: "foo", Contents: "\t// @@protoc_insertion_point(bar)\n"},
+		GeneratorTemplateInjection{
+			FileName:         "foo",
+			InsertionPoint:   "bar",
+			TemplateArtifact: TemplateArtifact{Template: genTpl, Data: "baz\n"},
+		},
+		GeneratorAppend{FileName: "foo", Contents: "quux\n"},
+		GeneratorInjection{FileName: "other", InsertionPoint: "bar", Contents: "fizz"},
+	)
+
+	assert.NoError(t, d.Err())
+	assert.Nil(t, resp.Error)
+	assert.Len(t, resp.File, 3)
+	assert.Equal(t, "foo", resp.File[0].GetName())
+	assert.Equal(t, "foo\n\tbaz\n\t// @@protoc_insertion_point(bar)\n", resp.File[0].GetContent())
+	assert.Equal(t, "", resp.File[1].GetName())
+	assert.Equal(t, "quux\n", resp.File[1].GetContent())
+	assert.Equal(t, "other", resp.File[2].GetName())
+	assert.Equal(t, "bar", resp.File[2].GetInsertionPoint(), "injections into unknown files are left to protoc")
+
+	resp = p.Persist(
+		GeneratorFile{Name: "foo", Contents: "foo\n"},
+		GeneratorInjection{FileName: "foo", InsertionPoint: "missing", Contents: "fizz"},
+	)
+	assert.Len(t, resp.File, 1)
+	assert.Equal(t, "foo\n", resp.File[0].GetContent())
+	assert.Equal(t, `foo: insertion point "missing" not found`, resp.GetError())
+}
+
+func TestInsertAtPoint(t *testing.T) {
+	t.Parallel()
+
+	file := "a\n  // @@protoc_insertion_point(foo)\nb\n"
+
+	out, err := insertAtPoint(file, "foo", "x\n\ny")
+	assert.NoError(t, err)
+	assert.Equal(t, "a\n  x\n\n  y\n  // @@protoc_insertion_point(foo)\nb\n", out)
+
+	_, err = insertAtPoint(file, "bar", "x\n")
+	assert.Error(t, err)
+}
+
 func TestPersister_Persist_GeneratorTemplateInjection(t *testing.T) {
 	t.Parallel()
 
@@ -334,10 +394,115 @@ func TestPersister_AddPostProcessor(t *testing.T) {
 	bad := &mockPP{err: errors.New("should not be called")}
 
 	p.AddPostProcessor(good, bad)
-	out := p.postProcess(GeneratorFile{}, "")
+	out, err := p.postProcess(GeneratorFile{}, "")
+	assert.NoError(t, err)
 	assert.Equal(t, "good", out)
 }
 
+func TestPersister_PostProcess_Artifact(t *testing.T) {
+	t.Parallel()
+
+	p := dummyPersister(InitMockDebugger())
+	p.AddPostProcessor(StampHeaders("protoc-gen-foo", ""))
+
+	out, err := p.postProcess(GeneratorFile{Name: "foo.go"}, "package foo\n")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "// Code generated by protoc-gen-foo. DO NOT EDIT.\n")
+}
+
+func TestPersister_Persist_PostProcessError(t *testing.T) {
+	t.Parallel()
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+	p.AddPostProcessor(&mockPP{match: true, err: errors.New("fizz")})
+
+	p.Persist(GeneratorFile{Name: "foo"})
+	assert.EqualError(t, d.Err(), "fizz")
+}
+
+// serialTpl is a Template that records whether it was ever executed
+// concurrently.
+type serialTpl struct {
+	running, overlapped int32
+}
+
+func (tpl *serialTpl) Execute(w io.Writer, data interface{}) error {
+	if atomic.AddInt32(&tpl.running, 1) > 1 {
+		atomic.StoreInt32(&tpl.overlapped, 1)
+	}
+	defer atomic.AddInt32(&tpl.running, -1)
+
+	time.Sleep(time.Millisecond)
+	_, err := fmt.Fprint(w, data)
+	return err
+}
+
+func TestPersister_Persist_Parallel(t *testing.T) {
+	t.Parallel()
+
+	custom := &serialTpl{}
+
+	arts := func() []Artifact {
+		var out []Artifact
+		for i := 0; i < 10; i++ {
+			name := fmt.Sprintf("file%d.txt", i)
+			out = append(out,
+				GeneratorTemplateFile{
+					Name:             name,
+					TemplateArtifact: TemplateArtifact{Template: genTpl, Data: name},
+				},
+				GeneratorFile{Name: "all.txt", Contents: name, Overwrite: true},
+				GeneratorTemplateInjection{
+					FileName:         name,
+					InsertionPoint:   "point",
+					TemplateArtifact: TemplateArtifact{Template: custom, Data: i},
+				},
+				CustomTemplateFile{
+					Name:             "/custom/" + name,
+					TemplateArtifact: TemplateArtifact{Template: custom, Data: name},
+					Perms:            0644,
+				})
+		}
+		return append(out,
+			GeneratorAppend{FileName: "file3.txt", Contents: "three"},
+			GeneratorAppend{FileName: "file3.txt", Contents: "tres"},
+		)
+	}
+
+	persist := func(workers int) (*plugin_go.CodeGeneratorResponse, afero.Fs) {
+		d := InitMockDebugger()
+		p := dummyPersister(d)
+		p.SetWorkers(workers)
+		p.AddPostProcessor(&upperPP{})
+
+		resp := p.Persist(arts()...)
+		assert.False(t, d.Failed())
+		assert.NoError(t, d.Err())
+		return resp, p.fs
+	}
+
+	serial, _ := persist(0)
+	parallel, fs := persist(4)
+
+	assert.Equal(t, len(serial.File), len(parallel.File))
+	for i, f := range serial.File {
+		assert.True(t, proto.Equal(f, parallel.File[i]), "file %d differs", i)
+	}
+	assert.Zero(t, atomic.LoadInt32(&custom.overlapped),
+		"non-standard templates must not execute concurrently")
+
+	b, err := afero.ReadFile(fs, "/custom/file7.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "FILE7.TXT", string(b))
+}
+
+// upperPP is a PostProcessor that upper-cases the content of every Artifact.
+type upperPP struct{}
+
+func (upperPP) Match(a Artifact) bool             { return true }
+func (upperPP) Process(in []byte) ([]byte, error) { return bytes.ToUpper(in), nil }
+
 func dummyPersister(d Debugger) *stdPersister {
 	return &stdPersister{
 		Debugger: d,
@@ -382,3 +547,51 @@ func TestPersister_Persist_GeneratorError(t *testing.T) {
 		})
 	}
 }
+
+func TestPersister_Persist_SupportedEditions(t *testing.T) {
+	t.Parallel()
+
+	p := dummyPersister(InitMockDebugger())
+
+	resp := p.Persist()
+	assert.Nil(t, resp.SupportedFeatures)
+	assert.Nil(t, resp.MinimumEdition)
+	assert.Nil(t, resp.MaximumEdition)
+
+	feat := uint64(plugin_go.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+	p.SetSupportedFeatures(&feat)
+	p.SetSupportedEditions(EditionProto2, Edition2023)
+
+	resp = p.Persist()
+	assert.Equal(t, uint64(plugin_go.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL|
+		plugin_go.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS), resp.GetSupportedFeatures())
+	assert.Equal(t, int32(EditionProto2), resp.GetMinimumEdition())
+	assert.Equal(t, int32(Edition2023), resp.GetMaximumEdition())
+	assert.Equal(t, uint64(plugin_go.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL), feat,
+		"provided features must not be mutated")
+}
+
+func TestPersister_Persist_Diagnostic(t *testing.T) {
+	t.Parallel()
+
+	m := dummyMsg()
+	m.addSourceCodeInfo(sci{desc: &descriptor.SourceCodeInfo_Location{
+		Span: []int32{1, 2, 3},
+	}})
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+
+	resp := p.Persist(
+		GeneratorError{Message: "foo"},
+		Diagnostic{Entity: m, Message: "bar"},
+		Diagnostic{Entity: m, Severity: SeverityWarning, Message: "fizz"},
+		Diagnostic{Entity: m.File(), Message: "buzz"},
+	)
+
+	assert.Equal(t, "foo; file.proto:2:3: bar; file.proto: buzz", resp.GetError())
+	assert.False(t, d.Failed())
+
+	out, _ := ioutil.ReadAll(d.Output())
+	assert.Contains(t, string(out), "file.proto:2:3: warning: fizz")
+}
@@ -10,3 +10,14 @@ type PostProcessor interface {
 	// an error if something goes wrong.
 	Process(in []byte) ([]byte, error)
 }
+
+// An ArtifactPostProcessor is a PostProcessor that requires the Artifact being
+// processed, such as to determine the file's name. ProcessArtifact is called
+// in place of Process for matching Artifacts.
+type ArtifactPostProcessor interface {
+	PostProcessor
+
+	// ProcessArtifact receives the rendered artifact a and returns the
+	// processed bytes or an error if something goes wrong.
+	ProcessArtifact(a Artifact, in []byte) ([]byte, error)
+}
@@ -0,0 +1,976 @@
+package pgs
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	maxFieldNumber = 536870911 // the largest valid field number
+	maxEnumNumber  = math.MaxInt32
+)
+
+// ProtoPrinter renders Files back to .proto source. The output includes the
+// File's imports, options (including custom options), reserved ranges and
+// names, and the comments from its SourceCodeInfo. When source locations are
+// available, declarations keep their original order; otherwise they are
+// printed in the order of the descriptor. References to types and custom
+// options use the shortest name that resolves to the same Entity.
+//
+// The rendered source can be emitted from a Module as a GeneratorFile:
+//
+//	src, err := pgs.PrintProto(f)
+//	m.CheckErr(err, "unable to print ", f.Name())
+//	m.AddGeneratorFile(f.Name().String(), src)
+type ProtoPrinter struct {
+	// Filter, if set, is called with each imported File of the printed File,
+	// and each Message, Enum, EnumValue, Field, OneOf, Extension, Service and
+	// Method it contains. Entities for which false is returned are omitted,
+	// along with any Entities they contain. Omitting an Entity that is still
+	// referenced, or an import that is still required, results in source that
+	// does not compile.
+	Filter func(e Entity) bool
+}
+
+// PrintProto renders f to .proto source with a ProtoPrinter that includes all
+// of the File's Entities.
+func PrintProto(f File) (string, error) { return ProtoPrinter{}.Print(f) }
+
+// Print renders f to .proto source. An error is returned if the File's options
+// cannot be decoded.
+func (pp ProtoPrinter) Print(f File) (string, error) {
+	p := &protoPrinter{
+		ProtoPrinter: pp,
+		f:            f,
+		syntax:       Syntax(f.Descriptor().GetSyntax()),
+		symbols:      map[string]symbolKind{},
+		types:        new(protoregistry.Types),
+	}
+
+	if p.syntax == "proto2" {
+		p.syntax = Proto2
+	}
+
+	for _, fl := range append([]File{f}, f.TransitiveImports()...) {
+		p.addSymbols(fl)
+	}
+
+	p.file()
+	return p.buf.String(), p.err
+}
+
+type symbolKind int
+
+const (
+	symbolPackage symbolKind = iota
+	symbolType
+	symbolService
+	symbolOther
+)
+
+type protoPrinter struct {
+	ProtoPrinter
+
+	f      File
+	syntax Syntax
+
+	// symbols contains the fully qualified names of all symbols visible to f,
+	// which are used to resolve the shortest names of references.
+	symbols map[string]symbolKind
+
+	// types contains the custom options visible to f.
+	types *protoregistry.Types
+
+	buf   bytes.Buffer
+	depth int
+	err   error
+}
+
+// printItem is a declaration within the body of a File or a block. Items with
+// a valid position are printed in source order.
+type printItem struct {
+	pos   Span
+	block bool
+	print func()
+}
+
+func (p *protoPrinter) keep(e Entity) bool { return p.Filter == nil || p.Filter(e) }
+
+func (p *protoPrinter) fail(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+func (p *protoPrinter) addSymbols(f File) {
+	if pkg := f.Descriptor().GetPackage(); pkg != "" {
+		scope := ""
+		for _, part := range strings.Split(pkg, ".") {
+			scope += "." + part
+			p.symbols[scope] = symbolPackage
+		}
+	}
+
+	addExts := func(exts []Extension) {
+		for _, x := range exts {
+			p.symbols[x.FullyQualifiedName()] = symbolOther
+
+			// custom options are decoded using the extensions' definitions in the
+			// AST, so they do not need to be linked into the plugin
+			if xd, ok := x.Reflect().(protoreflect.ExtensionDescriptor); ok && xd != nil {
+				_ = p.types.RegisterExtension(dynamicpb.NewExtensionType(xd))
+			}
+		}
+	}
+
+	addExts(f.DefinedExtensions())
+
+	for _, m := range f.AllMessages() {
+		p.symbols[m.FullyQualifiedName()] = symbolType
+		for _, me := range m.MapEntries() {
+			p.symbols[me.FullyQualifiedName()] = symbolType
+		}
+		for _, fld := range m.Fields() {
+			p.symbols[fld.FullyQualifiedName()] = symbolOther
+		}
+		for _, o := range m.OneOfs() {
+			p.symbols[o.FullyQualifiedName()] = symbolOther
+		}
+		addExts(m.DefinedExtensions())
+	}
+
+	for _, e := range f.AllEnums() {
+		p.symbols[e.FullyQualifiedName()] = symbolType
+
+		// enum values are scoped as siblings of their enum
+		scope := parentScope(e.FullyQualifiedName())
+		for _, v := range e.Values() {
+			p.symbols[scope+"."+v.Name().String()] = symbolOther
+		}
+	}
+
+	for _, s := range f.Services() {
+		p.symbols[s.FullyQualifiedName()] = symbolService
+		for _, m := range s.Methods() {
+			p.symbols[m.FullyQualifiedName()] = symbolOther
+		}
+	}
+}
+
+// parentScope returns the fully qualified name of the scope containing the
+// symbol fqn. The root scope is the empty string.
+func parentScope(fqn string) string {
+	if i := strings.LastIndexByte(fqn, '.'); i > 0 {
+		return fqn[:i]
+	}
+	return ""
+}
+
+// resolve returns the fully qualified name of the symbol that name refers to
+// from within scope, following protoc's scoping rules. If types is true, only
+// Messages and Enums are considered for single part names.
+func (p *protoPrinter) resolve(name, scope string, types bool) string {
+	first := name
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		first = name[:i]
+	}
+
+	for {
+		if kind, ok := p.symbols[scope+"."+first]; ok {
+			if first != name {
+				if kind != symbolOther {
+					return scope + "." + name
+				}
+			} else if !types || kind == symbolType {
+				return scope + "." + name
+			}
+		}
+
+		if scope == "" {
+			return ""
+		}
+		scope = parentScope(scope)
+	}
+}
+
+// ref returns the shortest name that refers to the symbol fqn from scope.
+func (p *protoPrinter) ref(fqn, scope string, types bool) string {
+	parts := strings.Split(strings.TrimPrefix(fqn, "."), ".")
+	for i := len(parts) - 1; i >= 0; i-- {
+		name := strings.Join(parts[i:], ".")
+		if p.resolve(name, scope, types) == fqn {
+			return name
+		}
+	}
+	return fqn
+}
+
+// packageScope returns the scope of the top-level declarations of the File.
+func (p *protoPrinter) packageScope() string {
+	if pkg := p.f.Descriptor().GetPackage(); pkg != "" {
+		return "." + pkg
+	}
+	return ""
+}
+
+// entityScope returns the scope names are resolved from for the declarations
+// within e.
+func (p *protoPrinter) entityScope(e Entity) string {
+	switch e := e.(type) {
+	case File:
+		return p.packageScope()
+	case EnumValue:
+		return parentScope(e.Enum().FullyQualifiedName())
+	default:
+		return parentScope(e.FullyQualifiedName())
+	}
+}
+
+func (p *protoPrinter) write(s string) {
+	p.buf.WriteString(strings.Repeat("  ", p.depth))
+	p.buf.WriteString(s)
+}
+
+func (p *protoPrinter) blank() {
+	if b := p.buf.Bytes(); len(b) > 0 && !bytes.HasSuffix(b, []byte("\n\n")) && !bytes.HasSuffix(b, []byte("{\n")) {
+		p.buf.WriteByte('\n')
+	}
+}
+
+func (p *protoPrinter) comment(text string) {
+	text = strings.TrimSuffix(text, "\n")
+	for _, ln := range strings.Split(text, "\n") {
+		p.write(strings.TrimRight("//"+ln, " \t") + "\n")
+	}
+}
+
+// leading prints the detached and leading comments of info.
+func (p *protoPrinter) leading(info SourceCodeInfo) {
+	if info == nil {
+		return
+	}
+
+	for _, c := range info.LeadingDetachedComments() {
+		p.blank()
+		p.comment(c)
+		p.buf.WriteByte('\n')
+	}
+
+	if c := info.LeadingComments(); c != "" {
+		p.comment(c)
+	}
+}
+
+// trailing ends the current line with the trailing comment of info.
+func (p *protoPrinter) trailing(info SourceCodeInfo) {
+	c := ""
+	if info != nil {
+		c = strings.TrimSuffix(info.TrailingComments(), "\n")
+	}
+
+	switch {
+	case c == "":
+		p.buf.WriteByte('\n')
+	case !strings.Contains(c, "\n"):
+		p.buf.WriteString(strings.TrimRight(" //"+c, " \t") + "\n")
+	default:
+		p.buf.WriteByte('\n')
+		p.depth++
+		p.comment(c)
+		p.depth--
+	}
+}
+
+func (p *protoPrinter) stmt(s string, info SourceCodeInfo) {
+	p.leading(info)
+	p.write(s)
+	p.trailing(info)
+}
+
+func (p *protoPrinter) open(s string, info SourceCodeInfo) {
+	p.leading(info)
+	p.write(s + " {")
+	p.trailing(info)
+	p.depth++
+}
+
+func (p *protoPrinter) close() {
+	p.depth--
+
+	// blank lines separating the last declaration are dropped
+	if bytes.HasSuffix(p.buf.Bytes(), []byte("\n\n")) {
+		p.buf.Truncate(p.buf.Len() - 1)
+	}
+
+	// empty blocks are closed on the same line
+	if bytes.HasSuffix(p.buf.Bytes(), []byte(" {\n")) {
+		p.buf.Truncate(p.buf.Len() - 1)
+		p.buf.WriteString("}\n")
+		return
+	}
+
+	p.write("}\n")
+}
+
+// body prints items, in source order if all of their positions are known.
+// Blocks are separated from the surrounding declarations by a blank line.
+func (p *protoPrinter) body(items []printItem, top bool) {
+	sorted := true
+	for _, it := range items {
+		sorted = sorted && it.pos.Valid()
+	}
+
+	if sorted {
+		sort.SliceStable(items, func(i, j int) bool {
+			a, b := items[i].pos, items[j].pos
+			return a.StartLine < b.StartLine || a.StartLine == b.StartLine && a.StartColumn < b.StartColumn
+		})
+	}
+
+	for i, it := range items {
+		if top || it.block || i > 0 && items[i-1].block {
+			p.blank()
+		}
+		it.print()
+	}
+}
+
+func span(info SourceCodeInfo) Span {
+	if info == nil {
+		return Span{}
+	}
+	return info.Span()
+}
+
+func (p *protoPrinter) file() {
+	f, desc := p.f, p.f.Descriptor()
+
+	if p.syntax == Editions {
+		p.stmt(fmt.Sprintf("edition = %q;", strings.TrimPrefix(desc.GetEdition().String(), "EDITION_")),
+			f.SyntaxSourceCodeInfo())
+	} else if p.syntax == Proto2 {
+		p.stmt(`syntax = "proto2";`, f.SyntaxSourceCodeInfo())
+	} else {
+		p.stmt(fmt.Sprintf("syntax = %q;", p.syntax), f.SyntaxSourceCodeInfo())
+	}
+
+	if pkg := desc.GetPackage(); pkg != "" {
+		p.blank()
+		p.stmt("package "+pkg+";", f.PackageSourceCodeInfo())
+	}
+
+	p.imports()
+	p.options(f, desc.GetOptions(), true)
+
+	var items []printItem
+	for _, m := range f.Messages() {
+		items = p.messageItem(items, m)
+	}
+	for _, e := range f.Enums() {
+		items = p.enumItem(items, e)
+	}
+	for _, s := range f.Services() {
+		items = p.serviceItem(items, s)
+	}
+	items = p.extendItems(items, f.DefinedExtensions())
+
+	p.body(items, true)
+}
+
+func (p *protoPrinter) imports() {
+	desc := p.f.Descriptor()
+
+	imports := map[string]File{}
+	for _, imp := range p.f.Imports() {
+		imports[imp.Name().String()] = imp
+	}
+
+	kinds := map[int32]string{}
+	for _, i := range desc.GetPublicDependency() {
+		kinds[i] = "public "
+	}
+	for _, i := range desc.GetWeakDependency() {
+		kinds[i] = "weak "
+	}
+
+	first := true
+	for i, dep := range desc.GetDependency() {
+		if imp, ok := imports[dep]; ok && !p.keep(imp) {
+			continue
+		}
+
+		if first {
+			p.blank()
+			first = false
+		}
+
+		p.stmt(fmt.Sprintf("import %s%q;", kinds[int32(i)], dep), sourceCodeInfoAt(p.f, dependencyPath, int32(i)))
+	}
+}
+
+func (p *protoPrinter) messageItem(items []printItem, m Message) []printItem {
+	if !p.keep(m) {
+		return items
+	}
+
+	return append(items, printItem{pos: span(m.SourceCodeInfo()), block: true, print: func() {
+		p.open("message "+m.Name().String(), m.SourceCodeInfo())
+		p.messageBody(m)
+		p.close()
+	}})
+}
+
+func (p *protoPrinter) messageBody(m Message) {
+	p.options(m, m.Descriptor().GetOptions(), true)
+
+	var items []printItem
+	for _, nm := range m.Messages() {
+		items = p.messageItem(items, nm)
+	}
+	for _, e := range m.Enums() {
+		items = p.enumItem(items, e)
+	}
+
+	oneofs := map[string]bool{}
+	for _, fld := range m.Fields() {
+		if !fld.InRealOneOf() {
+			items = p.fieldItem(items, fld)
+			continue
+		}
+
+		o := fld.OneOf()
+		if oneofs[o.FullyQualifiedName()] || !p.keep(o) {
+			continue
+		}
+		oneofs[o.FullyQualifiedName()] = true
+
+		var fields []printItem
+		for _, of := range o.Fields() {
+			fields = p.fieldItem(fields, of)
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		items = append(items, printItem{pos: span(o.SourceCodeInfo()), block: true, print: func() {
+			p.open("oneof "+o.Name().String(), o.SourceCodeInfo())
+			p.options(o, o.Descriptor().GetOptions(), true)
+			p.body(fields, false)
+			p.close()
+		}})
+	}
+
+	for i, r := range m.ExtensionRanges() {
+		i, r := i, r
+		info := sourceCodeInfoAt(m, messageTypeExtRangePath, int32(i))
+		items = append(items, printItem{pos: span(info), print: func() {
+			var comments SourceCodeInfo
+			if i == 0 {
+				comments = sourceCodeInfoAt(m, messageTypeExtRangePath)
+			}
+
+			s := "extensions " + formatRange(r.Start, r.End, maxFieldNumber)
+			if opts := p.optionEntries(m.FullyQualifiedName(), r.Options); len(opts) > 0 {
+				s += " [" + strings.Join(opts, ", ") + "]"
+			}
+			p.stmt(s+";", comments)
+		}})
+	}
+
+	items = p.reservedItems(items, m, m.ReservedRanges(), m.ReservedNames(), maxFieldNumber,
+		messageTypeReservedRangePath, messageTypeReservedNamePath)
+	items = p.extendItems(items, m.DefinedExtensions())
+
+	p.body(items, false)
+}
+
+func (p *protoPrinter) reservedItems(items []printItem, e Entity, ranges []ReservedRange, names []string,
+	max int32, rangePath, namePath int32) []printItem {
+	if len(ranges) > 0 {
+		info := sourceCodeInfoAt(e, rangePath)
+		items = append(items, printItem{pos: span(info), print: func() {
+			rs := make([]string, len(ranges))
+			for i, r := range ranges {
+				rs[i] = formatRange(r.Start, r.End, max)
+			}
+			p.stmt("reserved "+strings.Join(rs, ", ")+";", info)
+		}})
+	}
+
+	if len(names) > 0 {
+		info := sourceCodeInfoAt(e, namePath)
+		items = append(items, printItem{pos: span(info), print: func() {
+			ns := make([]string, len(names))
+			for i, n := range names {
+				ns[i] = strconv.Quote(n)
+				if p.syntax == Editions {
+					ns[i] = n
+				}
+			}
+			p.stmt("reserved "+strings.Join(ns, ", ")+";", info)
+		}})
+	}
+
+	return items
+}
+
+func formatRange(start, end, max int32) string {
+	switch {
+	case start == end:
+		return strconv.Itoa(int(start))
+	case end == max:
+		return fmt.Sprintf("%d to max", start)
+	default:
+		return fmt.Sprintf("%d to %d", start, end)
+	}
+}
+
+// extendItems adds an extend block for each run of consecutive Extensions with
+// the same extendee.
+func (p *protoPrinter) extendItems(items []printItem, exts []Extension) []printItem {
+	var kept []Extension
+	for _, x := range exts {
+		if p.keep(x) {
+			kept = append(kept, x)
+		}
+	}
+
+	for len(kept) > 0 {
+		n := 1
+		extendee := kept[0].Descriptor().GetExtendee()
+		for n < len(kept) && kept[n].Descriptor().GetExtendee() == extendee {
+			n++
+		}
+
+		run := kept[:n]
+		kept = kept[n:]
+
+		items = append(items, printItem{pos: span(run[0].SourceCodeInfo()), block: true, print: func() {
+			p.open("extend "+p.ref(extendee, p.entityScope(run[0]), true), nil)
+
+			var fields []printItem
+			for _, x := range run {
+				fields = p.fieldItem(fields, x)
+			}
+			p.body(fields, false)
+
+			p.close()
+		}})
+	}
+
+	return items
+}
+
+func (p *protoPrinter) fieldItem(items []printItem, f Field) []printItem {
+	if !p.keep(f) {
+		return items
+	}
+
+	return append(items, printItem{pos: span(f.SourceCodeInfo()), print: func() {
+		desc := f.Descriptor()
+		p.stmt(fmt.Sprintf("%s%s %s = %d%s;",
+			p.label(f), p.fieldType(f), desc.GetName(), desc.GetNumber(), p.fieldOptions(f)),
+			f.SourceCodeInfo())
+	}})
+}
+
+func (p *protoPrinter) label(f Field) string {
+	desc := f.Descriptor()
+
+	switch {
+	case f.Type().IsMap() || f.InRealOneOf():
+		return ""
+	case desc.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED:
+		return "repeated "
+	case p.syntax == Proto2 && desc.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REQUIRED:
+		return "required "
+	case p.syntax == Proto2, desc.GetProto3Optional():
+		return "optional "
+	default:
+		return ""
+	}
+}
+
+func (p *protoPrinter) fieldType(f Field) string {
+	if f.Type().IsMap() {
+		for _, me := range f.Message().MapEntries() {
+			if me.FullyQualifiedName() != f.Descriptor().GetTypeName() || len(me.Fields()) != 2 {
+				continue
+			}
+			return fmt.Sprintf("map<%s, %s>",
+				p.typeName(f, me.Fields()[0].Descriptor()), p.typeName(f, me.Fields()[1].Descriptor()))
+		}
+	}
+
+	return p.typeName(f, f.Descriptor())
+}
+
+// typeName returns the name of the type of desc, referenced from the Field f.
+func (p *protoPrinter) typeName(f Field, desc *descriptor.FieldDescriptorProto) string {
+	if desc.GetTypeName() != "" {
+		return p.ref(desc.GetTypeName(), p.entityScope(f), true)
+	}
+	return strings.ToLower(strings.TrimPrefix(desc.GetType().String(), "TYPE_"))
+}
+
+func (p *protoPrinter) fieldOptions(f Field) string {
+	desc := f.Descriptor()
+
+	var opts []string
+	if desc.DefaultValue != nil {
+		opts = append(opts, "default = "+p.formatDefault(desc))
+	}
+
+	if _, isExt := f.(Extension); !isExt && desc.JsonName != nil && desc.GetJsonName() != jsonCamelCase(desc.GetName()) {
+		opts = append(opts, "json_name = "+strconv.Quote(desc.GetJsonName()))
+	}
+
+	opts = append(opts, p.optionEntries(p.optionScope(f), desc.GetOptions())...)
+	if len(opts) == 0 {
+		return ""
+	}
+
+	return " [" + strings.Join(opts, ", ") + "]"
+}
+
+func (p *protoPrinter) formatDefault(desc *descriptor.FieldDescriptorProto) string {
+	switch desc.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_STRING:
+		return quoteProto([]byte(desc.GetDefaultValue()), false)
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		// bytes defaults are already escaped
+		return `"` + desc.GetDefaultValue() + `"`
+	default:
+		return desc.GetDefaultValue()
+	}
+}
+
+func (p *protoPrinter) enumItem(items []printItem, e Enum) []printItem {
+	if !p.keep(e) {
+		return items
+	}
+
+	return append(items, printItem{pos: span(e.SourceCodeInfo()), block: true, print: func() {
+		p.open("enum "+e.Name().String(), e.SourceCodeInfo())
+		p.options(e, e.Descriptor().GetOptions(), true)
+
+		var values []printItem
+		for _, v := range e.Values() {
+			v := v
+			if !p.keep(v) {
+				continue
+			}
+
+			values = append(values, printItem{pos: span(v.SourceCodeInfo()), print: func() {
+				s := fmt.Sprintf("%s = %d", v.Name(), v.Value())
+				if opts := p.optionEntries(p.optionScope(v), v.Descriptor().GetOptions()); len(opts) > 0 {
+					s += " [" + strings.Join(opts, ", ") + "]"
+				}
+				p.stmt(s+";", v.SourceCodeInfo())
+			}})
+		}
+
+		values = p.reservedItems(values, e, e.ReservedRanges(), e.ReservedNames(), maxEnumNumber,
+			enumTypeReservedRangePath, enumTypeReservedNamePath)
+
+		p.body(values, false)
+		p.close()
+	}})
+}
+
+func (p *protoPrinter) serviceItem(items []printItem, s Service) []printItem {
+	if !p.keep(s) {
+		return items
+	}
+
+	return append(items, printItem{pos: span(s.SourceCodeInfo()), block: true, print: func() {
+		p.open("service "+s.Name().String(), s.SourceCodeInfo())
+		p.options(s, s.Descriptor().GetOptions(), true)
+
+		var methods []printItem
+		for _, m := range s.Methods() {
+			m := m
+			if !p.keep(m) {
+				continue
+			}
+
+			opts := p.options(m, m.Descriptor().GetOptions(), false)
+			methods = append(methods, printItem{pos: span(m.SourceCodeInfo()), block: opts, print: func() {
+				scope := p.entityScope(m)
+				sig := fmt.Sprintf("rpc %s(%s%s) returns (%s%s)", m.Name(),
+					streaming(m.ClientStreaming()), p.ref(m.Descriptor().GetInputType(), scope, true),
+					streaming(m.ServerStreaming()), p.ref(m.Descriptor().GetOutputType(), scope, true))
+
+				if !opts {
+					p.stmt(sig+";", m.SourceCodeInfo())
+					return
+				}
+
+				p.open(sig, m.SourceCodeInfo())
+				p.options(m, m.Descriptor().GetOptions(), true)
+				p.close()
+			}})
+		}
+
+		p.body(methods, false)
+		p.close()
+	}})
+}
+
+func streaming(b bool) string {
+	if b {
+		return "stream "
+	}
+	return ""
+}
+
+// optionValue is a single value of an option set on an Entity.
+type optionValue struct {
+	fd protoreflect.FieldDescriptor
+	v  protoreflect.Value
+}
+
+// decodeOptions returns the options set in opts, sorted by field number, with
+// custom options decoded using the Extensions visible to the File.
+func (p *protoPrinter) decodeOptions(opts proto.Message) []optionValue {
+	if opts == nil || reflect.ValueOf(opts).IsNil() {
+		return nil
+	}
+
+	b, err := proto.Marshal(opts)
+	if err != nil {
+		p.fail(err)
+		return nil
+	}
+
+	m := dynamicpb.NewMessage(opts.ProtoReflect().Descriptor())
+	if err = (proto.UnmarshalOptions{Resolver: p.types}).Unmarshal(b, m); err != nil {
+		p.fail(fmt.Errorf("unable to decode %s: %v", m.Descriptor().FullName(), err))
+		return nil
+	}
+
+	var out []optionValue
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Name() != "uninterpreted_option" {
+			out = append(out, optionValue{fd, v})
+		}
+		return true
+	})
+
+	sort.Slice(out, func(i, j int) bool { return out[i].fd.Number() < out[j].fd.Number() })
+	return out
+}
+
+// options prints the options set in opts as option statements within the
+// declaration of e. It returns true if any options are set; if print is
+// false, the options are not printed.
+func (p *protoPrinter) options(e Entity, opts proto.Message, print bool) bool {
+	vals := p.decodeOptions(opts)
+	if !print || len(vals) == 0 {
+		return len(vals) > 0
+	}
+
+	p.blank()
+	for _, o := range vals {
+		name := p.optionName(p.optionScope(e), o.fd)
+		info := e.OptionSourceCodeInfo(int32(o.fd.Number()))
+
+		if !o.fd.IsList() {
+			p.stmt(fmt.Sprintf("option %s = %s;", name, p.formatValue(o.fd, o.v)), info)
+			continue
+		}
+
+		list := o.v.List()
+		for i := 0; i < list.Len(); i++ {
+			p.stmt(fmt.Sprintf("option %s = %s;", name, p.formatValue(o.fd, list.Get(i))), info)
+			info = nil
+		}
+	}
+
+	p.blank()
+	return true
+}
+
+// optionEntries returns the options set in opts in the compact form used
+// within brackets, with their names resolved from scope.
+func (p *protoPrinter) optionEntries(scope string, opts proto.Message) []string {
+	var out []string
+	for _, o := range p.decodeOptions(opts) {
+		name := p.optionName(scope, o.fd)
+
+		if !o.fd.IsList() {
+			out = append(out, fmt.Sprintf("%s = %s", name, p.formatValue(o.fd, o.v)))
+			continue
+		}
+
+		list := o.v.List()
+		for i := 0; i < list.Len(); i++ {
+			out = append(out, fmt.Sprintf("%s = %s", name, p.formatValue(o.fd, list.Get(i))))
+		}
+	}
+	return out
+}
+
+// optionName returns the name of the option fd as referred to from scope.
+func (p *protoPrinter) optionName(scope string, fd protoreflect.FieldDescriptor) string {
+	if !fd.IsExtension() {
+		return string(fd.Name())
+	}
+	return "(" + p.ref("."+string(fd.FullName()), scope, false) + ")"
+}
+
+// optionScope returns the scope the names of e's options are resolved from.
+// Like protoc, this is the scope enclosing the declaration's own name: the
+// options of a Message resolve from its parent, while those of its Fields,
+// OneOfs and Extensions resolve from the Message itself. EnumValues are scoped
+// as siblings of their Enum, so their options resolve from the Enum's parent.
+// File options resolve from the package, and the options of extension ranges
+// from their Message.
+func (p *protoPrinter) optionScope(e Entity) string {
+	switch e := e.(type) {
+	case File:
+		return p.packageScope()
+	case EnumValue:
+		return parentScope(e.Enum().FullyQualifiedName())
+	default:
+		return parentScope(e.FullyQualifiedName())
+	}
+}
+
+// formatValue formats a single (non-list) value of fd, using the text format
+// for message values.
+func (p *protoPrinter) formatValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return p.formatMessage(v.Message())
+	case protoreflect.BoolKind:
+		return strconv.FormatBool(v.Bool())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return strconv.Itoa(int(v.Enum()))
+	case protoreflect.StringKind:
+		return quoteProto([]byte(v.String()), false)
+	case protoreflect.BytesKind:
+		return quoteProto(v.Bytes(), true)
+	case protoreflect.FloatKind:
+		return formatFloat(v.Float(), 32)
+	case protoreflect.DoubleKind:
+		return formatFloat(v.Float(), 64)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+func (p *protoPrinter) formatMessage(m protoreflect.Message) string {
+	var fields []optionValue
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		fields = append(fields, optionValue{fd, v})
+		return true
+	})
+
+	if len(fields) == 0 {
+		return "{}"
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].fd.Number() < fields[j].fd.Number() })
+
+	parts := make([]string, 0, len(fields))
+	for _, f := range fields {
+		parts = append(parts, textName(f.fd)+": "+p.formatTextValue(f.fd, f.v))
+	}
+
+	return "{ " + strings.Join(parts, " ") + " }"
+}
+
+func (p *protoPrinter) formatTextValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch {
+	case fd.IsMap():
+		type entry struct{ k, v string }
+
+		var entries []entry
+		v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+			entries = append(entries, entry{
+				p.formatValue(fd.MapKey(), k.Value()),
+				p.formatValue(fd.MapValue(), mv),
+			})
+			return true
+		})
+		sort.Slice(entries, func(i, j int) bool { return entries[i].k < entries[j].k })
+
+		out := make([]string, len(entries))
+		for i, e := range entries {
+			out[i] = fmt.Sprintf("{ key: %s value: %s }", e.k, e.v)
+		}
+		return "[" + strings.Join(out, ", ") + "]"
+	case fd.IsList():
+		list := v.List()
+		out := make([]string, list.Len())
+		for i := range out {
+			out[i] = p.formatValue(fd, list.Get(i))
+		}
+		return "[" + strings.Join(out, ", ") + "]"
+	default:
+		return p.formatValue(fd, v)
+	}
+}
+
+// textName returns the name of fd in the text format.
+func textName(fd protoreflect.FieldDescriptor) string {
+	if fd.IsExtension() {
+		return "[" + string(fd.FullName()) + "]"
+	}
+	return string(fd.Name())
+}
+
+func formatFloat(f float64, bits int) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, bits)
+	}
+}
+
+// quoteProto returns b as a quoted string literal. Non-printable bytes are
+// octal escaped, as are non-ASCII bytes if escapeHigh is true.
+func quoteProto(b []byte, escapeHigh bool) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, c := range b {
+		switch {
+		case c == '"':
+			sb.WriteString(`\"`)
+		case c == '\\':
+			sb.WriteString(`\\`)
+		case c == '\n':
+			sb.WriteString(`\n`)
+		case c == '\r':
+			sb.WriteString(`\r`)
+		case c == '\t':
+			sb.WriteString(`\t`)
+		case c < 0x20 || c == 0x7f || escapeHigh && c >= 0x80:
+			fmt.Fprintf(&sb, `\%03o`, c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,215 @@
+package pgs
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestPrintProto_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	protoc, err := exec.LookPath("protoc")
+	if err != nil {
+		t.Skip("no protoc in PATH: ", err)
+	}
+
+	dirs := []string{
+		"defaults", "extensions", "info", "locations", "messages",
+		"nested", "packageless", "printer", "prune", "recursion", "references", "services",
+	}
+
+	for _, dir := range dirs {
+		dir := dir
+		t.Run(dir, func(t *testing.T) {
+			t.Parallel()
+
+			req := readCodeGenReq(t, dir)
+			ast := buildGraph(t, dir)
+
+			tmp, err := ioutil.TempDir("", "pgs-printer")
+			require.NoError(t, err)
+			defer os.RemoveAll(tmp)
+
+			targets := req.GetFileToGenerate()
+			for _, name := range targets {
+				src, err := PrintProto(ast.Targets()[name])
+				require.NoError(t, err, name)
+
+				path := filepath.Join(tmp, filepath.FromSlash(name))
+				require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+				require.NoError(t, ioutil.WriteFile(path, []byte(src), 0644))
+			}
+
+			out := filepath.Join(tmp, "fdset.bin")
+			cmd := exec.Command(protoc, append([]string{"-I", ".", "-o", out, "--include_imports"}, targets...)...)
+			cmd.Dir = tmp
+			b, err := cmd.CombinedOutput()
+			require.NoError(t, err, string(b))
+
+			b, err = ioutil.ReadFile(out)
+			require.NoError(t, err)
+
+			fdset := new(descriptor.FileDescriptorSet)
+			require.NoError(t, proto.Unmarshal(b, fdset))
+
+			normalize := optionNormalizer(t, fdset)
+
+			printed := map[string]*descriptor.FileDescriptorProto{}
+			for _, fd := range fdset.GetFile() {
+				printed[fd.GetName()] = normalize(fd)
+			}
+
+			for _, fd := range req.GetProtoFile() {
+				if !containsString(targets, fd.GetName()) {
+					continue
+				}
+
+				expected := normalize(fd)
+				expected.SourceCodeInfo = nil
+
+				if !proto.Equal(expected, printed[fd.GetName()]) {
+					diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+						A:       difflib.SplitLines(prototext.Format(expected)),
+						B:       difflib.SplitLines(prototext.Format(printed[fd.GetName()])),
+						Context: 3,
+					})
+					t.Errorf("%s does not match after printing:\n%s", fd.GetName(), diff)
+				}
+			}
+		})
+	}
+}
+
+func TestPrintProto(t *testing.T) {
+	t.Parallel()
+
+	ast := buildGraph(t, "printer")
+	src, err := PrintProto(ast.Targets()["printer/printer.proto"])
+	require.NoError(t, err)
+
+	for _, s := range []string{
+		"// Copyright notice, detached from the syntax statement.\n\n// The syntax statement.\nsyntax = \"proto2\";\n",
+		"import public \"printer/options.proto\";\n",
+		`option (opts.file_label) = "file \"label\"\n\001";`,
+		"  option (opts.msg_codes) = 1;\n  option (opts.msg_codes) = 2;\n",
+		"  message Timestamp {}\n",
+		"    optional Timestamp local = 1;\n    optional google.protobuf.Timestamp remote = 2;\n",
+		"    Inner inner = 3; // trailing\n",
+		"  extensions 100 to 199 [(opts.range_label) = \"low\"];\n",
+		"  reserved 10 to 20, 30;\n",
+		"    option (opts.method_kind) = KIND_STRICT;\n  }\n",
+
+		// option names are resolved from the scope of each declaration, which
+		// differs between a message and its contents
+		"  option (opts.msg_codes) = 3;\n",
+		"  option (Scoped.scoped_label) = \"own\";\n",
+		"    option (scoped_label) = \"child\";\n",
+		"  optional string value = 1 [(printer.opts.field_rule) = { min: 1 }, (scoped_flag) = true];\n",
+		"  extensions 100 to 199 [(printer.opts.range_label) = \"scoped\"];\n",
+	} {
+		assert.Contains(t, src, s)
+	}
+}
+
+func TestProtoPrinter_Filter(t *testing.T) {
+	t.Parallel()
+
+	ast := buildGraph(t, "printer")
+	pp := ProtoPrinter{Filter: func(e Entity) bool {
+		switch e.Name().String() {
+		case "Inner", "items", "Unary", "google/protobuf/timestamp.proto":
+			return false
+		default:
+			return true
+		}
+	}}
+
+	src, err := pp.Print(ast.Targets()["printer/printer.proto"])
+	require.NoError(t, err)
+
+	assert.NotContains(t, src, "message Inner")
+	assert.NotContains(t, src, "repeated Inner items = 2;")
+	assert.NotContains(t, src, "rpc Unary")
+	assert.NotContains(t, src, "timestamp.proto")
+	assert.Contains(t, src, "message Outer {")
+	assert.Contains(t, src, "rpc Stream")
+	assert.Contains(t, src, "import public \"printer/options.proto\";")
+}
+
+func TestFormatRange(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "5", formatRange(5, 5, maxFieldNumber))
+	assert.Equal(t, "5 to 10", formatRange(5, 10, maxFieldNumber))
+	assert.Equal(t, "5 to max", formatRange(5, maxFieldNumber, maxFieldNumber))
+	assert.Equal(t, "5 to max", formatRange(5, maxEnumNumber, maxEnumNumber))
+}
+
+func TestFormatFloat(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "inf", formatFloat(math.Inf(1), 64))
+	assert.Equal(t, "-inf", formatFloat(math.Inf(-1), 64))
+	assert.Equal(t, "nan", formatFloat(math.NaN(), 64))
+	assert.Equal(t, "1.5", formatFloat(1.5, 64))
+	assert.Equal(t, "0.1", formatFloat(float64(float32(0.1)), 32))
+}
+
+func TestQuoteProto(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `"a\"b\\c\n\t"`, quoteProto([]byte("a\"b\\c\n\t"), false))
+	assert.Equal(t, `"\000\177"`, quoteProto([]byte{0, 0x7f}, false))
+	assert.Equal(t, `"é"`, quoteProto([]byte("é"), false))
+	assert.Equal(t, `"\303\251"`, quoteProto([]byte("é"), true))
+	assert.Equal(t, `""`, quoteProto(nil, true))
+}
+
+// optionNormalizer returns a function that decodes the custom options of a
+// FileDescriptorProto, using the extensions in fdset. This allows comparing
+// options regardless of the order in which they were encoded.
+func optionNormalizer(t *testing.T, fdset *descriptor.FileDescriptorSet) func(*descriptor.FileDescriptorProto) *descriptor.FileDescriptorProto {
+	files, err := protodesc.NewFiles(fdset)
+	require.NoError(t, err)
+
+	types := new(protoregistry.Types)
+
+	var register func(xds protoreflect.ExtensionDescriptors, msgs protoreflect.MessageDescriptors)
+	register = func(xds protoreflect.ExtensionDescriptors, msgs protoreflect.MessageDescriptors) {
+		for i := 0; i < xds.Len(); i++ {
+			require.NoError(t, types.RegisterExtension(dynamicpb.NewExtensionType(xds.Get(i))))
+		}
+		for i := 0; i < msgs.Len(); i++ {
+			register(msgs.Get(i).Extensions(), msgs.Get(i).Messages())
+		}
+	}
+
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		register(fd.Extensions(), fd.Messages())
+		return true
+	})
+
+	return func(fd *descriptor.FileDescriptorProto) *descriptor.FileDescriptorProto {
+		b, err := proto.Marshal(fd)
+		require.NoError(t, err)
+
+		out := new(descriptor.FileDescriptorProto)
+		require.NoError(t, proto.UnmarshalOptions{Resolver: types}.Unmarshal(b, out))
+		return out
+	}
+}
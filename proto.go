@@ -18,6 +18,12 @@ const (
 	// Most of the field types in the generated go structs are value types.
 	// See: https://github.com/protocolbuffers/protobuf/blob/v3.17.0/docs/field_presence.md#presence-in-proto3-apis
 	Proto3 Syntax = "proto3"
+
+	// Editions syntax replaces the proto2/proto3 distinction with a set of
+	// features resolved per entity. See the Edition and Features methods on
+	// File and Entity, respectively.
+	// See: https://protobuf.dev/editions/overview/
+	Editions Syntax = "editions"
 )
 
 // SupportsRequiredPrefix returns true if s supports "optional" and
@@ -30,6 +36,45 @@ func (s Syntax) String() string {
 	return string(s)
 }
 
+// Edition wraps the Edition enum for better readability. It is a 1-to-1
+// conversion.
+type Edition descriptor.Edition
+
+const (
+	// EditionUnknown is the zero value for an Edition and indicates the edition
+	// could not be determined.
+	EditionUnknown = Edition(descriptor.Edition_EDITION_UNKNOWN)
+
+	// EditionProto2 is the legacy edition used by files with Proto2 syntax.
+	EditionProto2 = Edition(descriptor.Edition_EDITION_PROTO2)
+
+	// EditionProto3 is the legacy edition used by files with Proto3 syntax.
+	EditionProto3 = Edition(descriptor.Edition_EDITION_PROTO3)
+
+	// Edition2023 is the first edition released with Editions syntax.
+	Edition2023 = Edition(descriptor.Edition_EDITION_2023)
+
+	// Edition2024 is the edition released after Edition2023.
+	Edition2024 = Edition(descriptor.Edition_EDITION_2024)
+)
+
+// Proto returns the Edition enum value for this Edition. This method is
+// exclusively used to improve readability without having to switch the types.
+func (e Edition) Proto() descriptor.Edition {
+	return descriptor.Edition(e)
+}
+
+// ProtoPtr returns a pointer to the Edition enum value for this Edition.
+func (e Edition) ProtoPtr() *descriptor.Edition {
+	ed := e.Proto()
+	return &ed
+}
+
+// String returns a string representation of the edition.
+func (e Edition) String() string {
+	return e.Proto().String()
+}
+
 // ProtoLabel wraps the FieldDescriptorProto_Label enum for better readability.
 // It is a 1-to-1 conversion.
 type ProtoLabel descriptor.FieldDescriptorProto_Label
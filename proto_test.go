@@ -11,6 +11,7 @@ func TestSyntax_SupportsRequiredPrefix(t *testing.T) {
 	t.Parallel()
 	assert.True(t, Proto2.SupportsRequiredPrefix())
 	assert.False(t, Proto3.SupportsRequiredPrefix())
+	assert.False(t, Editions.SupportsRequiredPrefix())
 }
 
 func TestSyntax_String(t *testing.T) {
@@ -80,6 +81,22 @@ func TestProtoType_String(t *testing.T) {
 	assert.Equal(t, DoubleT.String(), "TYPE_DOUBLE")
 }
 
+func TestEdition_Proto(t *testing.T) {
+	t.Parallel()
+
+	e := Edition2023.Proto()
+	ePtr := Edition2023.ProtoPtr()
+
+	assert.Equal(t, descriptor.Edition_EDITION_2023, e)
+	assert.Equal(t, e, *ePtr)
+}
+
+func TestEdition_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, Edition2023.String(), "EDITION_2023")
+}
+
 func TestProtoLabel_Proto(t *testing.T) {
 	t.Parallel()
 
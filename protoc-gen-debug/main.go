@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/pluginpb"
 
 	"google.golang.org/protobuf/proto"
@@ -43,10 +44,13 @@ func main() {
 		log.Fatal("unable to write request to disk: ", err)
 	}
 
-	// protoc-gen-debug supports proto3 field presence for testing purposes
-	var supportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+	// protoc-gen-debug supports proto3 field presence and editions for testing purposes
+	var supportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL |
+		pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS)
 	if data, err = proto.Marshal(&plugin_go.CodeGeneratorResponse{
 		SupportedFeatures: &supportedFeatures,
+		MinimumEdition:    proto.Int32(int32(descriptorpb.Edition_EDITION_PROTO2)),
+		MaximumEdition:    proto.Int32(int32(descriptorpb.Edition_EDITION_2023)),
 	}); err != nil {
 		log.Fatal("unable to marshal response payload: ", err)
 	}
@@ -0,0 +1,508 @@
+package pgs
+
+import (
+	"fmt"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func (g *graph) Prune(roots []Entity, keep func(Entity) bool) (*descriptor.FileDescriptorSet, error) {
+	p := &pruner{
+		g:      g,
+		keep:   keep,
+		seen:   map[string]bool{},
+		shells: map[string]bool{},
+		deps:   map[string]map[string]bool{},
+		exts:   make(map[string]Extension, len(g.extensions)),
+	}
+
+	for _, ext := range g.extensions {
+		p.exts[extensionKey(ext.Descriptor().GetExtendee(), protowire.Number(ext.Descriptor().GetNumber()))] = ext
+	}
+
+	for _, r := range roots {
+		switch r := r.(type) {
+		case File:
+			p.all(r)
+		case Service:
+			if p.include(r) {
+				for _, mtd := range r.Methods() {
+					p.include(mtd)
+				}
+			}
+		case Message, Enum, Extension, Method:
+			p.include(r)
+		default:
+			return nil, fmt.Errorf("unsupported prune root %s", r.FullyQualifiedName())
+		}
+	}
+
+	for len(p.queue) > 0 && p.err == nil {
+		e := p.queue[0]
+		p.queue = p.queue[1:]
+		p.process(e)
+	}
+
+	if p.err != nil {
+		return nil, p.err
+	}
+
+	fdset := &descriptor.FileDescriptorSet{}
+	for _, f := range TopologicalFiles(g) {
+		if p.seen[f.Name().String()] {
+			fdset.File = append(fdset.File, p.file(f))
+		}
+	}
+
+	if _, err := protodesc.NewFiles(fdset); err != nil {
+		return nil, fmt.Errorf("pruned descriptor set is invalid: %w", err)
+	}
+
+	return fdset, nil
+}
+
+// pruner computes the closure of the Entities required by a set of roots.
+type pruner struct {
+	g    *graph
+	keep func(Entity) bool
+	err  error
+
+	// seen contains the names (as keyed in the graph) of the Files and Entities
+	// included in the closure, and queue those not yet processed.
+	seen  map[string]bool
+	queue []Entity
+
+	// shells contains the names of the Messages retained only as namespaces
+	// for the nested Entities in the closure. Of a shell, only its name and
+	// retained nested Entities are kept.
+	shells map[string]bool
+
+	// deps maps the name of each File to the names of the Files it refers to.
+	deps map[string]map[string]bool
+
+	// exts indexes the AST's Extensions by their extendee and number.
+	exts map[string]Extension
+}
+
+func extensionKey(extendee string, n protowire.Number) string {
+	return fmt.Sprintf("%s:%d", extendee, n)
+}
+
+// all includes f and all of its Entities.
+func (p *pruner) all(f File) {
+	p.include(f)
+
+	for _, m := range f.AllMessages() {
+		p.include(m)
+		for _, ext := range m.DefinedExtensions() {
+			p.include(ext)
+		}
+	}
+
+	for _, e := range f.AllEnums() {
+		p.include(e)
+	}
+
+	for _, ext := range f.DefinedExtensions() {
+		p.include(ext)
+	}
+
+	for _, svc := range f.Services() {
+		if p.include(svc) {
+			for _, mtd := range svc.Methods() {
+				p.include(mtd)
+			}
+		}
+	}
+}
+
+// include adds e to the closure, unless it is rejected by the predicate.
+// Files are never passed to the predicate.
+func (p *pruner) include(e Entity) bool {
+	if _, ok := e.(File); !ok && p.keep != nil && !p.keep(e) {
+		return false
+	}
+	p.add(e)
+	return true
+}
+
+// require adds e to the closure as a dependency of from. It is an error if e
+// is rejected by the predicate.
+func (p *pruner) require(from, e Entity) {
+	if e == nil {
+		return
+	}
+
+	if f, t := from.File().Name().String(), e.File().Name().String(); f != t {
+		if p.deps[f] == nil {
+			p.deps[f] = map[string]bool{}
+		}
+		p.deps[f][t] = true
+	}
+
+	if p.seen[p.g.resolveFQN(e)] {
+		return
+	}
+
+	if !p.include(e) && p.err == nil {
+		p.err = fmt.Errorf("%s requires %s, which is excluded", from.FullyQualifiedName(), e.FullyQualifiedName())
+	}
+}
+
+// namespace retains m, the Message containing e, as a shell. It is an error if
+// m is rejected by the predicate.
+func (p *pruner) namespace(e Entity, m Message) {
+	key := p.g.resolveFQN(m)
+	if p.seen[key] || p.shells[key] {
+		return
+	}
+
+	if p.keep != nil && !p.keep(m) {
+		if p.err == nil {
+			p.err = fmt.Errorf("%s requires %s, which is excluded", e.FullyQualifiedName(), m.FullyQualifiedName())
+		}
+		return
+	}
+
+	p.shells[key] = true
+	if parent, ok := m.Parent().(Message); ok {
+		p.namespace(m, parent)
+	}
+}
+
+// retained returns true if the Entity with the name fqn is in the closure,
+// either in full or as a shell.
+func (p *pruner) retained(fqn string) bool { return p.seen[fqn] || p.shells[fqn] }
+
+func (p *pruner) add(e Entity) {
+	key := p.g.resolveFQN(e)
+	if p.seen[key] {
+		return
+	}
+	p.seen[key] = true
+	p.queue = append(p.queue, e)
+
+	if _, ok := e.(File); !ok {
+		p.add(e.File())
+	}
+}
+
+// lookup returns the Entity with the fully qualified name fqn, or nil if there
+// is none.
+func (p *pruner) lookup(fqn string) Entity {
+	if fqn == "" {
+		return nil
+	}
+	return p.g.entities[fqn]
+}
+
+func (p *pruner) process(e Entity) {
+	switch e := e.(type) {
+	case File:
+		p.options(e, e.Descriptor().GetOptions())
+	case Message:
+		if parent, ok := e.Parent().(Message); ok {
+			p.namespace(e, parent)
+		}
+		for _, fld := range e.Fields() {
+			p.include(fld)
+		}
+		p.options(e, e.Descriptor().GetOptions())
+		for _, rng := range e.Descriptor().GetExtensionRange() {
+			p.options(e, rng.GetOptions())
+		}
+	case Extension:
+		if parent, ok := e.DefinedIn().(Message); ok {
+			p.namespace(e, parent)
+		}
+		p.require(e, p.lookup(e.Descriptor().GetExtendee()))
+		p.require(e, p.lookup(e.Descriptor().GetTypeName()))
+		p.options(e, e.Descriptor().GetOptions())
+	case Field:
+		p.require(e, p.lookup(e.Descriptor().GetTypeName()))
+		if e.InOneOf() {
+			// OneOfs are kept for as long as any of their Fields are
+			p.add(e.OneOf())
+		}
+		p.options(e, e.Descriptor().GetOptions())
+	case OneOf:
+		p.options(e, e.Descriptor().GetOptions())
+	case Enum:
+		if parent, ok := e.Parent().(Message); ok {
+			p.namespace(e, parent)
+		}
+		for _, val := range e.Values() {
+			p.include(val)
+		}
+		p.options(e, e.Descriptor().GetOptions())
+	case EnumValue:
+		p.options(e, e.Descriptor().GetOptions())
+	case Service:
+		p.options(e, e.Descriptor().GetOptions())
+	case Method:
+		p.require(e, e.Service())
+		p.require(e, p.lookup(e.Descriptor().GetInputType()))
+		p.require(e, p.lookup(e.Descriptor().GetOutputType()))
+		p.options(e, e.Descriptor().GetOptions())
+	}
+}
+
+// options requires the Extensions set in opts, the options of e.
+func (p *pruner) options(e Entity, opts proto.Message) {
+	if opts == nil || !opts.ProtoReflect().IsValid() {
+		return
+	}
+
+	b, err := proto.Marshal(opts)
+	if err != nil {
+		p.err = fmt.Errorf("unable to marshal options of %s: %w", e.FullyQualifiedName(), err)
+		return
+	}
+
+	p.scanOptions(e, b, "."+string(opts.ProtoReflect().Descriptor().FullName()))
+}
+
+// scanOptions requires the Extensions set in b, an encoded message of type
+// msg, including those set within its message-typed fields.
+func (p *pruner) scanOptions(e Entity, b []byte, msg string) {
+	m, _ := p.lookup(msg).(Message)
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return
+		}
+		b = b[n:]
+
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return
+		}
+		val := b[:n]
+		b = b[n:]
+
+		var typeName string
+		if ext, ok := p.exts[extensionKey(msg, num)]; ok {
+			p.require(e, ext)
+			typeName = ext.Descriptor().GetTypeName()
+		} else if m != nil {
+			for _, fld := range m.Fields() {
+				if protowire.Number(fld.Descriptor().GetNumber()) == num {
+					typeName = fld.Descriptor().GetTypeName()
+				}
+			}
+		}
+
+		if _, ok := p.lookup(typeName).(Message); ok && typ == protowire.BytesType {
+			inner, _ := protowire.ConsumeBytes(val)
+			p.scanOptions(e, inner, typeName)
+		}
+	}
+}
+
+// file returns a copy of the descriptor of f, retaining only the Entities in
+// the closure and the imports they require. Its SourceCodeInfo is updated to
+// match the retained elements.
+func (p *pruner) file(f File) *descriptor.FileDescriptorProto {
+	fd := proto.Clone(f.Descriptor()).(*descriptor.FileDescriptorProto)
+	paths := map[string][]int32{}
+	fqn := f.FullyQualifiedName()
+
+	fd.MessageType = p.messages(paths, fqn, fd.GetMessageType(), nil, nil, messageTypePath)
+	fd.EnumType = p.enums(paths, fqn, fd.GetEnumType(), nil, nil, enumTypePath)
+	fd.Extension = p.extensions(paths, fqn, fd.GetExtension(), nil, nil, extensionPath)
+
+	var svcs []*descriptor.ServiceDescriptorProto
+	sds := fd.GetService()
+	for _, i := range p.filter(paths, fqn, len(sds), func(i int) string { return sds[i].GetName() }, nil, nil, servicePath) {
+		sd := sds[i]
+		op, np := childPath(nil, servicePath, i), childPath(nil, servicePath, len(svcs))
+		mds := sd.GetMethod()
+
+		var mtds []*descriptor.MethodDescriptorProto
+		for _, j := range p.filter(paths, fqn+"."+sd.GetName(), len(mds), func(j int) string { return mds[j].GetName() },
+			op, np, serviceTypeMethodPath) {
+			mtds = append(mtds, mds[j])
+		}
+		sd.Method = mtds
+
+		svcs = append(svcs, sd)
+	}
+	fd.Service = svcs
+
+	p.dependencies(paths, f.Name().String(), fd)
+
+	if fd.SourceCodeInfo != nil {
+		fd.SourceCodeInfo.Location = remapLocations(fd.GetSourceCodeInfo().GetLocation(), paths)
+	}
+
+	return fd
+}
+
+// dependencies rewrites the imports of fd, the descriptor of the File name,
+// to those required by the Entities in the closure. Retained imports keep
+// their order and public or weak modifiers; imports that were previously
+// provided by a public import are appended in lexical order.
+func (p *pruner) dependencies(paths map[string][]int32, name string, fd *descriptor.FileDescriptorProto) {
+	deps, public, weak := fd.GetDependency(), fd.GetPublicDependency(), fd.GetWeakDependency()
+	fd.Dependency, fd.PublicDependency, fd.WeakDependency = nil, nil, nil
+
+	for i, dep := range deps {
+		op := childPath(nil, dependencyPath, i)
+		if !p.deps[name][dep] {
+			paths[pathKey(op)] = nil
+			continue
+		}
+
+		j := len(fd.Dependency)
+		paths[pathKey(op)] = childPath(nil, dependencyPath, j)
+
+		if indexOf(len(public), func(k int) bool { return public[k] == int32(i) }) >= 0 {
+			fd.PublicDependency = append(fd.PublicDependency, int32(j))
+		}
+		if indexOf(len(weak), func(k int) bool { return weak[k] == int32(i) }) >= 0 {
+			fd.WeakDependency = append(fd.WeakDependency, int32(j))
+		}
+		fd.Dependency = append(fd.Dependency, dep)
+	}
+
+	var extra []string
+	for dep := range p.deps[name] {
+		if !containsString(deps, dep) {
+			extra = append(extra, dep)
+		}
+	}
+	sort.Strings(extra)
+	fd.Dependency = append(fd.Dependency, extra...)
+}
+
+// filter returns the indices of the n elements, with the names returned by
+// name and scoped to parent, that are in the closure. The elements are in the
+// list field of the elements at the old and new paths. The old path of each
+// element is mapped to its new path in paths, or to nil if it is dropped.
+func (p *pruner) filter(paths map[string][]int32, parent string, n int, name func(i int) string,
+	old, new []int32, field int32) []int {
+	var out []int
+	for i := 0; i < n; i++ {
+		op := childPath(old, field, i)
+		if !p.retained(parent + "." + name(i)) {
+			paths[pathKey(op)] = nil
+			continue
+		}
+
+		paths[pathKey(op)] = childPath(new, field, len(out))
+		out = append(out, i)
+	}
+	return out
+}
+
+func (p *pruner) messages(paths map[string][]int32, parent string, mds []*descriptor.DescriptorProto,
+	old, new []int32, field int32) []*descriptor.DescriptorProto {
+	var out []*descriptor.DescriptorProto
+	for _, i := range p.filter(paths, parent, len(mds), func(i int) string { return mds[i].GetName() }, old, new, field) {
+		md := mds[i]
+		op, np := childPath(old, field, i), childPath(new, field, len(out))
+		fqn := parent + "." + md.GetName()
+
+		ods := md.GetOneofDecl()
+		oneofs := map[int32]int32{}
+		md.OneofDecl = nil
+		for _, j := range p.filter(paths, fqn, len(ods), func(j int) string { return ods[j].GetName() },
+			op, np, messageTypeOneofDeclPath) {
+			oneofs[int32(j)] = int32(len(md.OneofDecl))
+			md.OneofDecl = append(md.OneofDecl, ods[j])
+		}
+
+		fds := md.GetField()
+		md.Field = nil
+		for _, j := range p.filter(paths, fqn, len(fds), func(j int) string { return fds[j].GetName() },
+			op, np, messageTypeFieldPath) {
+			fld := fds[j]
+			if fld.OneofIndex != nil {
+				fld.OneofIndex = proto.Int32(oneofs[fld.GetOneofIndex()])
+			}
+			md.Field = append(md.Field, fld)
+		}
+
+		md.NestedType = p.messages(paths, fqn, md.GetNestedType(), op, np, messageTypeNestedTypePath)
+		md.EnumType = p.enums(paths, fqn, md.GetEnumType(), op, np, messageTypeEnumTypePath)
+		md.Extension = p.extensions(paths, fqn, md.GetExtension(), op, np, messageTypeExtensionPath)
+
+		if !p.seen[fqn] {
+			// a shell has no options, ranges or reserved names
+			md.Options, md.ExtensionRange, md.ReservedRange, md.ReservedName = nil, nil, nil, nil
+			for _, f := range []int32{messageTypeOptionsPath, messageTypeExtRangePath,
+				messageTypeReservedRangePath, messageTypeReservedNamePath} {
+				paths[pathKey(append(op[:len(op):len(op)], f))] = nil
+			}
+		}
+
+		out = append(out, md)
+	}
+	return out
+}
+
+func (p *pruner) enums(paths map[string][]int32, parent string, eds []*descriptor.EnumDescriptorProto,
+	old, new []int32, field int32) []*descriptor.EnumDescriptorProto {
+	var out []*descriptor.EnumDescriptorProto
+	for _, i := range p.filter(paths, parent, len(eds), func(i int) string { return eds[i].GetName() }, old, new, field) {
+		ed := eds[i]
+		op, np := childPath(old, field, i), childPath(new, field, len(out))
+		vds := ed.GetValue()
+
+		ed.Value = nil
+		for _, j := range p.filter(paths, parent+"."+ed.GetName(), len(vds), func(j int) string { return vds[j].GetName() },
+			op, np, enumTypeValuePath) {
+			ed.Value = append(ed.Value, vds[j])
+		}
+
+		out = append(out, ed)
+	}
+	return out
+}
+
+func (p *pruner) extensions(paths map[string][]int32, parent string, fds []*descriptor.FieldDescriptorProto,
+	old, new []int32, field int32) []*descriptor.FieldDescriptorProto {
+	var out []*descriptor.FieldDescriptorProto
+	for _, i := range p.filter(paths, parent, len(fds), func(i int) string { return fds[i].GetName() }, old, new, field) {
+		out = append(out, fds[i])
+	}
+	return out
+}
+
+// remapLocations updates the paths of locs using paths, a mapping from the
+// old to the new paths of the elements of a pruned file. The locations of
+// dropped elements, and of anything they contain, are removed.
+func remapLocations(locs []*descriptor.SourceCodeInfo_Location, paths map[string][]int32) []*descriptor.SourceCodeInfo_Location {
+	out := make([]*descriptor.SourceCodeInfo_Location, 0, len(locs))
+
+locations:
+	for _, loc := range locs {
+		path := loc.GetPath()
+
+		for n := len(path); n > 0; n-- {
+			np, ok := paths[pathKey(path[:n])]
+			if !ok {
+				continue
+			}
+			if np == nil {
+				continue locations
+			}
+
+			loc.Path = append(append([]int32{}, np...), path[n:]...)
+			break
+		}
+
+		if len(path) > 0 && (path[0] == publicDependencyPath || path[0] == weakDependencyPath) {
+			continue
+		}
+
+		out = append(out, loc)
+	}
+
+	return out
+}
@@ -0,0 +1,215 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func notInternal(e Entity) bool {
+	internal, ok, err := e.ExtensionByName("graph.prune.internal")
+	return err != nil || !ok || internal != true
+}
+
+func prunedFiles(fdset *descriptor.FileDescriptorSet) (names []string, files map[string]*descriptor.FileDescriptorProto) {
+	files = map[string]*descriptor.FileDescriptorProto{}
+	for _, fd := range fdset.GetFile() {
+		names = append(names, fd.GetName())
+		files[fd.GetName()] = fd
+	}
+	return names, files
+}
+
+func TestGraph_Prune(t *testing.T) {
+	t.Parallel()
+
+	ast := buildGraph(t, "prune")
+
+	charge, ok := ast.Lookup(".graph.prune.Billing.Charge")
+	require.True(t, ok)
+
+	fdset, err := ast.Prune([]Entity{charge}, notInternal)
+	require.NoError(t, err)
+
+	names, files := prunedFiles(fdset)
+	assert.Equal(t, []string{
+		"google/protobuf/descriptor.proto",
+		"prune/options.proto",
+		"prune/common.proto",
+		"prune/api.proto",
+	}, names)
+
+	api := files["prune/api.proto"]
+	assert.Equal(t, []string{"prune/common.proto"}, api.GetDependency())
+	assert.Empty(t, api.GetPublicDependency())
+
+	require.Len(t, api.GetService(), 1)
+	require.Len(t, api.GetService()[0].GetMethod(), 1)
+	assert.Equal(t, "Charge", api.GetService()[0].GetMethod()[0].GetName())
+
+	require.Len(t, api.GetMessageType(), 2)
+	req := api.GetMessageType()[0]
+	assert.Equal(t, "ChargeRequest", req.GetName())
+	assert.Equal(t, "ChargeResponse", api.GetMessageType()[1].GetName())
+
+	var flds []string
+	for _, fld := range req.GetField() {
+		flds = append(flds, fld.GetName())
+	}
+	assert.Equal(t, []string{"amount", "account", "currency", "breakdown"}, flds)
+
+	require.Len(t, req.GetOneofDecl(), 1)
+	assert.Equal(t, "target", req.GetOneofDecl()[0].GetName())
+	assert.Equal(t, int32(0), req.GetField()[1].GetOneofIndex())
+
+	require.Len(t, req.GetNestedType(), 1)
+	assert.Equal(t, "BreakdownEntry", req.GetNestedType()[0].GetName())
+
+	var comment string
+	for _, loc := range api.GetSourceCodeInfo().GetLocation() {
+		if pathKey(loc.GetPath()) == pathKey([]int32{messageTypePath, 0, messageTypeFieldPath, 2}) {
+			comment = loc.GetLeadingComments()
+		}
+		assert.NotEqual(t, []int32{dependencyPath, 1}, loc.GetPath())
+	}
+	assert.Equal(t, " the currency of the amount\n", comment)
+
+	common := files["prune/common.proto"]
+	assert.Equal(t, []string{"prune/options.proto"}, common.GetDependency())
+	require.Len(t, common.GetMessageType(), 1)
+	assert.Equal(t, "Money", common.GetMessageType()[0].GetName())
+	require.Len(t, common.GetEnumType(), 1)
+	assert.Equal(t, "Currency", common.GetEnumType()[0].GetName())
+
+	opts := files["prune/options.proto"]
+	require.Len(t, opts.GetExtension(), 1)
+	assert.Equal(t, "label", opts.GetExtension()[0].GetName())
+	require.Len(t, opts.GetMessageType(), 1)
+	assert.Equal(t, "Label", opts.GetMessageType()[0].GetName())
+}
+
+func TestGraph_Prune_Roots(t *testing.T) {
+	t.Parallel()
+
+	ast := buildGraph(t, "prune")
+
+	t.Run("file", func(t *testing.T) {
+		t.Parallel()
+
+		f, ok := ast.Lookup("prune/common.proto")
+		require.True(t, ok)
+
+		fdset, err := ast.Prune([]Entity{f}, nil)
+		require.NoError(t, err)
+
+		names, files := prunedFiles(fdset)
+		assert.Equal(t, []string{
+			"google/protobuf/descriptor.proto",
+			"prune/options.proto",
+			"prune/common.proto",
+		}, names)
+		assert.Len(t, files["prune/common.proto"].GetMessageType(), 2)
+	})
+
+	t.Run("service", func(t *testing.T) {
+		t.Parallel()
+
+		svc, ok := ast.Lookup(".graph.prune.Billing")
+		require.True(t, ok)
+
+		fdset, err := ast.Prune([]Entity{svc}, nil)
+		require.NoError(t, err)
+
+		names, files := prunedFiles(fdset)
+		assert.Contains(t, names, "google/protobuf/timestamp.proto")
+		assert.Len(t, files["prune/api.proto"].GetService()[0].GetMethod(), 2)
+		assert.Equal(t, []string{"prune/options.proto", "google/protobuf/timestamp.proto", "prune/common.proto"},
+			files["prune/api.proto"].GetDependency())
+	})
+
+	t.Run("excluded root", func(t *testing.T) {
+		t.Parallel()
+
+		msg, ok := ast.Lookup(".graph.prune.Money")
+		require.True(t, ok)
+
+		fdset, err := ast.Prune([]Entity{msg}, func(Entity) bool { return false })
+		require.NoError(t, err)
+		assert.Empty(t, fdset.GetFile())
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		t.Parallel()
+
+		addr, ok := ast.Lookup(".graph.prune.Account.Address")
+		require.True(t, ok)
+
+		fdset, err := ast.Prune([]Entity{addr}, nil)
+		require.NoError(t, err)
+
+		// the containing message is only kept as a namespace, so the types of
+		// its fields and its options are not required
+		names, files := prunedFiles(fdset)
+		assert.Equal(t, []string{"prune/api.proto"}, names)
+
+		api := files["prune/api.proto"]
+		assert.Empty(t, api.GetDependency())
+		require.Len(t, api.GetMessageType(), 1)
+
+		acct := api.GetMessageType()[0]
+		assert.Equal(t, "Account", acct.GetName())
+		assert.Empty(t, acct.GetField())
+		assert.Nil(t, acct.GetOptions())
+		assert.Empty(t, acct.GetReservedRange())
+		require.Len(t, acct.GetNestedType(), 1)
+		assert.Equal(t, "Address", acct.GetNestedType()[0].GetName())
+		assert.Len(t, acct.GetNestedType()[0].GetField(), 1)
+
+		var comment string
+		for _, loc := range api.GetSourceCodeInfo().GetLocation() {
+			if pathKey(loc.GetPath()) == pathKey([]int32{messageTypePath, 0, messageTypeNestedTypePath, 0}) {
+				comment = loc.GetLeadingComments()
+			}
+			if path := loc.GetPath(); len(path) >= 3 {
+				assert.NotEqual(t, []int32{messageTypePath, 0, messageTypeOptionsPath}, path[:3])
+				assert.NotEqual(t, []int32{messageTypePath, 0, messageTypeFieldPath}, path[:3])
+			}
+		}
+		assert.Equal(t, " the postal address of an account\n", comment)
+
+		// requiring the message in full includes its fields
+		acctMsg, ok := ast.Lookup(".graph.prune.Account")
+		require.True(t, ok)
+
+		fdset, err = ast.Prune([]Entity{addr, acctMsg}, nil)
+		require.NoError(t, err)
+
+		names, files = prunedFiles(fdset)
+		assert.Contains(t, names, "prune/common.proto")
+		assert.Len(t, files["prune/api.proto"].GetMessageType()[0].GetField(), 3)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		t.Parallel()
+
+		fld, ok := ast.Lookup(".graph.prune.Money.units")
+		require.True(t, ok)
+
+		_, err := ast.Prune([]Entity{fld}, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("required", func(t *testing.T) {
+		t.Parallel()
+
+		charge, ok := ast.Lookup(".graph.prune.Billing.Charge")
+		require.True(t, ok)
+
+		_, err := ast.Prune([]Entity{charge}, func(e Entity) bool {
+			return e.FullyQualifiedName() != ".graph.prune.Money"
+		})
+		assert.EqualError(t, err, ".graph.prune.ChargeRequest.amount requires .graph.prune.Money, which is excluded")
+	})
+}
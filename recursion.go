@@ -0,0 +1,197 @@
+package pgs
+
+import "sort"
+
+// StronglyConnectedMessages returns the strongly connected components of the
+// message reference graph of ast. A Message references the Messages used as
+// the types of its fields, including repeated fields, map values and fields
+// within OneOfs. Each component is a set of Messages that all reference each
+// other, directly or transitively; a component with more than one Message, or
+// whose only Message IsRecursive, is a cycle.
+//
+// The components are ordered so that each appears after the components it
+// references. Within a component, Messages are in the order they are declared,
+// and files are visited in lexical order. Map entries are not included, as
+// map fields reference their value's Message directly.
+func StronglyConnectedMessages(ast AST) [][]Message {
+	msgs := astMessages(ast)
+
+	t := &tarjan{
+		index: make(map[string]int, len(msgs)),
+		low:   make(map[string]int, len(msgs)),
+		stack: make([]Message, 0, len(msgs)),
+		on:    make(map[string]bool, len(msgs)),
+		order: make(map[string]int, len(msgs)),
+	}
+
+	for i, m := range msgs {
+		t.order[m.FullyQualifiedName()] = i
+	}
+
+	for _, m := range msgs {
+		if _, ok := t.index[m.FullyQualifiedName()]; !ok {
+			t.connect(m)
+		}
+	}
+
+	return t.components
+}
+
+// TopologicalMessages returns all Messages in ast ordered so that each appears
+// after the Messages it references. Messages that are part of the same cycle
+// are adjacent, in the order they are declared. See StronglyConnectedMessages
+// for details.
+func TopologicalMessages(ast AST) []Message {
+	var out []Message
+	for _, c := range StronglyConnectedMessages(ast) {
+		out = append(out, c...)
+	}
+	return out
+}
+
+// TopologicalFiles returns all Files in ast ordered so that each appears after
+// the Files it imports. Files are otherwise visited in lexical order.
+func TopologicalFiles(ast AST) []File {
+	files := astFiles(ast)
+
+	out := make([]File, 0, len(files))
+	seen := make(map[string]bool, len(files))
+
+	var visit func(f File)
+	visit = func(f File) {
+		if seen[f.Name().String()] {
+			return
+		}
+		seen[f.Name().String()] = true
+
+		for _, imp := range f.Imports() {
+			visit(imp)
+		}
+		out = append(out, f)
+	}
+
+	for _, f := range files {
+		visit(f)
+	}
+
+	return out
+}
+
+// astFiles returns all Files in ast, sorted by name.
+func astFiles(ast AST) []File {
+	var files []File
+	for _, pkg := range ast.Packages() {
+		files = append(files, pkg.Files()...)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	return files
+}
+
+// astMessages returns all Messages in ast, excluding map entries, in the order
+// they are declared in the Files returned by astFiles.
+func astMessages(ast AST) []Message {
+	var msgs []Message
+	for _, f := range astFiles(ast) {
+		msgs = append(msgs, f.AllMessages()...)
+	}
+	return msgs
+}
+
+// messageRefs returns the Messages referenced by the fields of m.
+func messageRefs(m Message) []Message {
+	var refs []Message
+	for _, f := range m.Fields() {
+		ft := f.Type()
+		switch {
+		case ft.IsEmbed():
+			refs = append(refs, ft.Embed())
+		case ft.IsRepeated(), ft.IsMap():
+			if ft.Element().IsEmbed() {
+				refs = append(refs, ft.Element().Embed())
+			}
+		}
+	}
+	return refs
+}
+
+// isRecursive returns true if m references itself, directly or transitively.
+func isRecursive(m Message) bool {
+	seen := map[string]bool{}
+
+	var reaches func(from Message) bool
+	reaches = func(from Message) bool {
+		for _, ref := range messageRefs(from) {
+			fqn := ref.FullyQualifiedName()
+			if fqn == m.FullyQualifiedName() {
+				return true
+			}
+			if seen[fqn] {
+				continue
+			}
+			seen[fqn] = true
+
+			if reaches(ref) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return reaches(m)
+}
+
+// tarjan implements Tarjan's strongly connected components algorithm over the
+// message reference graph. Components are emitted in reverse topological
+// order, which places referenced Messages first.
+type tarjan struct {
+	next       int
+	index, low map[string]int
+	stack      []Message
+	on         map[string]bool
+	order      map[string]int
+	components [][]Message
+}
+
+func (t *tarjan) connect(m Message) {
+	fqn := m.FullyQualifiedName()
+	t.index[fqn], t.low[fqn] = t.next, t.next
+	t.next++
+
+	t.stack = append(t.stack, m)
+	t.on[fqn] = true
+
+	for _, ref := range messageRefs(m) {
+		rfqn := ref.FullyQualifiedName()
+		if _, ok := t.index[rfqn]; !ok {
+			t.connect(ref)
+			if t.low[rfqn] < t.low[fqn] {
+				t.low[fqn] = t.low[rfqn]
+			}
+		} else if t.on[rfqn] && t.index[rfqn] < t.low[fqn] {
+			t.low[fqn] = t.index[rfqn]
+		}
+	}
+
+	if t.low[fqn] != t.index[fqn] {
+		return
+	}
+
+	var c []Message
+	for {
+		n := len(t.stack) - 1
+		top := t.stack[n]
+		t.stack = t.stack[:n]
+		t.on[top.FullyQualifiedName()] = false
+		c = append(c, top)
+
+		if top.FullyQualifiedName() == fqn {
+			break
+		}
+	}
+
+	sort.SliceStable(c, func(i, j int) bool {
+		return t.order[c[i].FullyQualifiedName()] < t.order[c[j].FullyQualifiedName()]
+	})
+	t.components = append(t.components, c)
+}
@@ -0,0 +1,123 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func messageNames(msgs []Message) []string {
+	names := make([]string, len(msgs))
+	for i, m := range msgs {
+		names[i] = m.Name().String()
+	}
+	return names
+}
+
+func TestMsg_IsRecursive(t *testing.T) {
+	t.Parallel()
+
+	ast := buildGraph(t, "recursion")
+
+	tests := map[string]bool{
+		"Leaf":    false,
+		"Tree":    true,
+		"Expr":    true,
+		"Binary":  true,
+		"Literal": false,
+		"Scope":   true,
+		"Program": false,
+	}
+
+	for name, expected := range tests {
+		e, ok := ast.Lookup(".graph.recursion." + name)
+		require.True(t, ok, name)
+		assert.Equal(t, expected, e.(Message).IsRecursive(), name)
+	}
+
+	ast = buildGraph(t, "messages")
+	for _, name := range []string{"Recursive", "Circular.Rock", "RepeatedRecursive"} {
+		e, ok := ast.Lookup(".graph.messages." + name)
+		require.True(t, ok, name)
+		assert.True(t, e.(Message).IsRecursive(), name)
+	}
+
+	e, ok := ast.Lookup(".graph.messages.Circular")
+	require.True(t, ok)
+	assert.False(t, e.(Message).IsRecursive())
+}
+
+func TestStronglyConnectedMessages(t *testing.T) {
+	t.Parallel()
+
+	ast := buildGraph(t, "recursion")
+
+	var components [][]string
+	for _, c := range StronglyConnectedMessages(ast) {
+		components = append(components, messageNames(c))
+	}
+
+	assert.Equal(t, [][]string{
+		{"Leaf"},
+		{"Literal"},
+		{"Expr", "Binary"},
+		{"Scope"},
+		{"Tree"},
+		{"Program"},
+	}, components)
+
+	ast = buildGraph(t, "messages")
+	for _, c := range StronglyConnectedMessages(ast) {
+		if c[0].Name() == "Rock" || c[0].Name() == "Paper" || c[0].Name() == "Scissors" {
+			assert.Equal(t, []string{"Rock", "Paper", "Scissors"}, messageNames(c))
+		}
+	}
+}
+
+func TestTopologicalMessages(t *testing.T) {
+	t.Parallel()
+
+	ast := buildGraph(t, "recursion")
+	msgs := TopologicalMessages(ast)
+	assert.Equal(t, []string{"Leaf", "Literal", "Expr", "Binary", "Scope", "Tree", "Program"}, messageNames(msgs))
+
+	pos := map[string]int{}
+	for i, m := range msgs {
+		pos[m.FullyQualifiedName()] = i
+	}
+
+	for _, m := range msgs {
+		if m.IsRecursive() {
+			continue
+		}
+		for _, ref := range messageRefs(m) {
+			assert.Less(t, pos[ref.FullyQualifiedName()], pos[m.FullyQualifiedName()])
+		}
+	}
+}
+
+func TestTopologicalFiles(t *testing.T) {
+	t.Parallel()
+
+	var names []string
+	for _, f := range TopologicalFiles(buildGraph(t, "recursion")) {
+		names = append(names, f.Name().String())
+	}
+	assert.Equal(t, []string{"recursion/tree.proto", "recursion/expr.proto"}, names)
+
+	names = nil
+	for _, f := range TopologicalFiles(buildGraph(t, "extensions")) {
+		names = append(names, f.Name().String())
+	}
+
+	pos := map[string]int{}
+	for i, n := range names {
+		pos[n] = i
+	}
+	for _, f := range TopologicalFiles(buildGraph(t, "extensions")) {
+		for _, imp := range f.Imports() {
+			assert.Less(t, pos[imp.Name().String()], pos[f.Name().String()])
+		}
+	}
+}
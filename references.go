@@ -0,0 +1,165 @@
+package pgs
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ReferenceKind describes how an Entity refers to a Message or Enum.
+type ReferenceKind int
+
+const (
+	// FieldReference is a Field (optionally repeated or within a OneOf) whose
+	// type is the referenced Message or Enum.
+	FieldReference ReferenceKind = iota
+
+	// MapValueReference is a map Field whose value type is the referenced
+	// Message or Enum.
+	MapValueReference
+
+	// ExtensionReference is an Extension whose type is the referenced Message or
+	// Enum.
+	ExtensionReference
+
+	// ExtendeeReference is an Extension that extends the referenced Message.
+	ExtendeeReference
+
+	// MethodInputReference is a unary or server streaming Method whose input is
+	// the referenced Message.
+	MethodInputReference
+
+	// MethodOutputReference is a unary or client streaming Method whose output is
+	// the referenced Message.
+	MethodOutputReference
+
+	// StreamingInputReference is a client streaming Method whose input is the
+	// referenced Message.
+	StreamingInputReference
+
+	// StreamingOutputReference is a server streaming Method whose output is the
+	// referenced Message.
+	StreamingOutputReference
+)
+
+// String returns a string representation of the kind.
+func (k ReferenceKind) String() string {
+	switch k {
+	case FieldReference:
+		return "field"
+	case MapValueReference:
+		return "map value"
+	case ExtensionReference:
+		return "extension"
+	case ExtendeeReference:
+		return "extendee"
+	case MethodInputReference:
+		return "method input"
+	case MethodOutputReference:
+		return "method output"
+	case StreamingInputReference:
+		return "streaming input"
+	case StreamingOutputReference:
+		return "streaming output"
+	default:
+		return fmt.Sprintf("ReferenceKind(%d)", int(k))
+	}
+}
+
+// A Reference describes a use of a Message or Enum by another Entity.
+type Reference struct {
+	// Kind describes how the Message or Enum is referenced.
+	Kind ReferenceKind
+
+	// From is the referencing Entity: a Field, Extension or Method.
+	From Entity
+}
+
+// referenceIndex maps the fully qualified names of Messages and Enums to their
+// References. It is built once, the first time it is queried.
+type referenceIndex struct {
+	once sync.Once
+	refs map[string][]Reference
+}
+
+func (g *graph) References(e Entity) []Reference {
+	g.refs.once.Do(func() { g.refs.refs = buildReferences(g) })
+
+	switch e.(type) {
+	case Message, Enum:
+		return g.refs.refs[e.FullyQualifiedName()]
+	default:
+		return nil
+	}
+}
+
+func buildReferences(ast AST) map[string][]Reference {
+	refs := map[string][]Reference{}
+
+	add := func(target Entity, kind ReferenceKind, from Entity) {
+		if target == nil {
+			return
+		}
+		fqn := target.FullyQualifiedName()
+		refs[fqn] = append(refs[fqn], Reference{Kind: kind, From: from})
+	}
+
+	addType := func(ft FieldType, kind ReferenceKind, from Entity) {
+		switch {
+		case ft.IsEmbed():
+			add(ft.Embed(), kind, from)
+		case ft.IsEnum():
+			add(ft.Enum(), kind, from)
+		case ft.IsMap():
+			add(fieldTypeElemEntity(ft.Element()), MapValueReference, from)
+		case ft.IsRepeated():
+			add(fieldTypeElemEntity(ft.Element()), kind, from)
+		}
+	}
+
+	addExts := func(exts []Extension) {
+		for _, ext := range exts {
+			addType(ext.Type(), ExtensionReference, ext)
+			add(ext.Extendee(), ExtendeeReference, ext)
+		}
+	}
+
+	for _, f := range astFiles(ast) {
+		addExts(f.DefinedExtensions())
+
+		for _, m := range f.AllMessages() {
+			for _, fld := range m.Fields() {
+				addType(fld.Type(), FieldReference, fld)
+			}
+			addExts(m.DefinedExtensions())
+		}
+
+		for _, svc := range f.Services() {
+			for _, mtd := range svc.Methods() {
+				in, out := MethodInputReference, MethodOutputReference
+				if mtd.ClientStreaming() {
+					in = StreamingInputReference
+				}
+				if mtd.ServerStreaming() {
+					out = StreamingOutputReference
+				}
+				add(mtd.Input(), in, mtd)
+				add(mtd.Output(), out, mtd)
+			}
+		}
+	}
+
+	return refs
+}
+
+// fieldTypeElemEntity returns the Message or Enum of el, or nil if it is a
+// scalar.
+func fieldTypeElemEntity(el FieldTypeElem) Entity {
+	switch {
+	case el.IsEmbed():
+		return el.Embed()
+	case el.IsEnum():
+		return el.Enum()
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,88 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReferenceKind_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "field", FieldReference.String())
+	assert.Equal(t, "map value", MapValueReference.String())
+	assert.Equal(t, "extension", ExtensionReference.String())
+	assert.Equal(t, "extendee", ExtendeeReference.String())
+	assert.Equal(t, "method input", MethodInputReference.String())
+	assert.Equal(t, "method output", MethodOutputReference.String())
+	assert.Equal(t, "streaming input", StreamingInputReference.String())
+	assert.Equal(t, "streaming output", StreamingOutputReference.String())
+	assert.Equal(t, "ReferenceKind(99)", ReferenceKind(99).String())
+}
+
+func TestGraph_References(t *testing.T) {
+	t.Parallel()
+
+	ast := buildGraph(t, "references")
+
+	lookup := func(name string) Entity {
+		e, ok := ast.Lookup(".graph.references." + name)
+		require.True(t, ok, name)
+		return e
+	}
+
+	type ref struct {
+		Kind ReferenceKind
+		From string
+	}
+
+	refs := func(e Entity) []ref {
+		var out []ref
+		for _, r := range ast.References(e) {
+			out = append(out, ref{r.Kind, r.From.FullyQualifiedName()})
+		}
+		return out
+	}
+
+	const p = ".graph.references."
+
+	assert.Equal(t, []ref{
+		{ExtensionReference, p + "related"},
+		{ExtendeeReference, p + "related"},
+		{MapValueReference, p + "Item.children"},
+		{FieldReference, p + "Item.item"},
+		{ExtendeeReference, p + "Item.tags"},
+		{MethodInputReference, p + "Items.Get"},
+		{MethodOutputReference, p + "Items.Get"},
+		{StreamingInputReference, p + "Items.Upload"},
+		{MethodOutputReference, p + "Items.Upload"},
+		{MethodInputReference, p + "Items.Watch"},
+		{StreamingOutputReference, p + "Items.Watch"},
+	}, refs(lookup("Item")))
+
+	assert.Equal(t, []ref{
+		{FieldReference, p + "Item.kind"},
+		{MapValueReference, p + "Item.kinds"},
+		{ExtensionReference, p + "Item.tags"},
+	}, refs(lookup("Kind")))
+
+	assert.Empty(t, ast.References(lookup("Unused")))
+	assert.Nil(t, ast.References(lookup("Item.kind")))
+	assert.Nil(t, ast.References(lookup("Kind.UNKNOWN")))
+}
+
+func TestGraph_References_Imports(t *testing.T) {
+	t.Parallel()
+
+	ast := buildGraph(t, "recursion")
+
+	leaf, ok := ast.Lookup(".graph.recursion.Leaf")
+	require.True(t, ok)
+
+	var from []string
+	for _, r := range ast.References(leaf) {
+		from = append(from, r.From.FullyQualifiedName())
+	}
+	assert.Equal(t, []string{".graph.recursion.Literal.leaf", ".graph.recursion.Tree.leaf"}, from)
+}
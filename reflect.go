@@ -0,0 +1,100 @@
+package pgs
+
+import (
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// reflectRegistry lazily builds the protoreflect descriptors for the Files of
+// an AST. A File's descriptor (and those of its imports) is only built the
+// first time it is requested. Files whose descriptors cannot be built are
+// logged once and resolve to nil. The registry is safe for concurrent use.
+type reflectRegistry struct {
+	d Debugger
+
+	// base, if set, is the registry the AST was built from. Its descriptors are
+	// used instead of building new ones.
+	base *protoregistry.Files
+
+	mu     sync.Mutex
+	files  *protoregistry.Files
+	failed map[string]error
+}
+
+func newReflectRegistry(d Debugger) *reflectRegistry {
+	return &reflectRegistry{d: d, files: new(protoregistry.Files), failed: map[string]error{}}
+}
+
+// file returns the protoreflect descriptor for f, building it if necessary.
+// Nil is returned if it cannot be built.
+func (r *reflectRegistry) file(f File) protoreflect.FileDescriptor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := f.Name().String()
+	if _, ok := r.failed[name]; ok {
+		return nil
+	}
+
+	fd, err := r.resolve(f)
+	if err != nil {
+		r.failed[name] = err
+		r.d.Logf("unable to build reflection descriptor for %s: %v", name, err)
+		return nil
+	}
+	return fd
+}
+
+func (r *reflectRegistry) resolve(f File) (protoreflect.FileDescriptor, error) {
+	if fd, err := r.files.FindFileByPath(f.Name().String()); err == nil {
+		return fd, nil
+	}
+
+	if r.base != nil {
+		if fd, err := r.base.FindFileByPath(f.Name().String()); err == nil {
+			return fd, r.files.RegisterFile(fd)
+		}
+	}
+
+	for _, imp := range f.Imports() {
+		if _, err := r.resolve(imp); err != nil {
+			return nil, err
+		}
+	}
+
+	fd, err := protodesc.NewFile(f.Descriptor(), r.files)
+	if err != nil {
+		return nil, err
+	}
+
+	return fd, r.files.RegisterFile(fd)
+}
+
+// find returns the descriptor with the fully qualified name fqn, or nil if it
+// is not found. The File containing it must already be built.
+func (r *reflectRegistry) find(fqn string) protoreflect.Descriptor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, err := r.files.FindDescriptorByName(protoreflect.FullName(strings.TrimPrefix(fqn, ".")))
+	if err != nil {
+		r.d.Logf("unable to find reflection descriptor for %s: %v", fqn, err)
+		return nil
+	}
+	return d
+}
+
+// reflectDescriptor returns the protoreflect descriptor for e, building its
+// File's descriptor if necessary. Nil is returned if it cannot be built.
+func reflectDescriptor(e Entity) protoreflect.Descriptor {
+	f, ok := e.File().(*file)
+	if !ok || f.Reflect() == nil {
+		return nil
+	}
+
+	return f.reg.find(e.FullyQualifiedName())
+}
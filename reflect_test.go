@@ -0,0 +1,136 @@
+package pgs
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestReflect(t *testing.T) {
+	t.Parallel()
+
+	g := buildGraph(t, "extensions")
+
+	lookup := func(name string) Entity {
+		ent, ok := g.Lookup(name)
+		require.True(t, ok, name)
+		return ent
+	}
+
+	f := lookup("extensions/everything.proto").(File)
+	fd := f.Reflect()
+	require.NotNil(t, fd)
+	assert.Equal(t, "extensions/everything.proto", fd.Path())
+	assert.Equal(t, protoreflect.FullName("extensions"), fd.Package())
+	assert.Same(t, fd, f.Reflect(), "descriptor should be cached")
+
+	m := lookup(".extensions.RootMessage.NestedMessage").(Message)
+	md := m.Reflect()
+	require.NotNil(t, md)
+	assert.Equal(t, protoreflect.FullName("extensions.RootMessage.NestedMessage"), md.FullName())
+	assert.Equal(t, fd, md.ParentFile())
+
+	fld := lookup(".extensions.RootMessage.recursive_map").(Field)
+	fldd := fld.Reflect()
+	require.NotNil(t, fldd)
+	assert.True(t, fldd.IsMap())
+	assert.Equal(t, protoreflect.FullName("extensions.RootMessage"), fldd.MapValue().Message().FullName())
+
+	o := lookup(".extensions.RootMessage.union").(OneOf)
+	od := o.Reflect()
+	require.NotNil(t, od)
+	assert.Equal(t, 3, od.Fields().Len())
+
+	e := lookup(".extensions.RootMessage.NestedEnum").(Enum)
+	ed := e.Reflect()
+	require.NotNil(t, ed)
+	assert.Equal(t, 3, ed.Values().Len())
+
+	ev := e.Values()[1]
+	evd := ev.Reflect()
+	require.NotNil(t, evd)
+	assert.Equal(t, protoreflect.Name("ONE"), evd.Name())
+	assert.Equal(t, protoreflect.EnumNumber(1), evd.Number())
+	assert.Equal(t, ed, evd.Parent())
+
+	s := lookup(".extensions.API").(Service)
+	sd := s.Reflect()
+	require.NotNil(t, sd)
+	assert.Equal(t, 4, sd.Methods().Len())
+
+	mtd := lookup(".extensions.API.BiDi").(Method)
+	mtdd := mtd.Reflect()
+	require.NotNil(t, mtdd)
+	assert.True(t, mtdd.IsStreamingClient())
+	assert.True(t, mtdd.IsStreamingServer())
+
+	ext := lookup("extensions/ext/data.proto").(File).DefinedExtensions()[1]
+	extd := ext.Reflect()
+	require.NotNil(t, extd)
+	assert.True(t, extd.IsExtension())
+	assert.Equal(t, protoreflect.FullName("extensions.ext.name"), extd.FullName())
+	assert.Equal(t, protoreflect.FullName("google.protobuf.FieldOptions"), extd.ContainingMessage().FullName())
+}
+
+func TestReflect_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	g := buildGraph(t, "extensions")
+
+	ent, ok := g.Lookup("extensions/everything.proto")
+	require.True(t, ok)
+
+	var wg sync.WaitGroup
+	for _, m := range ent.(File).AllMessages() {
+		m := m
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NotNil(t, m.Reflect())
+		}()
+	}
+	wg.Wait()
+}
+
+func TestReflect_Invalid(t *testing.T) {
+	t.Parallel()
+
+	// use the real debugger, whose CheckErr and Fail would exit the process
+	l := newMockLogger()
+	d := initDebugger(false, l).(rootDebugger)
+	d.exit = func(int) { t.Fatal("debugger should not exit") }
+
+	f := &file{
+		desc: &descriptor.FileDescriptorProto{
+			Name:       proto.String("foo.proto"),
+			Dependency: []string{"missing.proto"},
+		},
+		reg: newReflectRegistry(d),
+	}
+
+	assert.Nil(t, f.Reflect())
+	assert.Contains(t, l.buf.String(), "unable to build reflection descriptor for foo.proto")
+	assert.Error(t, f.reg.failed["foo.proto"])
+
+	m := &msg{desc: &descriptor.DescriptorProto{Name: proto.String("Foo")}}
+	f.addMessage(m)
+	assert.Nil(t, m.Reflect())
+	assert.Equal(t, 1, strings.Count(l.buf.String(), "unable to build"), "failures should be logged once")
+}
+
+func TestReflect_NotFound(t *testing.T) {
+	t.Parallel()
+
+	l := newMockLogger()
+	d := initDebugger(false, l).(rootDebugger)
+	d.exit = func(int) { t.Fatal("debugger should not exit") }
+
+	assert.Nil(t, newReflectRegistry(d).find(".foo.Bar"))
+	assert.Contains(t, l.buf.String(), "unable to find reflection descriptor for .foo.Bar")
+}
@@ -0,0 +1,44 @@
+package pgs
+
+import descriptor "google.golang.org/protobuf/types/descriptorpb"
+
+// ReservedRange describes a range of field or enum value numbers that are
+// reserved, and cannot be used. Unlike the ranges in the descriptors (which
+// have an exclusive end for messages, but an inclusive end for enums), both
+// Start and End are inclusive.
+type ReservedRange struct {
+	Start, End int32
+}
+
+// Contains returns true if n is within the range.
+func (r ReservedRange) Contains(n int32) bool { return n >= r.Start && n <= r.End }
+
+// ExtensionRange describes a range of field numbers of a Message that are
+// available for Extensions. Both Start and End are inclusive.
+type ExtensionRange struct {
+	Start, End int32
+
+	// Options are the options declared on the range, if any.
+	Options *descriptor.ExtensionRangeOptions
+}
+
+// Contains returns true if n is within the range.
+func (r ExtensionRange) Contains(n int32) bool { return n >= r.Start && n <= r.End }
+
+func inReservedRanges(rs []ReservedRange, n int32) bool {
+	for _, r := range rs {
+		if r.Contains(n) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package pgs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservedRange_Contains(t *testing.T) {
+	t.Parallel()
+
+	r := ReservedRange{Start: 5, End: 7}
+	assert.False(t, r.Contains(4))
+	assert.True(t, r.Contains(5))
+	assert.True(t, r.Contains(7))
+	assert.False(t, r.Contains(8))
+}
+
+func TestExtensionRange_Contains(t *testing.T) {
+	t.Parallel()
+
+	r := ExtensionRange{Start: 100, End: 100}
+	assert.False(t, r.Contains(99))
+	assert.True(t, r.Contains(100))
+	assert.False(t, r.Contains(101))
+}
+
+func TestReserved_Graph(t *testing.T) {
+	t.Parallel()
+
+	ast := buildGraph(t, "references")
+
+	e, ok := ast.Lookup(".graph.references.Item")
+	if assert.True(t, ok) {
+		assert.Equal(t, []ExtensionRange{{Start: 100, End: 200}}, e.(Message).ExtensionRanges())
+	}
+
+	ast = buildGraph(t, "locations")
+
+	e, ok = ast.Lookup(".graph.locations.Range")
+	if assert.True(t, ok) {
+		m := e.(Message)
+		assert.Equal(t, []ReservedRange{{Start: 2, End: 2}, {Start: 15, End: 20}}, m.ReservedRanges())
+		assert.Equal(t, []string{"foo", "bar"}, m.ReservedNames())
+	}
+
+	e, ok = ast.Lookup(".graph.locations.Status")
+	if assert.True(t, ok) {
+		en := e.(Enum)
+		assert.Equal(t, []ReservedRange{{Start: 5, End: 10}}, en.ReservedRanges())
+		assert.True(t, en.IsReservedName("GONE"))
+	}
+}
@@ -1,6 +1,7 @@
 package pgs
 
 import (
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/runtime/protoimpl"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
@@ -12,6 +13,11 @@ type Service interface {
 	// Descriptor returns the underlying proto descriptor for this service
 	Descriptor() *descriptor.ServiceDescriptorProto
 
+	// Reflect returns the protoreflect descriptor for this service, built lazily
+	// from its File's descriptor. Nil is returned if the descriptor cannot be
+	// built.
+	Reflect() protoreflect.ServiceDescriptor
+
 	// Methods returns each rpc method exposed by this service
 	Methods() []Method
 
@@ -37,10 +43,23 @@ func (s *service) BuildTarget() bool                              { return s.fil
 func (s *service) SourceCodeInfo() SourceCodeInfo                 { return s.info }
 func (s *service) Descriptor() *descriptor.ServiceDescriptorProto { return s.desc }
 
+func (s *service) Reflect() protoreflect.ServiceDescriptor {
+	d, _ := reflectDescriptor(s).(protoreflect.ServiceDescriptor)
+	return d
+}
+
+func (s *service) Features() *descriptor.FeatureSet {
+	return mergeFeatures(s.file.Features(), s.desc.GetOptions().GetFeatures())
+}
+
 func (s *service) Extension(desc *protoimpl.ExtensionInfo, ext interface{}) (bool, error) {
 	return extension(s.desc.GetOptions(), desc, &ext)
 }
 
+func (s *service) ExtensionByName(name string) (interface{}, bool, error) {
+	return dynamicExtension(s, s.desc.GetOptions(), name)
+}
+
 func (s *service) Imports() (i []File) {
 	// Mapping for avoiding duplicate entries
 	mp := make(map[string]File, len(s.methods))
@@ -92,7 +111,7 @@ func (s *service) childAtPath(path []int32) Entity {
 		return s
 	case len(path)%2 != 0:
 		return nil
-	case path[0] == serviceTypeMethodPath:
+	case path[0] == serviceTypeMethodPath && inRange(path[1], len(s.methods)):
 		return s.methods[path[1]].childAtPath(path[2:])
 	default:
 		return nil
@@ -101,4 +120,14 @@ func (s *service) childAtPath(path []int32) Entity {
 
 func (s *service) addSourceCodeInfo(info SourceCodeInfo) { s.info = info }
 
+func (s *service) OptionSourceCodeInfo(path ...int32) SourceCodeInfo {
+	return sourceCodeInfoAt(s, append([]int32{serviceOptionsPath}, path...)...)
+}
+
+func (s *service) SourcePath() []int32 {
+	srvs := s.file.Descriptor().GetService()
+	i := indexOf(len(srvs), func(i int) bool { return srvs[i] == s.desc })
+	return childPath(nil, servicePath, i)
+}
+
 var _ Service = (*service)(nil)
@@ -1,26 +1,66 @@
 package pgs
 
 import (
+	"fmt"
+
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
 
 const (
-	packagePath               int32 = 2  // FileDescriptorProto.Package
-	messageTypePath           int32 = 4  // FileDescriptorProto.MessageType
-	enumTypePath              int32 = 5  // FileDescriptorProto.EnumType
-	servicePath               int32 = 6  // FileDescriptorProto.Service
-	syntaxPath                int32 = 12 // FileDescriptorProto.Syntax
-	messageTypeFieldPath      int32 = 2  // DescriptorProto.Field
-	messageTypeNestedTypePath int32 = 3  // DescriptorProto.NestedType
-	messageTypeEnumTypePath   int32 = 4  // DescriptorProto.EnumType
-	messageTypeOneofDeclPath  int32 = 8  // DescriptorProto.OneofDecl
-	enumTypeValuePath         int32 = 2  // EnumDescriptorProto.Value
-	serviceTypeMethodPath     int32 = 2  // ServiceDescriptorProto.Method
+	packagePath                  int32 = 2  // FileDescriptorProto.Package
+	dependencyPath               int32 = 3  // FileDescriptorProto.Dependency
+	messageTypePath              int32 = 4  // FileDescriptorProto.MessageType
+	enumTypePath                 int32 = 5  // FileDescriptorProto.EnumType
+	servicePath                  int32 = 6  // FileDescriptorProto.Service
+	extensionPath                int32 = 7  // FileDescriptorProto.Extension
+	fileOptionsPath              int32 = 8  // FileDescriptorProto.Options
+	publicDependencyPath         int32 = 10 // FileDescriptorProto.PublicDependency
+	weakDependencyPath           int32 = 11 // FileDescriptorProto.WeakDependency
+	syntaxPath                   int32 = 12 // FileDescriptorProto.Syntax
+	messageTypeFieldPath         int32 = 2  // DescriptorProto.Field
+	messageTypeNestedTypePath    int32 = 3  // DescriptorProto.NestedType
+	messageTypeEnumTypePath      int32 = 4  // DescriptorProto.EnumType
+	messageTypeExtRangePath      int32 = 5  // DescriptorProto.ExtensionRange
+	messageTypeExtensionPath     int32 = 6  // DescriptorProto.Extension
+	messageTypeOptionsPath       int32 = 7  // DescriptorProto.Options
+	messageTypeOneofDeclPath     int32 = 8  // DescriptorProto.OneofDecl
+	messageTypeReservedRangePath int32 = 9  // DescriptorProto.ReservedRange
+	messageTypeReservedNamePath  int32 = 10 // DescriptorProto.ReservedName
+	fieldOptionsPath             int32 = 8  // FieldDescriptorProto.Options
+	oneofOptionsPath             int32 = 2  // OneofDescriptorProto.Options
+	enumTypeValuePath            int32 = 2  // EnumDescriptorProto.Value
+	enumTypeOptionsPath          int32 = 3  // EnumDescriptorProto.Options
+	enumTypeReservedRangePath    int32 = 4  // EnumDescriptorProto.ReservedRange
+	enumTypeReservedNamePath     int32 = 5  // EnumDescriptorProto.ReservedName
+	enumValueOptionsPath         int32 = 3  // EnumValueDescriptorProto.Options
+	serviceTypeMethodPath        int32 = 2  // ServiceDescriptorProto.Method
+	serviceOptionsPath           int32 = 3  // ServiceDescriptorProto.Options
+	methodOptionsPath            int32 = 4  // MethodDescriptorProto.Options
 )
 
-// SourceCodeInfo represents data about an entity from the source. Currently
-// this only contains information about comments protoc associates with
-// entities.
+// A Span is the range of source text occupied by an entity or element, with
+// 1-based line and column numbers. The end column is exclusive, identifying
+// the column immediately following the last character. The zero value
+// describes an unknown location.
+type Span struct {
+	StartLine, StartColumn int
+	EndLine, EndColumn     int
+}
+
+// Valid returns true if the Span describes a known location.
+func (s Span) Valid() bool { return s.StartLine > 0 }
+
+// String returns the Span in the form "line:col-line:col", or an empty string
+// if the Span is not valid.
+func (s Span) String() string {
+	if !s.Valid() {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d-%d:%d", s.StartLine, s.StartColumn, s.EndLine, s.EndColumn)
+}
+
+// SourceCodeInfo represents data about an entity from the source, including
+// its location in the file and any comments protoc associates with it.
 //
 // All comments have their // or /* */ stripped by protoc. See the
 // SourceCodeInfo documentation for more details about how comments are
@@ -29,6 +69,15 @@ type SourceCodeInfo interface {
 	// Location returns the SourceCodeInfo_Location from the file descriptor.
 	Location() *descriptor.SourceCodeInfo_Location
 
+	// Span returns the location of the entity in its file, decoded from the
+	// Location's span. The zero Span is returned if the span is malformed.
+	Span() Span
+
+	// Position returns the start of the entity's location in the form
+	// "file:line:col", as used by protoc when reporting errors. If the Span is
+	// not valid, only the file name is returned.
+	Position() string
+
 	// LeadingComments returns any comment immediately preceding the entity,
 	// without any whitespace between it and the comment.
 	LeadingComments() string
@@ -46,6 +95,7 @@ type SourceCodeInfo interface {
 
 type sci struct {
 	desc *descriptor.SourceCodeInfo_Location
+	file string
 }
 
 func (info sci) Location() *descriptor.SourceCodeInfo_Location { return info.desc }
@@ -53,4 +103,105 @@ func (info sci) LeadingComments() string                       { return info.des
 func (info sci) LeadingDetachedComments() []string             { return info.desc.GetLeadingDetachedComments() }
 func (info sci) TrailingComments() string                      { return info.desc.GetTrailingComments() }
 
+func (info sci) Span() Span {
+	// spans are either [start line, start col, end line, end col] or, if the
+	// element is on a single line, [start line, start col, end col]
+	s := info.desc.GetSpan()
+	switch len(s) {
+	case 3:
+		return Span{int(s[0]) + 1, int(s[1]) + 1, int(s[0]) + 1, int(s[2]) + 1}
+	case 4:
+		return Span{int(s[0]) + 1, int(s[1]) + 1, int(s[2]) + 1, int(s[3]) + 1}
+	default:
+		return Span{}
+	}
+}
+
+func (info sci) Position() string { return position(info.file, info.Span()) }
+
+// position renders the start of span in file in protoc's "file:line:col"
+// format.
+func position(file string, span Span) string {
+	if !span.Valid() {
+		return file
+	}
+	return fmt.Sprintf("%s:%d:%d", file, span.StartLine, span.StartColumn)
+}
+
+// A SourceLocation is a location in a File's SourceCodeInfo, resolved to the
+// Entity that contains it.
+type SourceLocation struct {
+	SourceCodeInfo
+
+	// Entity is the innermost Entity containing the location.
+	Entity Entity
+
+	// Path is the location's path relative to the Entity's SourcePath. It is
+	// empty if the location describes the Entity itself, and otherwise points
+	// into the Entity's descriptor (eg, to its name or options).
+	Path []int32
+}
+
+// entityPath returns true if the descriptor field of e with the number field
+// contains child entities.
+func entityPath(e Entity, field int32) bool {
+	switch e.(type) {
+	case File:
+		switch field {
+		case messageTypePath, enumTypePath, servicePath, extensionPath:
+			return true
+		}
+	case Message:
+		switch field {
+		case messageTypeFieldPath, messageTypeNestedTypePath, messageTypeEnumTypePath,
+			messageTypeOneofDeclPath, messageTypeExtensionPath:
+			return true
+		}
+	case Enum:
+		return field == enumTypeValuePath
+	case Service:
+		return field == serviceTypeMethodPath
+	}
+	return false
+}
+
+// pathKey returns the key of a source path in a File's locations.
+func pathKey(path []int32) string { return fmt.Sprint(path) }
+
+// sourceCodeInfoAt returns the SourceCodeInfo of the element at path, relative
+// to the entity e. Nil is returned if there is no location for the element.
+func sourceCodeInfoAt(e Entity, path ...int32) SourceCodeInfo {
+	f, ok := e.File().(*file)
+	if !ok {
+		return nil
+	}
+	return f.locs[pathKey(append(e.SourcePath(), path...))]
+}
+
+// childPath returns the source path of the element at index i of the repeated
+// field of the descriptor at parent. Nil is returned if i is negative.
+func childPath(parent []int32, field int32, i int) []int32 {
+	if i < 0 {
+		return nil
+	}
+
+	path := make([]int32, len(parent), len(parent)+2)
+	copy(path, parent)
+	return append(path, field, int32(i))
+}
+
+// inRange returns true if i is a valid index of a slice of length n.
+func inRange(i int32, n int) bool { return i >= 0 && int(i) < n }
+
+// indexOf returns the index of the first of n elements that matches, or -1 if
+// none do.
+func indexOf(n int, match func(i int) bool) int {
+	for i := 0; i < n; i++ {
+		if match(i) {
+			return i
+		}
+	}
+	return -1
+}
+
 var _ SourceCodeInfo = sci{}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/proto"
 	descriptor "google.golang.org/protobuf/types/descriptorpb"
 )
@@ -17,10 +18,125 @@ func TestSourceCodeInfo(t *testing.T) {
 		LeadingDetachedComments: []string{"detached"},
 	}
 
-	info := sci{desc}
+	info := sci{desc: desc}
 
 	assert.Equal(t, desc, info.Location())
 	assert.Equal(t, "leading", info.LeadingComments())
 	assert.Equal(t, "trailing", info.TrailingComments())
 	assert.Equal(t, []string{"detached"}, info.LeadingDetachedComments())
 }
+
+func TestSourceCodeInfo_Span(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		span     []int32
+		expected Span
+		pos      string
+	}{
+		{nil, Span{}, "foo.proto"},
+		{[]int32{1, 2}, Span{}, "foo.proto"},
+		{[]int32{1, 2, 10}, Span{2, 3, 2, 11}, "foo.proto:2:3"},
+		{[]int32{1, 2, 3, 4}, Span{2, 3, 4, 5}, "foo.proto:2:3"},
+	}
+
+	for _, test := range tests {
+		info := sci{desc: &descriptor.SourceCodeInfo_Location{Span: test.span}, file: "foo.proto"}
+		assert.Equal(t, test.expected, info.Span())
+		assert.Equal(t, test.expected.Valid(), info.Span().Valid())
+		assert.Equal(t, test.pos, info.Position())
+	}
+
+	assert.Equal(t, "2:3-4:5", Span{2, 3, 4, 5}.String())
+	assert.Empty(t, Span{}.String())
+}
+
+func TestSourceCodeInfo_Locations(t *testing.T) {
+	t.Parallel()
+
+	g := buildGraph(t, "locations")
+
+	lookup := func(name string) Entity {
+		ent, ok := g.Lookup(name)
+		require.True(t, ok, name)
+		return ent
+	}
+
+	pos := func(info SourceCodeInfo) string {
+		if info == nil {
+			return ""
+		}
+		return info.Position()
+	}
+
+	const name = "locations/locations.proto"
+
+	f := lookup(name).(File)
+	assert.Equal(t, name+":7:1", pos(f.OptionSourceCodeInfo(50000)))
+	assert.Equal(t, Span{10, 5, 10, 29}, f.DefinedExtensions()[0].SourceCodeInfo().Span())
+	assert.Equal(t, name+":14:5", pos(f.DefinedExtensions()[1].SourceCodeInfo()))
+
+	m := lookup(".graph.locations.Range").(Message)
+	assert.Equal(t, name+":23:5", pos(m.OptionSourceCodeInfo(3)))
+	assert.Equal(t, name+":25:14", pos(m.ReservedRangeSourceCodeInfo(0)))
+	assert.Equal(t, Span{25, 17, 25, 25}, m.ReservedRangeSourceCodeInfo(1).Span())
+	assert.Equal(t, name+":26:21", pos(m.ReservedNameSourceCodeInfo(1)))
+	assert.Nil(t, m.ReservedNameSourceCodeInfo(2))
+	assert.Equal(t, name+":31:9", pos(m.DefinedExtensions()[0].SourceCodeInfo()))
+	assert.Nil(t, m.DefinedExtensions()[0].OptionSourceCodeInfo())
+
+	fld := lookup(".graph.locations.Range.value").(Field)
+	assert.Equal(t, Span{28, 21, 28, 72}, fld.OptionSourceCodeInfo().Span())
+	assert.Equal(t, name+":28:22", pos(fld.OptionSourceCodeInfo(3)))
+	assert.Equal(t, name+":28:41", pos(fld.OptionSourceCodeInfo(50001, 1)))
+	assert.Equal(t, name+":28:57", pos(fld.OptionSourceCodeInfo(50001, 2)))
+	assert.Nil(t, fld.OptionSourceCodeInfo(50001))
+
+	e := lookup(".graph.locations.Status").(Enum)
+	assert.Equal(t, name+":36:5", pos(e.OptionSourceCodeInfo(2)))
+	assert.Equal(t, name+":38:14", pos(e.ReservedRangeSourceCodeInfo(0)))
+	assert.Equal(t, name+":39:14", pos(e.ReservedNameSourceCodeInfo(0)))
+	assert.Equal(t, name+":42:17", pos(e.Values()[1].OptionSourceCodeInfo(1)))
+	assert.Nil(t, e.Values()[2].OptionSourceCodeInfo())
+}
+
+type entityCollector struct {
+	ents []Entity
+}
+
+func (c *entityCollector) visit(e Entity) (Visitor, error) {
+	c.ents = append(c.ents, e)
+	return c, nil
+}
+
+func (c *entityCollector) VisitPackage(Package) (Visitor, error)       { return c, nil }
+func (c *entityCollector) VisitFile(f File) (Visitor, error)           { return c.visit(f) }
+func (c *entityCollector) VisitMessage(m Message) (Visitor, error)     { return c.visit(m) }
+func (c *entityCollector) VisitEnum(e Enum) (Visitor, error)           { return c.visit(e) }
+func (c *entityCollector) VisitEnumValue(e EnumValue) (Visitor, error) { return c.visit(e) }
+func (c *entityCollector) VisitField(f Field) (Visitor, error)         { return c.visit(f) }
+func (c *entityCollector) VisitExtension(e Extension) (Visitor, error) { return c.visit(e) }
+func (c *entityCollector) VisitOneOf(o OneOf) (Visitor, error)         { return c.visit(o) }
+func (c *entityCollector) VisitService(s Service) (Visitor, error)     { return c.visit(s) }
+func (c *entityCollector) VisitMethod(m Method) (Visitor, error)       { return c.visit(m) }
+
+func TestEntity_SourcePath(t *testing.T) {
+	t.Parallel()
+
+	for _, dir := range []string{"info", "extensions", "locations"} {
+		g := buildGraph(t, dir)
+
+		for _, tgt := range g.Targets() {
+			c := &entityCollector{}
+			require.NoError(t, Walk(c, tgt))
+			require.NotEmpty(t, c.ents)
+
+			for _, e := range c.ents {
+				path := e.SourcePath()
+				require.NotNil(t, path, e.FullyQualifiedName())
+				assert.Equal(t, e, tgt.childAtPath(path), e.FullyQualifiedName())
+				assert.Equal(t, e, tgt.EntityAtPath(path), e.FullyQualifiedName())
+			}
+		}
+	}
+}
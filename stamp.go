@@ -0,0 +1,218 @@
+package pgs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// HandEditPolicy describes how a stamped CustomFile or CustomTemplateFile that
+// was edited by hand since it was generated is handled when overwriting it.
+type HandEditPolicy int
+
+const (
+	// HandEditsOverwrite overwrites the file regardless of any changes made to
+	// it. This is the default behavior.
+	HandEditsOverwrite HandEditPolicy = iota
+
+	// HandEditsWarn overwrites the file, logging a warning if it was edited.
+	HandEditsWarn
+
+	// HandEditsSkip logs a warning and leaves the file untouched if it was
+	// edited.
+	HandEditsSkip
+)
+
+// CommentStyle describes the syntax of a single line comment in a file format.
+type CommentStyle struct {
+	Prefix string
+	Suffix string
+}
+
+var (
+	slashComment = CommentStyle{Prefix: "//"}
+	hashComment  = CommentStyle{Prefix: "#"}
+	dashComment  = CommentStyle{Prefix: "--"}
+	blockComment = CommentStyle{Prefix: "/*", Suffix: " */"}
+	xmlComment   = CommentStyle{Prefix: "<!--", Suffix: " -->"}
+)
+
+// DefaultCommentStyles maps file extensions to the CommentStyle used by the
+// StampHeaders PostProcessor.
+var DefaultCommentStyles = map[string]CommentStyle{
+	".go":    slashComment,
+	".proto": slashComment,
+	".c":     slashComment,
+	".h":     slashComment,
+	".cc":    slashComment,
+	".cpp":   slashComment,
+	".hpp":   slashComment,
+	".cs":    slashComment,
+	".java":  slashComment,
+	".kt":    slashComment,
+	".scala": slashComment,
+	".swift": slashComment,
+	".rs":    slashComment,
+	".dart":  slashComment,
+	".js":    slashComment,
+	".jsx":   slashComment,
+	".ts":    slashComment,
+	".tsx":   slashComment,
+	".php":   slashComment,
+	".py":    hashComment,
+	".rb":    hashComment,
+	".sh":    hashComment,
+	".bzl":   hashComment,
+	".yaml":  hashComment,
+	".yml":   hashComment,
+	".toml":  hashComment,
+	".sql":   dashComment,
+	".lua":   dashComment,
+	".css":   blockComment,
+	".html":  xmlComment,
+	".xml":   xmlComment,
+	".md":    xmlComment,
+}
+
+// checksumPattern matches a stamped header at the start of a file, after an
+// optional shebang line, up to and including the blank line that terminates
+// it. Checksum lines elsewhere in the file are not part of a header.
+var checksumPattern = regexp.MustCompile(
+	`\A(?:#!.*\n)?.*Code generated by .*DO NOT EDIT\..*\n(?:.*source: .*\n)?.*checksum: sha256:([0-9a-f]{64}).*\n\n`)
+
+// HeaderStamper is a PostProcessor that prepends a "Code generated ... DO NOT
+// EDIT." header to generated files. The header includes the plugin's name and
+// version, the source proto File of the Artifact (if set) and a checksum of
+// the file's contents, which is used to detect hand-edited files on later
+// runs. The comment syntax is determined by the file's extension; files with
+// an unknown extension are not stamped.
+//
+// Since the checksum covers the final contents of the file, a HeaderStamper
+// should be registered after all other PostProcessors.
+type HeaderStamper struct {
+	// Plugin is the name of the plugin, eg "protoc-gen-foo".
+	Plugin string
+
+	// Version is the version of the plugin. It is omitted if empty.
+	Version string
+
+	// Styles maps file extensions (including the leading dot) to their
+	// CommentStyle, overriding DefaultCommentStyles.
+	Styles map[string]CommentStyle
+}
+
+// StampHeaders returns a HeaderStamper for the plugin and version.
+func StampHeaders(plugin, version string) *HeaderStamper {
+	return &HeaderStamper{Plugin: plugin, Version: version}
+}
+
+// Match returns true for GeneratorFile, GeneratorTemplateFile, CustomFile and
+// CustomTemplateFile Artifacts with a known CommentStyle.
+func (hs *HeaderStamper) Match(a Artifact) bool {
+	name, _ := stampTarget(a)
+	_, ok := hs.style(name)
+	return ok
+}
+
+// Process returns in unchanged, as the Artifact is required to stamp it. The
+// persister calls ProcessArtifact instead.
+func (hs *HeaderStamper) Process(in []byte) ([]byte, error) { return in, nil }
+
+// ProcessArtifact prepends the header to in, the rendered contents of a. If
+// the contents begin with a shebang line, the header is placed after it.
+func (hs *HeaderStamper) ProcessArtifact(a Artifact, in []byte) ([]byte, error) {
+	name, src := stampTarget(a)
+	style, ok := hs.style(name)
+	if !ok {
+		return in, nil
+	}
+
+	var shebang []byte
+	if bytes.HasPrefix(in, []byte("#!")) {
+		i := bytes.IndexByte(in, '\n')
+		if i < 0 {
+			return nil, fmt.Errorf("file %s contains only a shebang line", name)
+		}
+		shebang, in = in[:i+1], in[i+1:]
+	}
+
+	gen := hs.Plugin
+	if hs.Version != "" {
+		gen = fmt.Sprintf("%s %s", gen, hs.Version)
+	}
+
+	buf := &bytes.Buffer{}
+	buf.Write(shebang)
+
+	line := func(format string, args ...interface{}) {
+		fmt.Fprintf(buf, "%s %s%s\n", style.Prefix, fmt.Sprintf(format, args...), style.Suffix)
+	}
+
+	line("Code generated by %s. DO NOT EDIT.", gen)
+	if src != nil {
+		line("source: %s", src.Name())
+	}
+	line("checksum: sha256:%s", checksum(in))
+	buf.WriteByte('\n')
+	buf.Write(in)
+
+	return buf.Bytes(), nil
+}
+
+func (hs *HeaderStamper) style(name string) (CommentStyle, bool) {
+	if name == "" {
+		return CommentStyle{}, false
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	if s, ok := hs.Styles[ext]; ok {
+		return s, true
+	}
+
+	s, ok := DefaultCommentStyles[ext]
+	return s, ok
+}
+
+// stampTarget returns the name and source File of an Artifact that can be
+// stamped. An empty name is returned for all other Artifacts.
+func stampTarget(a Artifact) (name string, src File) {
+	switch a := a.(type) {
+	case GeneratorFile:
+		return a.Name, a.Source
+	case GeneratorTemplateFile:
+		return a.Name, a.Source
+	case CustomFile:
+		return a.Name, a.Source
+	case CustomTemplateFile:
+		return a.Name, a.Source
+	default:
+		return "", nil
+	}
+}
+
+// HandEdited returns true if content begins with a header stamped by a
+// HeaderStamper (after an optional shebang line) and its checksum does not
+// match the rest of the content. False is returned if content was never
+// stamped.
+func HandEdited(content []byte) bool {
+	m := checksumPattern.FindSubmatchIndex(content)
+	if m == nil {
+		return false
+	}
+
+	return string(content[m[2]:m[3]]) != checksum(content[m[1]:])
+}
+
+func checksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+var (
+	_ PostProcessor         = (*HeaderStamper)(nil)
+	_ ArtifactPostProcessor = (*HeaderStamper)(nil)
+)
@@ -0,0 +1,160 @@
+package pgs
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderStamper_Match(t *testing.T) {
+	t.Parallel()
+
+	hs := StampHeaders("protoc-gen-foo", "v1.0.0")
+	hs.Styles = map[string]CommentStyle{".foo": {Prefix: "%"}}
+
+	tests := []struct {
+		a     Artifact
+		match bool
+	}{
+		{GeneratorFile{Name: "a.go"}, true},
+		{GeneratorTemplateFile{Name: "a.PY"}, true},
+		{CustomFile{Name: "a.foo"}, true},
+		{CustomTemplateFile{Name: "a.html"}, true},
+		{CustomFile{Name: "a.unknown"}, false},
+		{GeneratorAppend{FileName: "a.go"}, false},
+		{GeneratorInjection{FileName: "a.go"}, false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.match, hs.Match(test.a), "%#v", test.a)
+	}
+}
+
+func TestHeaderStamper_ProcessArtifact(t *testing.T) {
+	t.Parallel()
+
+	hs := StampHeaders("protoc-gen-foo", "v1.0.0")
+
+	body := []byte("package foo\n")
+	out, err := hs.ProcessArtifact(GeneratorFile{Name: "foo.go", Source: dummyFile()}, body)
+	assert.NoError(t, err)
+	assert.Equal(t, `// Code generated by protoc-gen-foo v1.0.0. DO NOT EDIT.
+// source: file.proto
+// checksum: sha256:`+checksum(body)+`
+
+package foo
+`, string(out))
+	assert.False(t, HandEdited(out))
+
+	hs.Version = ""
+	out, err = hs.ProcessArtifact(CustomFile{Name: "foo.sh"}, []byte("#!/bin/sh\necho hi\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, `#!/bin/sh
+# Code generated by protoc-gen-foo. DO NOT EDIT.
+# checksum: sha256:`+checksum([]byte("echo hi\n"))+`
+
+echo hi
+`, string(out))
+	assert.False(t, HandEdited(out))
+
+	out, err = hs.ProcessArtifact(CustomFile{Name: "foo.css"}, body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), "/* checksum: sha256:"+checksum(body)+" */\n\n")
+	assert.False(t, HandEdited(out))
+
+	out, err = hs.ProcessArtifact(CustomFile{Name: "foo.unknown"}, body)
+	assert.NoError(t, err)
+	assert.Equal(t, body, out)
+
+	_, err = hs.ProcessArtifact(CustomFile{Name: "foo.sh"}, []byte("#!/bin/sh"))
+	assert.Error(t, err)
+
+	out, err = hs.Process(body)
+	assert.NoError(t, err)
+	assert.Equal(t, body, out)
+}
+
+func TestHandEdited(t *testing.T) {
+	t.Parallel()
+
+	hs := StampHeaders("protoc-gen-foo", "")
+	out, err := hs.ProcessArtifact(CustomFile{Name: "foo.go"}, []byte("package foo\n"))
+	assert.NoError(t, err)
+
+	assert.False(t, HandEdited(out))
+	assert.True(t, HandEdited(append(out, "// edited\n"...)))
+	assert.False(t, HandEdited([]byte("package foo\n")), "unstamped files are not considered edited")
+
+	body := []byte("package foo\n\n// checksum: sha256:" + strings.Repeat("0", 64) + "\n\nvar x int\n")
+	assert.False(t, HandEdited(body), "checksums outside of the header are ignored")
+	assert.False(t, HandEdited(append([]byte("// edited\n"), out...)), "the header must begin the file")
+
+	stamped, err := hs.ProcessArtifact(CustomFile{Name: "foo.sh", Source: dummyFile()}, []byte("#!/bin/sh\necho\n"))
+	assert.NoError(t, err)
+	assert.False(t, HandEdited(stamped))
+	assert.True(t, HandEdited(append(stamped, "echo\n"...)))
+}
+
+func TestPersister_Persist_HandEdits(t *testing.T) {
+	t.Parallel()
+
+	stamped, err := StampHeaders("protoc-gen-foo", "").
+		ProcessArtifact(CustomFile{Name: "foo.go"}, []byte("package foo\n"))
+	assert.NoError(t, err)
+	edited := append(stamped, "// edited\n"...)
+
+	tests := []struct {
+		name     string
+		existing []byte
+		policy   HandEditPolicy
+		written  bool
+		warning  string
+	}{
+		{"overwrite edited", edited, HandEditsOverwrite, true, ""},
+		{"warn edited", edited, HandEditsWarn, true, "overwriting"},
+		{"skip edited", edited, HandEditsSkip, false, "skipping"},
+		{"skip unedited", stamped, HandEditsSkip, true, ""},
+		{"skip unstamped", []byte("package foo\n"), HandEditsSkip, true, ""},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			d := InitMockDebugger()
+			p := dummyPersister(d)
+			p.AddPostProcessor(StampHeaders("protoc-gen-foo", ""))
+			assert.NoError(t, afero.WriteFile(p.fs, "/foo.go", tc.existing, 0644))
+
+			p.Persist(CustomFile{
+				Name:      "/foo.go",
+				Contents:  "package bar\n",
+				Perms:     0644,
+				Overwrite: true,
+				HandEdits: tc.policy,
+			})
+			assert.NoError(t, d.Err())
+
+			b, err := afero.ReadFile(p.fs, "/foo.go")
+			assert.NoError(t, err)
+			if tc.written {
+				assert.Contains(t, string(b), "package bar\n")
+				assert.False(t, HandEdited(b))
+			} else {
+				assert.Equal(t, tc.existing, b)
+			}
+
+			logs, err := ioutil.ReadAll(d.Output())
+			assert.NoError(t, err)
+			if tc.warning == "" {
+				assert.NotContains(t, string(logs), "edited by hand")
+			} else {
+				assert.Contains(t, string(logs), "warning: file /foo.go was edited by hand, "+tc.warning)
+			}
+		})
+	}
+}
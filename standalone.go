@@ -0,0 +1,189 @@
+package pgs
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+// standaloneConfig describes the command line arguments of a standalone
+// execution of a Generator.
+type standaloneConfig struct {
+	descriptorSet string   // path to a FileDescriptorSet
+	request       string   // path to an encoded CodeGeneratorRequest
+	params        string   // parameters, overriding those in the request
+	outDir        string   // directory in which files are generated
+	files         []string // proto files to generate
+}
+
+func parseStandaloneArgs(name string, args []string) (cfg standaloneConfig, err error) {
+	usage := &bytes.Buffer{}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(usage)
+
+	fs.StringVar(&cfg.descriptorSet, "descriptor_set_in", "",
+		"path to a FileDescriptorSet, as produced by protoc -o with --include_imports")
+	fs.StringVar(&cfg.request, "request", "",
+		"path to an encoded CodeGeneratorRequest, as produced by protoc-gen-debug")
+	fs.StringVar(&cfg.params, "param", "", "plugin parameters")
+	fs.StringVar(&cfg.outDir, "out", ".", "output directory for generated files")
+
+	if err = fs.Parse(args); err != nil {
+		return cfg, errors.New(strings.TrimSpace(usage.String()))
+	}
+	cfg.files = fs.Args()
+
+	switch {
+	case cfg.descriptorSet == "" && cfg.request == "":
+		err = errors.New("one of -descriptor_set_in or -request is required")
+	case cfg.descriptorSet != "" && cfg.request != "":
+		err = errors.New("only one of -descriptor_set_in or -request may be provided")
+	case cfg.descriptorSet != "" && len(cfg.files) == 0:
+		err = errors.New("no files to generate")
+	}
+
+	return cfg, err
+}
+
+// RenderStandalone executes the plugin without protoc, for instance from a
+// main function with os.Args[1:] as args. Instead of reading a
+// CodeGeneratorRequest from the input io.Reader, the request is loaded from the
+// file provided by one of the following flags:
+//
+//	-descriptor_set_in  a FileDescriptorSet, as produced by
+//	                    `protoc -o fdset.bin --include_imports`
+//	-request            a CodeGeneratorRequest, as saved by protoc-gen-debug
+//
+// The remaining arguments are the proto files to generate, which are required
+// for a FileDescriptorSet and override those in a CodeGeneratorRequest. The
+// -param flag sets the plugin's parameters (also overriding a request's). The
+// registered modules are executed as usual, and the files in the resulting
+// CodeGeneratorResponse, including appends and insertion points, are written
+// to the -out directory (the working directory by default) instead of to the
+// output io.Writer. Like Render, the process fails if an error occurs or the
+// response contains an error.
+func (g *Generator) RenderStandalone(args []string) {
+	cfg, err := parseStandaloneArgs(filepath.Base(os.Args[0]), args)
+	g.CheckErr(err, "parsing arguments")
+
+	req, err := cfg.loadRequest()
+	g.CheckErr(err, "loading request")
+
+	data, err := proto.Marshal(req)
+	g.CheckErr(err, "marshaling request")
+
+	out := &bytes.Buffer{}
+	g.in, g.out = bytes.NewReader(data), out
+	g.persister.SetResolveInsertionPoints(true)
+	g.Render()
+
+	resp := new(plugin_go.CodeGeneratorResponse)
+	g.CheckErr(proto.Unmarshal(out.Bytes(), resp), "parsing response")
+	g.Assert(resp.Error == nil, resp.GetError())
+
+	g.persister.WriteResponse(cfg.outDir, resp)
+}
+
+// loadRequest builds the CodeGeneratorRequest described by cfg.
+func (cfg standaloneConfig) loadRequest() (*plugin_go.CodeGeneratorRequest, error) {
+	req := new(plugin_go.CodeGeneratorRequest)
+
+	if cfg.request != "" {
+		b, err := ioutil.ReadFile(cfg.request)
+		if err != nil {
+			return nil, err
+		}
+		if err = proto.Unmarshal(b, req); err != nil {
+			return nil, err
+		}
+	} else {
+		b, err := ioutil.ReadFile(cfg.descriptorSet)
+		if err != nil {
+			return nil, err
+		}
+		fdset := new(descriptor.FileDescriptorSet)
+		if err = proto.Unmarshal(b, fdset); err != nil {
+			return nil, err
+		}
+		req.ProtoFile = fdset.File
+	}
+
+	if len(cfg.files) > 0 {
+		req.FileToGenerate = cfg.files
+	}
+
+	if cfg.params != "" {
+		req.Parameter = proto.String(cfg.params)
+	}
+
+	known := make(map[string]struct{}, len(req.GetProtoFile()))
+	for _, f := range req.GetProtoFile() {
+		known[f.GetName()] = struct{}{}
+	}
+
+	for _, f := range req.GetFileToGenerate() {
+		if _, ok := known[f]; !ok {
+			return nil, fmt.Errorf("file to generate %q not found in input", f)
+		}
+	}
+
+	return req, nil
+}
+
+// WriteResponse writes the files in resp to the directory dir, as protoc would.
+// Files without a name are appended to the preceding file, and insertion
+// points are resolved against files generated earlier in resp or already
+// present in dir.
+func (p *stdPersister) WriteResponse(dir string, resp *plugin_go.CodeGeneratorResponse) {
+	files := map[string]string{}
+	var order []string
+	var last, lastPoint string
+
+	for _, f := range resp.GetFile() {
+		name, point := f.GetName(), f.GetInsertionPoint()
+		if name == "" {
+			// an unnamed file continues the previous file or insertion
+			p.Assert(last != "", "append without a preceding file")
+			name, point = last, lastPoint
+		}
+
+		content, ok := files[name]
+		switch {
+		case point != "":
+			if !ok {
+				b, err := afero.ReadFile(p.fs, filepath.Join(dir, name))
+				p.CheckErr(err, "unable to read insertion point target: ", name)
+				content = string(b)
+				order = append(order, name)
+			}
+
+			var err error
+			content, err = insertAtPoint(content, point, f.GetContent())
+			p.CheckErr(err, "unable to insert into ", name)
+		case f.GetName() == "":
+			content += f.GetContent()
+		default:
+			if !ok {
+				order = append(order, name)
+			}
+			content = f.GetContent()
+		}
+
+		files[name] = content
+		last, lastPoint = name, point
+	}
+
+	for _, name := range order {
+		p.writeFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(files[name]), true, 0644)
+	}
+}
@@ -0,0 +1,174 @@
+package pgs
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestParseStandaloneArgs(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := parseStandaloneArgs("foo", []string{
+		"-descriptor_set_in", "fdset.bin", "-param", "a=b", "-out", "gen", "foo.proto", "bar.proto"})
+	assert.NoError(t, err)
+	assert.Equal(t, standaloneConfig{
+		descriptorSet: "fdset.bin",
+		params:        "a=b",
+		outDir:        "gen",
+		files:         []string{"foo.proto", "bar.proto"},
+	}, cfg)
+
+	cfg, err = parseStandaloneArgs("foo", []string{"-request", "req.pb.bin"})
+	assert.NoError(t, err)
+	assert.Equal(t, ".", cfg.outDir)
+	assert.Empty(t, cfg.files)
+
+	tests := map[string][]string{
+		"no input":     {"foo.proto"},
+		"both inputs":  {"-request", "a", "-descriptor_set_in", "b", "foo.proto"},
+		"no files":     {"-descriptor_set_in", "fdset.bin"},
+		"unknown flag": {"-nope"},
+	}
+
+	for desc, args := range tests {
+		_, err = parseStandaloneArgs("foo", args)
+		assert.Error(t, err, desc)
+	}
+}
+
+func TestStandaloneConfig_LoadRequest(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	fdset := &descriptor.FileDescriptorSet{File: []*descriptor.FileDescriptorProto{
+		{Name: proto.String("foo.proto")},
+		{Name: proto.String("bar.proto")},
+	}}
+	b, err := proto.Marshal(fdset)
+	assert.NoError(t, err)
+	fdsetPath := filepath.Join(dir, "fdset.bin")
+	assert.NoError(t, ioutil.WriteFile(fdsetPath, b, 0644))
+
+	b, err = proto.Marshal(&plugin_go.CodeGeneratorRequest{
+		FileToGenerate: []string{"foo.proto"},
+		Parameter:      proto.String("x=y"),
+		ProtoFile:      fdset.File,
+	})
+	assert.NoError(t, err)
+	reqPath := filepath.Join(dir, "req.pb.bin")
+	assert.NoError(t, ioutil.WriteFile(reqPath, b, 0644))
+
+	req, err := standaloneConfig{descriptorSet: fdsetPath, files: []string{"bar.proto"}}.loadRequest()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar.proto"}, req.GetFileToGenerate())
+	assert.Len(t, req.GetProtoFile(), 2)
+	assert.Nil(t, req.Parameter)
+
+	req, err = standaloneConfig{request: reqPath}.loadRequest()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo.proto"}, req.GetFileToGenerate())
+	assert.Equal(t, "x=y", req.GetParameter())
+
+	req, err = standaloneConfig{request: reqPath, params: "z", files: []string{"bar.proto"}}.loadRequest()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bar.proto"}, req.GetFileToGenerate())
+	assert.Equal(t, "z", req.GetParameter())
+
+	_, err = standaloneConfig{request: reqPath, files: []string{"baz.proto"}}.loadRequest()
+	assert.Error(t, err)
+
+	_, err = standaloneConfig{request: filepath.Join(dir, "missing")}.loadRequest()
+	assert.Error(t, err)
+
+	_, err = standaloneConfig{descriptorSet: reqPath + "x"}.loadRequest()
+	assert.Error(t, err)
+}
+
+func TestPersister_WriteResponse(t *testing.T) {
+	t.Parallel()
+
+	d := InitMockDebugger()
+	p := dummyPersister(d)
+	assert.NoError(t, afero.WriteFile(p.fs, "/out/existing.txt",
+		[]byte("// @@protoc_insertion_point(point)\n"), 0644))
+
+	p.WriteResponse("/out", &plugin_go.CodeGeneratorResponse{
+		File: []*plugin_go.CodeGeneratorResponse_File{
+			{Name: proto.String("a/foo.txt"), Content: proto.String("foo\n// @@protoc_insertion_point(point)\n")},
+			{Content: proto.String("bar\n")},
+			{Name: proto.String("a/foo.txt"), InsertionPoint: proto.String("point"), Content: proto.String("baz\n")},
+			{Content: proto.String("quux\n")},
+			{Name: proto.String("existing.txt"), InsertionPoint: proto.String("point"), Content: proto.String("fizz\n")},
+		},
+	})
+	assert.NoError(t, d.Err())
+	assert.False(t, d.Failed())
+
+	b, err := afero.ReadFile(p.fs, "/out/a/foo.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo\nbaz\nquux\n// @@protoc_insertion_point(point)\nbar\n", string(b))
+
+	b, err = afero.ReadFile(p.fs, "/out/existing.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "fizz\n// @@protoc_insertion_point(point)\n", string(b))
+
+	p.WriteResponse("/out", &plugin_go.CodeGeneratorResponse{
+		File: []*plugin_go.CodeGeneratorResponse_File{
+			{Name: proto.String("existing.txt"), InsertionPoint: proto.String("missing"), Content: proto.String("x")},
+		},
+	})
+	assert.Error(t, d.Err())
+}
+
+// standaloneModule generates a file per target, injecting into and appending
+// to it.
+type standaloneModule struct {
+	*ModuleBase
+}
+
+func (m standaloneModule) Name() string { return "standalone" }
+
+func (m standaloneModule) Execute(targets map[string]File, pkgs map[string]Package) []Artifact {
+	for name := range targets {
+		out := name + "." + m.Parameters().Str("ext")
+		m.AddGeneratorFile(out, "// @@protoc_insertion_point(point)\n")
+		m.AddGeneratorInjection(out, "point", "injected\n")
+		m.AddGeneratorAppend(out, "appended\n")
+	}
+	return m.Artifacts()
+}
+
+func TestGenerator_RenderStandalone(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	b, err := proto.Marshal(&descriptor.FileDescriptorSet{File: []*descriptor.FileDescriptorProto{
+		{Name: proto.String("foo.proto"), Package: proto.String("foo"), Syntax: proto.String("proto3")},
+	}})
+	assert.NoError(t, err)
+	fdsetPath := filepath.Join(dir, "fdset.bin")
+	assert.NoError(t, ioutil.WriteFile(fdsetPath, b, 0644))
+
+	fs := afero.NewMemMapFs()
+	g := Init(FileSystem(fs))
+	g.Debugger = InitMockDebugger()
+	g.persister.SetDebugger(g.Debugger)
+	g.RegisterModule(standaloneModule{&ModuleBase{}})
+
+	g.RenderStandalone([]string{
+		"-descriptor_set_in", fdsetPath, "-param", "ext=txt", "-out", "/gen", "foo.proto"})
+	assert.False(t, g.Debugger.(MockDebugger).Failed())
+	assert.NoError(t, g.Debugger.(MockDebugger).Err())
+
+	b, err = afero.ReadFile(fs, "/gen/foo.proto.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "injected\n// @@protoc_insertion_point(point)\nappended\n", string(b))
+}
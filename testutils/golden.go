@@ -0,0 +1,352 @@
+package testutils
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pgs "github.com/lyft/protoc-gen-star/v2"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/afero"
+	"google.golang.org/protobuf/proto"
+	descriptor "google.golang.org/protobuf/types/descriptorpb"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+// UpdateGoldens is the value of the -update-goldens flag. If set, Golden
+// harnesses rewrite their golden directories instead of comparing against
+// them. For example:
+//
+//	go test ./... -update-goldens
+var UpdateGoldens = flag.Bool("update-goldens", false, "rewrite golden files with the current output")
+
+const (
+	goldenErrorFile = "error.txt"
+	goldenGenerated = "generated"
+	goldenCustom    = "custom"
+
+	// customRoot is the directory in the in-memory file system under which
+	// custom files are written.
+	customRoot = "/pgs-custom"
+)
+
+// Golden is a testing harness that executes Modules end to end and compares
+// their output against the contents of a golden directory. The directory has
+// the following layout:
+//
+//	generated/  files in the CodeGeneratorResponse, relative to the output
+//	custom/     custom files written directly to the file system
+//	error.txt   the CodeGeneratorResponse's error, if any
+//
+// Only these entries are compared or rewritten, so the directory may contain
+// other files, such as the protos under test. Appends are merged into the
+// files they target, and insertion points into files generated in the same
+// run are resolved. Injections into files not generated by the Modules are
+// captured as "generated/name#point". Custom files with absolute paths are
+// stored relative to the file system root.
+type Golden struct {
+	// Loader is used to compile proto files.
+	Loader
+
+	// Dir is the golden directory on the OS file system. It must not be empty
+	// or the working directory when updating goldens.
+	Dir string
+
+	// Params are the parameters passed to the plugin, in protoc's
+	// "key=value,..." format.
+	Params string
+
+	// Options are additional InitOptions applied to the Generator. Options that
+	// replace the Generator's input, output, Debugger or file system are
+	// overridden by the harness.
+	Options []pgs.InitOption
+
+	// Update rewrites the golden directory with the current output instead of
+	// comparing against it. If false, the -update-goldens flag is used.
+	Update bool
+}
+
+// Output is the result of a Generator execution captured by a Golden harness.
+type Output struct {
+	// Response is the CodeGeneratorResponse emitted by the Generator.
+	Response *plugin_go.CodeGeneratorResponse
+
+	// Files contains the golden representation of the output, keyed by the
+	// slash-separated path relative to the golden directory.
+	Files map[string]string
+}
+
+// RunProtos compiles files (or globs, as defined by filepath.Glob) with
+// protoc, executes mods against them, and compares the output against the
+// golden directory. The test/benchmark is fatally stopped if there is any
+// error or mismatch.
+func (g Golden) RunProtos(t T, files []string, mods ...pgs.Module) Output {
+	fdset, targets := g.compileProtos(t, files...)
+	if fdset == nil {
+		return Output{}
+	}
+
+	return g.run(t, fdset, targets, mods)
+}
+
+// RunFDSet executes mods against the targets within the serialized
+// FileDescriptorSet at path on the Loader's FS, and compares the output
+// against the golden directory. The test/benchmark is fatally stopped if there
+// is any error or mismatch.
+func (g Golden) RunFDSet(t T, path string, targets []string, mods ...pgs.Module) Output {
+	fdset := g.readFDSetFile(t, path)
+	if fdset == nil {
+		return Output{}
+	}
+
+	return g.run(t, fdset, targets, mods)
+}
+
+func (g Golden) run(t T, fdset *descriptor.FileDescriptorSet, targets []string, mods []pgs.Module) Output {
+	req := &plugin_go.CodeGeneratorRequest{
+		FileToGenerate: targets,
+		ProtoFile:      fdset.GetFile(),
+	}
+	if g.Params != "" {
+		req.Parameter = proto.String(g.Params)
+	}
+
+	out, ok := g.Generate(t, req, mods...)
+	if ok {
+		g.Check(t, out)
+	}
+	return out
+}
+
+// Generate executes mods against req using an in-memory file system, and
+// captures their output. The test/benchmark is fatally stopped and false is
+// returned if the Generator fails.
+func (g Golden) Generate(t T, req *plugin_go.CodeGeneratorRequest, mods ...pgs.Module) (out Output, ok bool) {
+	in, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatalf("unable to marshal request: %v", err)
+		return out, false
+	}
+
+	mem := afero.NewMemMapFs()
+	resp := &bytes.Buffer{}
+	d := pgs.InitMockDebugger()
+
+	defer func() {
+		// the MockDebugger does not halt execution on failure, which may cause a
+		// panic further along
+		if r := recover(); r != nil {
+			logs, _ := ioutil.ReadAll(d.Output())
+			t.Fatalf("generator panicked: %v\n%s", r, logs)
+			ok = false
+		}
+	}()
+
+	opts := append(append([]pgs.InitOption{}, g.Options...),
+		pgs.ProtocInput(bytes.NewReader(in)),
+		pgs.ProtocOutput(resp),
+		pgs.UseDebugger(d),
+		pgs.FileSystem(afero.NewBasePathFs(mem, customRoot)),
+		pgs.ResolveInsertionPoints(),
+	)
+
+	pgs.Init(opts...).RegisterModule(mods...).Render()
+
+	if d.Failed() || d.Exited() || d.Err() != nil {
+		logs, _ := ioutil.ReadAll(d.Output())
+		t.Fatalf("generator failed: %v\n%s", d.Err(), logs)
+		return out, false
+	}
+
+	out.Response = new(plugin_go.CodeGeneratorResponse)
+	if err = proto.Unmarshal(resp.Bytes(), out.Response); err != nil {
+		t.Fatalf("unable to unmarshal response: %v", err)
+		return out, false
+	}
+
+	out.Files = generatedFiles(out.Response)
+
+	err = afero.Walk(mem, customRoot, func(name string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) && name == customRoot {
+			return nil
+		}
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		b, err := afero.ReadFile(mem, name)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(customRoot, name)
+		if err != nil {
+			return err
+		}
+
+		out.Files[path.Join(goldenCustom, filepath.ToSlash(rel))] = string(b)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unable to read custom files: %v", err)
+		return out, false
+	}
+
+	return out, true
+}
+
+// generatedFiles converts the files in resp to their golden representation.
+func generatedFiles(resp *plugin_go.CodeGeneratorResponse) map[string]string {
+	files := map[string]string{}
+
+	if resp.Error != nil {
+		files[goldenErrorFile] = resp.GetError()
+	}
+
+	var last string
+	for _, f := range resp.GetFile() {
+		name := last
+		if f.GetName() != "" {
+			name = path.Join(goldenGenerated, f.GetName())
+			if f.GetInsertionPoint() != "" {
+				name = fmt.Sprintf("%s#%s", name, f.GetInsertionPoint())
+			}
+		}
+
+		files[name] += f.GetContent()
+		last = name
+	}
+
+	return files
+}
+
+// Check compares out against the golden directory, or rewrites it if the
+// harness is in update mode. The test/benchmark is fatally stopped with a diff
+// of every mismatched file.
+func (g Golden) Check(t T, out Output) {
+	if g.Update || *UpdateGoldens {
+		g.write(t, out)
+		return
+	}
+
+	golden, err := readGoldens(g.Dir)
+	if err != nil {
+		t.Fatalf("unable to read golden directory %q: %v", g.Dir, err)
+		return
+	}
+
+	names := make([]string, 0, len(golden)+len(out.Files))
+	for name := range golden {
+		names = append(names, name)
+	}
+	for name := range out.Files {
+		if _, ok := golden[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	buf := &strings.Builder{}
+	for _, name := range names {
+		want, inGolden := golden[name]
+		got, inOut := out.Files[name]
+
+		switch {
+		case !inGolden:
+			fmt.Fprintf(buf, "unexpected file %s\n", name)
+		case !inOut:
+			fmt.Fprintf(buf, "missing file %s\n", name)
+		case want != got:
+			diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        difflib.SplitLines(want),
+				B:        difflib.SplitLines(got),
+				FromFile: "golden/" + name,
+				ToFile:   "actual/" + name,
+				Context:  3,
+			})
+			buf.WriteString(diff)
+		}
+	}
+
+	if buf.Len() > 0 {
+		t.Fatalf("output does not match golden directory %q (run with -update-goldens to update):\n%s",
+			g.Dir, buf.String())
+	}
+}
+
+// goldenEntries are the entries of a golden directory owned by the harness.
+// Other files in the directory are neither compared nor removed.
+var goldenEntries = []string{goldenGenerated, goldenCustom, goldenErrorFile}
+
+func (g Golden) write(t T, out Output) {
+	if dir := filepath.Clean(g.Dir); g.Dir == "" || dir == "." || dir == string(filepath.Separator) {
+		t.Fatalf("refusing to update golden directory %q; set Dir to a dedicated directory", g.Dir)
+		return
+	}
+
+	for _, entry := range goldenEntries {
+		if err := os.RemoveAll(filepath.Join(g.Dir, entry)); err != nil {
+			t.Fatalf("unable to clear golden directory %q: %v", g.Dir, err)
+			return
+		}
+	}
+
+	for name, content := range out.Files {
+		p := filepath.Join(g.Dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("unable to create golden directory: %v", err)
+			return
+		}
+
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("unable to write golden file: %v", err)
+			return
+		}
+	}
+
+	t.Logf("updated golden directory %q", g.Dir)
+}
+
+// readGoldens reads the files of the goldenEntries in dir, keyed by their
+// slash-separated path relative to dir. Missing entries are treated as empty.
+func readGoldens(dir string) (map[string]string, error) {
+	files := map[string]string{}
+
+	for _, entry := range goldenEntries {
+		root := filepath.Join(dir, entry)
+
+		err := filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) && name == root {
+				return nil
+			}
+			if err != nil || info.IsDir() {
+				return err
+			}
+
+			b, err := ioutil.ReadFile(name)
+			if err != nil {
+				return err
+			}
+
+			rel, err := filepath.Rel(dir, name)
+			if err != nil {
+				return err
+			}
+
+			files[filepath.ToSlash(rel)] = string(b)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,221 @@
+package testutils
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	pgs "github.com/lyft/protoc-gen-star/v2"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	plugin_go "google.golang.org/protobuf/types/pluginpb"
+)
+
+// goldenModule lists the messages of each target file in a generated file,
+// exercising appends, injections and custom files.
+type goldenModule struct {
+	*pgs.ModuleBase
+}
+
+func (m goldenModule) Name() string { return "golden" }
+
+func (m goldenModule) Execute(targets map[string]pgs.File, pkgs map[string]pgs.Package) []pgs.Artifact {
+	for _, f := range targets {
+		name := f.InputPath().SetExt(".txt").String()
+		buf := &bytes.Buffer{}
+		for _, msg := range f.AllMessages() {
+			fmt.Fprintln(buf, msg.FullyQualifiedName())
+		}
+
+		m.AddGeneratorFile(name, "// @@protoc_insertion_point(top)\n"+buf.String())
+		m.AddGeneratorInjection(name, "top", m.Parameters().Str("title")+"\n")
+		m.AddGeneratorAppend(name, "-- end --\n")
+		m.AddGeneratorInjection("elsewhere.txt", "point", "injected\n")
+		m.AddCustomFile("/custom/"+name, fmt.Sprint(len(f.AllMessages())), 0644)
+	}
+
+	return m.Artifacts()
+}
+
+func TestGolden_RunProtos(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("protoc"); err != nil {
+		t.Skip("protoc not found in PATH")
+		return
+	}
+
+	g := Golden{
+		Loader: Loader{ImportPaths: []string{"../testdata/protos"}},
+		Dir:    "../testdata/golden/kitchen",
+		Params: "title=Kitchen",
+	}
+
+	out := g.RunProtos(t, []string{"../testdata/protos/kitchen/kitchen.proto"}, goldenModule{&pgs.ModuleBase{}})
+	assert.NotNil(t, out.Response)
+	assert.Contains(t, out.Files, "generated/kitchen/kitchen.txt")
+	assert.Contains(t, out.Files, "generated/elsewhere.txt#point")
+	assert.Contains(t, out.Files, "custom/custom/kitchen/kitchen.txt")
+}
+
+func TestGolden_RunFDSet(t *testing.T) {
+	t.Parallel()
+
+	fs := afero.NewMemMapFs()
+	b, err := proto.Marshal(dummyFDSet())
+	require.NoError(t, err)
+	require.NoError(t, afero.WriteFile(fs, "fdset.bin", b, 0644))
+
+	dir := t.TempDir()
+	g := Golden{Loader: Loader{FS: fs}, Dir: dir, Params: "title=Foo", Update: true}
+
+	mt := &mockT{}
+	out := g.RunFDSet(mt, "fdset.bin", []string{"foo.proto"}, goldenModule{&pgs.ModuleBase{}})
+	require.False(t, mt.failed, mt.log)
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "generated", "foo.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "Foo\n// @@protoc_insertion_point(top)\n-- end --\n", string(content))
+	assert.Equal(t, string(content), out.Files["generated/foo.txt"])
+
+	t.Run("match", func(t *testing.T) {
+		g.Update = false
+		mt := &mockT{}
+		g.RunFDSet(mt, "fdset.bin", []string{"foo.proto"}, goldenModule{&pgs.ModuleBase{}})
+		assert.False(t, mt.failed, mt.log)
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		g.Update = false
+		g.Params = "title=Bar"
+		mt := &mockT{}
+		g.RunFDSet(mt, "fdset.bin", []string{"foo.proto"}, goldenModule{&pgs.ModuleBase{}})
+		assert.True(t, mt.failed)
+		assert.Contains(t, mt.log, "--- golden/generated/foo.txt\n+++ actual/generated/foo.txt\n")
+		assert.Contains(t, mt.log, "-Foo\n+Bar\n")
+	})
+
+	t.Run("missing fdset", func(t *testing.T) {
+		mt := &mockT{}
+		out := g.RunFDSet(mt, "missing.bin", nil)
+		assert.True(t, mt.failed)
+		assert.Nil(t, out.Response)
+	})
+}
+
+func TestGolden_Check(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "generated"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "generated", "stale.txt"), []byte("x"), 0644))
+
+	g := Golden{Dir: dir}
+
+	mt := &mockT{}
+	g.Check(mt, Output{Files: map[string]string{"custom/new.txt": "y"}})
+	assert.True(t, mt.failed)
+	assert.Contains(t, mt.log, "missing file generated/stale.txt\n")
+	assert.Contains(t, mt.log, "unexpected file custom/new.txt\n")
+
+	mt = &mockT{}
+	g.Update = true
+	g.Check(mt, Output{Files: map[string]string{"custom/new.txt": "y"}})
+	assert.False(t, mt.failed)
+
+	_, err := os.Stat(filepath.Join(dir, "generated", "stale.txt"))
+	assert.True(t, os.IsNotExist(err), "update removes stale goldens")
+
+	mt = &mockT{}
+	g.Update = false
+	g.Check(mt, Output{Files: map[string]string{"custom/new.txt": "y"}})
+	assert.False(t, mt.failed, mt.log)
+
+	mt = &mockT{}
+	Golden{Dir: filepath.Join(dir, "missing")}.Check(mt, Output{})
+	assert.False(t, mt.failed, "a missing golden directory is empty")
+}
+
+func TestGolden_Check_SharedDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	fixture := filepath.Join(dir, "fixtures", "foo.proto")
+	require.NoError(t, os.MkdirAll(filepath.Dir(fixture), 0755))
+	require.NoError(t, ioutil.WriteFile(fixture, []byte("syntax = \"proto3\";"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, goldenErrorFile), []byte("stale"), 0644))
+
+	g := Golden{Dir: dir, Update: true}
+	mt := &mockT{}
+	g.Check(mt, Output{Files: map[string]string{"generated/foo.txt": "foo"}})
+	require.False(t, mt.failed, mt.log)
+
+	_, err := os.Stat(fixture)
+	assert.NoError(t, err, "unowned files are preserved")
+	_, err = os.Stat(filepath.Join(dir, goldenErrorFile))
+	assert.True(t, os.IsNotExist(err), "owned files are removed")
+
+	g.Update = false
+	mt = &mockT{}
+	g.Check(mt, Output{Files: map[string]string{"generated/foo.txt": "foo"}})
+	assert.False(t, mt.failed, "unowned files are not compared: %s", mt.log)
+}
+
+func TestGolden_Check_UnsafeDir(t *testing.T) {
+	t.Parallel()
+
+	for _, dir := range []string{"", ".", "./", string(filepath.Separator)} {
+		mt := &mockT{}
+		Golden{Dir: dir, Update: true}.Check(mt, Output{Files: map[string]string{"generated/foo.txt": "foo"}})
+		assert.True(t, mt.failed, dir)
+		assert.Contains(t, mt.log, "refusing to update golden directory")
+	}
+
+	_, err := os.Stat(goldenGenerated)
+	assert.True(t, os.IsNotExist(err), "nothing is written")
+}
+
+func TestGolden_Generate(t *testing.T) {
+	t.Parallel()
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+
+		mt := &mockT{}
+		out, ok := Golden{}.Generate(mt, &plugin_go.CodeGeneratorRequest{
+			FileToGenerate: []string{"foo.proto"},
+			ProtoFile:      dummyFDSet().File,
+		}, errModule{&pgs.ModuleBase{}})
+
+		assert.True(t, ok, mt.log)
+		assert.Equal(t, "something went wrong", out.Files["error.txt"])
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		t.Parallel()
+
+		mt := &mockT{}
+		_, ok := Golden{}.Generate(mt, &plugin_go.CodeGeneratorRequest{}, goldenModule{&pgs.ModuleBase{}})
+
+		assert.False(t, ok)
+		assert.True(t, mt.failed)
+	})
+}
+
+// errModule emits a GeneratorError.
+type errModule struct {
+	*pgs.ModuleBase
+}
+
+func (m errModule) Name() string { return "err" }
+
+func (m errModule) Execute(targets map[string]pgs.File, pkgs map[string]pgs.Package) []pgs.Artifact {
+	m.AddError("something went wrong")
+	return m.Artifacts()
+}
@@ -1,10 +1,12 @@
 package testutils
 
 import (
+	"bytes"
 	"io"
 	"io/ioutil"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	pgs "github.com/lyft/protoc-gen-star/v2"
 	"github.com/spf13/afero"
@@ -60,10 +62,7 @@ func (l Loader) LoadProtos(t T, files ...string) (ast pgs.AST) {
 
 	l.withTempDir(t, func(tmpDir string) {
 		tmpFile := filepath.Join(tmpDir, "fdset.bin")
-		args := l.resolveArgs(tmpFile, targets)
-
-		if out, err := exec.Command(protoc, args...).CombinedOutput(); err != nil {
-			t.Fatalf("protoc execution failed with the following error: %v | Std Out/Err: \n%s", err, string(out))
+		if !l.execProtoc(t, protoc, tmpFile, targets) {
 			return
 		}
 
@@ -73,6 +72,67 @@ func (l Loader) LoadProtos(t T, files ...string) (ast pgs.AST) {
 	return ast
 }
 
+// compileProtos executes protoc against the provided files (or globs),
+// returning the resulting FileDescriptorSet along with the names of the
+// targeted files within it. The test/benchmark is fatally stopped if there is
+// any error.
+func (l Loader) compileProtos(t T, files ...string) (fdset *descriptor.FileDescriptorSet, names []string) {
+	switch l.FS.(type) {
+	case nil, *afero.OsFs:
+	// noop
+	default:
+		t.Fatal("cannot compile protos with a non-OS file system")
+		return nil, nil
+	}
+
+	protoc := l.resolveProtoc(t)
+	targets := l.resolveTargets(t, files...)
+
+	l.withTempDir(t, func(tmpDir string) {
+		tmpFile := filepath.Join(tmpDir, "fdset.bin")
+		if !l.execProtoc(t, protoc, tmpFile, targets) {
+			return
+		}
+
+		fdset = l.readFDSetFile(t, tmpFile)
+	})
+
+	if fdset == nil {
+		return nil, nil
+	}
+
+	for _, target := range targets {
+		target = filepath.ToSlash(target)
+
+		name := ""
+		for _, f := range fdset.GetFile() {
+			n := f.GetName()
+			if (target == n || strings.HasSuffix(target, "/"+n)) && len(n) > len(name) {
+				name = n
+			}
+		}
+
+		if name == "" {
+			t.Fatalf("unable to resolve %q in compiled protos", target)
+			return nil, nil
+		}
+		names = append(names, name)
+	}
+
+	return fdset, names
+}
+
+func (l Loader) execProtoc(t T, protoc, tmpFile string, targets []string) bool {
+	args := l.resolveArgs(tmpFile, targets)
+
+	if out, err := exec.Command(protoc, args...).CombinedOutput(); err != nil {
+		t.Fatalf("protoc execution failed with the following error: %v | Std Out/Err: \n%s", err, string(out))
+		return false
+	}
+
+	return true
+}
+
 // LoadFDSet resolves an AST from a serialized FileDescriptorSet file path on
 // l.FS. The test/benchmark is fatally stopped if there is any error.
 func (l Loader) LoadFDSet(t T, path string) (ast pgs.AST) {
@@ -96,15 +156,8 @@ func (l Loader) LoadFDSet(t T, path string) (ast pgs.AST) {
 // LoadFDSetReader resolve an AST from a serialized FileDescriptorSet in r. The
 // test/benchmark is fatally stopped if there is any error.
 func (l Loader) LoadFDSetReader(t T, r io.Reader) (ast pgs.AST) {
-	raw, err := ioutil.ReadAll(r)
-	if err != nil {
-		t.Fatalf("unable to read fdset: %v", err)
-		return nil
-	}
-
-	fdset := &descriptor.FileDescriptorSet{}
-	if err = proto.Unmarshal(raw, fdset); err != nil {
-		t.Fatalf("unable to unmarshal fdset: %v", err)
+	fdset := l.readFDSet(t, r)
+	if fdset == nil {
 		return nil
 	}
 
@@ -134,6 +187,36 @@ func (l Loader) LoadFDSetReader(t T, r io.Reader) (ast pgs.AST) {
 	return ast
 }
 
+// readFDSetFile reads a serialized FileDescriptorSet from path on l.FS. The
+// test/benchmark is fatally stopped if there is any error.
+func (l Loader) readFDSetFile(t T, path string) *descriptor.FileDescriptorSet {
+	raw, err := afero.ReadFile(l.resolveFS(), path)
+	if err != nil {
+		t.Fatalf("unable to read fdset from path %q: %v", path, err)
+		return nil
+	}
+
+	return l.readFDSet(t, bytes.NewReader(raw))
+}
+
+// readFDSet reads a serialized FileDescriptorSet from r. The test/benchmark is
+// fatally stopped if there is any error.
+func (l Loader) readFDSet(t T, r io.Reader) *descriptor.FileDescriptorSet {
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("unable to read fdset: %v", err)
+		return nil
+	}
+
+	fdset := &descriptor.FileDescriptorSet{}
+	if err = proto.Unmarshal(raw, fdset); err != nil {
+		t.Fatalf("unable to unmarshal fdset: %v", err)
+		return nil
+	}
+
+	return fdset
+}
+
 func (l Loader) resolveFS() afero.Fs {
 	if l.FS == nil {
 		return afero.NewOsFs()
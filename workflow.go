@@ -34,6 +34,7 @@ func (wf *standardWorkflow) Init(g *Generator) AST {
 	err = proto.Unmarshal(data, req)
 	wf.CheckErr(err, "parsing input proto")
 	wf.Assert(len(req.FileToGenerate) > 0, "no files to generate")
+	wf.persister.SetTargets(req.FileToGenerate...)
 
 	wf.Debug("parsing command-line params")
 	wf.params = ParseParameters(req.GetParameter())
@@ -41,6 +42,14 @@ func (wf *standardWorkflow) Init(g *Generator) AST {
 		pm(wf.params)
 	}
 
+	dryRun, err := wf.params.DryRun()
+	wf.CheckErr(err, "parsing dry_run param")
+	if dryRun {
+		fail, err := wf.params.DryRunFail()
+		wf.CheckErr(err, "parsing dry_run_fail param")
+		wf.persister.SetDryRun(wf.params.DryRunReport(), fail)
+	}
+
 	if wf.BiDi {
 		return ProcessCodeGeneratorRequestBidirectional(g, req)
 	}
@@ -56,14 +65,151 @@ func (wf *standardWorkflow) Run(ast AST) (arts []Artifact) {
 		m.InitContext(ctx.Push(m.Name()))
 	}
 
+	deps := wf.moduleDeps()
+	order := wf.moduleOrder(deps)
+
 	wf.Debug("executing modules")
-	for _, m := range wf.mods {
-		arts = append(arts, m.Execute(ast.Targets(), ast.Packages())...)
+	results := make([][]Artifact, len(wf.mods))
+	if wf.workers > 1 {
+		wf.runParallel(ast, order, deps, results)
+	} else {
+		for _, i := range order {
+			results[i] = wf.mods[i].Execute(ast.Targets(), ast.Packages())
+		}
+	}
+
+	for _, i := range order {
+		arts = append(arts, results[i]...)
 	}
 
 	return
 }
 
+// runParallel executes the modules concurrently, with each module waiting on
+// the completion of its dependencies. The Artifacts of the module at index i
+// are stored in results[i].
+func (wf *standardWorkflow) runParallel(ast AST, order []int, deps [][]int, results [][]Artifact) {
+	warmAST(ast)
+
+	done := make([]chan struct{}, len(wf.mods))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	sem := make(chan struct{}, wf.workers)
+	wg := sync.WaitGroup{}
+	wg.Add(len(order))
+
+	for _, i := range order {
+		go func(i int, deps []int) {
+			defer wg.Done()
+			defer close(done[i])
+
+			for _, d := range deps {
+				<-done[d]
+			}
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = wf.mods[i].Execute(ast.Targets(), ast.Packages())
+		}(i, deps[i])
+	}
+
+	wg.Wait()
+}
+
+// moduleDeps resolves the dependencies declared by DependentModules. The
+// returned slice contains, for the module at each index, the indices of the
+// modules it depends on.
+func (wf *standardWorkflow) moduleDeps() [][]int {
+	deps := make([][]int, len(wf.mods))
+
+	for i, mod := range wf.mods {
+		dm, ok := mod.(DependentModule)
+		if !ok {
+			continue
+		}
+
+		for _, name := range dm.DependsOn() {
+			found := false
+			for j, m := range wf.mods {
+				if m.Name() == name && j != i {
+					deps[i] = append(deps[i], j)
+					found = true
+				}
+			}
+			wf.Assert(found, "module ", mod.Name(), " depends on unregistered module ", name)
+		}
+	}
+
+	return deps
+}
+
+// moduleOrder returns the indices of the registered modules in an order that
+// satisfies their dependencies. Independent modules retain their registration
+// order.
+func (wf *standardWorkflow) moduleOrder(deps [][]int) []int {
+	order := make([]int, 0, len(wf.mods))
+	added := make([]bool, len(wf.mods))
+
+	for len(order) < len(wf.mods) {
+		next := nextReadyModule(deps, added)
+		if next < 0 {
+			wf.Fail("cyclic dependency detected between modules")
+			return order
+		}
+
+		order = append(order, next)
+		added[next] = true
+	}
+
+	return order
+}
+
+// nextReadyModule returns the lowest index of a module that has not been added
+// and whose dependencies have all been added. If no module is ready, -1 is
+// returned.
+func nextReadyModule(deps [][]int, added []bool) int {
+modules:
+	for i := range deps {
+		if added[i] {
+			continue
+		}
+
+		for _, d := range deps[i] {
+			if !added[d] {
+				continue modules
+			}
+		}
+
+		return i
+	}
+
+	return -1
+}
+
+// warmAST populates the lazily computed values within the AST, permitting the
+// graph to be safely read from multiple goroutines.
+func warmAST(ast AST) {
+	for _, pkg := range ast.Packages() {
+		for _, f := range pkg.Files() {
+			f.Dependents()
+
+			for _, m := range f.AllMessages() {
+				m.Dependents()
+				for _, me := range m.MapEntries() {
+					me.Dependents()
+				}
+			}
+
+			for _, e := range f.AllEnums() {
+				e.Dependents()
+			}
+		}
+	}
+}
+
 func (wf *standardWorkflow) Persist(arts []Artifact) {
 	resp := wf.persister.Persist(arts...)
 
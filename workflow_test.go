@@ -3,7 +3,9 @@ package pgs
 import (
 	"bytes"
 	"io/ioutil"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/protobuf/proto"
@@ -23,6 +25,7 @@ func TestStandardWorkflow_Init(t *testing.T) {
 	g.workflow.Init(g)
 
 	assert.True(t, mutated)
+	assert.Equal(t, []string{"foo"}, g.persister.(*stdPersister).targets)
 
 	t.Run("bidi", func(t *testing.T) {
 		mutated = false
@@ -31,6 +34,23 @@ func TestStandardWorkflow_Init(t *testing.T) {
 
 		assert.True(t, mutated)
 	})
+
+	t.Run("dry run", func(t *testing.T) {
+		req := &plugin_go.CodeGeneratorRequest{
+			FileToGenerate: []string{"foo"},
+			Parameter:      proto.String("dry_run,dry_run_report=diff.txt,dry_run_fail"),
+		}
+		b, err := proto.Marshal(req)
+		assert.NoError(t, err)
+
+		g = Init(ProtocInput(bytes.NewReader(b)))
+		g.workflow.Init(g)
+
+		p := g.persister.(*stdPersister)
+		assert.True(t, p.dryRun)
+		assert.Equal(t, "diff.txt", p.dryRunReport)
+		assert.True(t, p.dryRunFail)
+	})
 }
 
 func TestStandardWorkflow_Run(t *testing.T) {
@@ -49,6 +69,108 @@ func TestStandardWorkflow_Run(t *testing.T) {
 	assert.True(t, m.executed)
 }
 
+func TestStandardWorkflow_Run_Dependencies(t *testing.T) {
+	t.Parallel()
+
+	for _, workers := range []int{0, 4} {
+		g := Init()
+		g.workflow = &standardWorkflow{Generator: g}
+		g.params = Parameters{}
+		g.workers = workers
+
+		var mtx sync.Mutex
+		var executed []string
+
+		mods := []*orderedModule{
+			{name: "a", deps: []string{"c"}},
+			{name: "b"},
+			{name: "c", deps: []string{"b"}},
+			{name: "d"},
+		}
+		for _, m := range mods {
+			m.ModuleBase = &ModuleBase{}
+			m.mtx, m.executed = &mtx, &executed
+			g.RegisterModule(m)
+		}
+
+		arts := g.workflow.Run(&graph{})
+
+		assert.Equal(t, []Artifact{
+			GeneratorFile{Name: "b"},
+			GeneratorFile{Name: "c"},
+			GeneratorFile{Name: "a"},
+			GeneratorFile{Name: "d"},
+		}, arts, "artifacts are in dependency then registration order")
+
+		indexOf := func(name string) int {
+			for i, n := range executed {
+				if n == name {
+					return i
+				}
+			}
+			return -1
+		}
+
+		assert.Len(t, executed, 4)
+		assert.Less(t, indexOf("b"), indexOf("c"))
+		assert.Less(t, indexOf("c"), indexOf("a"))
+
+		if workers == 0 {
+			assert.Equal(t, []string{"b", "c", "a", "d"}, executed)
+		}
+	}
+}
+
+func TestStandardWorkflow_Run_BadDependencies(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string][]*orderedModule{
+		"cycle": {
+			{name: "a", deps: []string{"b"}},
+			{name: "b", deps: []string{"a"}},
+		},
+		"unregistered": {
+			{name: "a", deps: []string{"b"}},
+		},
+	}
+
+	for desc, mods := range tests {
+		mods := mods
+		t.Run(desc, func(t *testing.T) {
+			d := InitMockDebugger()
+			g := &Generator{Debugger: d, params: Parameters{}}
+			wf := &standardWorkflow{Generator: g}
+
+			var mtx sync.Mutex
+			var executed []string
+			for _, m := range mods {
+				m.ModuleBase = &ModuleBase{}
+				m.mtx, m.executed = &mtx, &executed
+				g.mods = append(g.mods, m)
+			}
+
+			wf.Run(&graph{})
+			assert.True(t, d.Failed())
+		})
+	}
+}
+
+func TestWarmAST(t *testing.T) {
+	t.Parallel()
+
+	m := dummyMsg()
+	e := dummyEnum()
+	m.File().addEnum(e)
+	p := m.Package()
+	p.addFile(e.File())
+
+	warmAST(&graph{packages: map[string]Package{"pkg": p}})
+
+	assert.NotNil(t, m.File().(*file).dependentsCache)
+	assert.NotNil(t, m.dependentsCache)
+	assert.NotNil(t, e.dependentsCache)
+}
+
 func TestStandardWorkflow_Persist(t *testing.T) {
 	t.Parallel()
 
@@ -98,3 +220,26 @@ type dummyWorkflow struct {
 func (wf *dummyWorkflow) Init(g *Generator) AST   { wf.initted = true; return wf.AST }
 func (wf *dummyWorkflow) Run(ast AST) []Artifact  { wf.run = true; return wf.Artifacts }
 func (wf *dummyWorkflow) Persist(arts []Artifact) { wf.persisted = true }
+
+type orderedModule struct {
+	*ModuleBase
+	name string
+	deps []string
+
+	mtx      *sync.Mutex
+	executed *[]string
+}
+
+func (m *orderedModule) Name() string        { return m.name }
+func (m *orderedModule) DependsOn() []string { return m.deps }
+
+func (m *orderedModule) Execute(targets map[string]File, packages map[string]Package) []Artifact {
+	time.Sleep(time.Millisecond)
+
+	m.mtx.Lock()
+	*m.executed = append(*m.executed, m.name)
+	m.mtx.Unlock()
+
+	m.AddArtifact(GeneratorFile{Name: m.name})
+	return m.Artifacts()
+}